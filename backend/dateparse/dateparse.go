@@ -0,0 +1,357 @@
+// Package dateparse parses the Tarih column of payment imports. It looks at
+// the whole column first to decide between day-first and month-first dates,
+// Excel serial numbers and compact YYYYMMDD numbers, and then parses every
+// value the same way instead of guessing row by row.
+package dateparse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Orders of numeric text dates such as 05/03/2025
+const (
+	OrderDMY = "DMY" // day/month/year, the Turkish convention
+	OrderMDY = "MDY" // month/day/year
+	OrderYMD = "YMD" // year-month-day (ISO)
+)
+
+// Excel date systems for serial numbers
+const (
+	System1900 = "1900" // Windows Excel, day 1 is 1900-01-01
+	System1904 = "1904" // older Mac Excel, day 0 is 1904-01-01
+)
+
+// ErrAmbiguousDate is returned for a value like 05/03/2025 in a column that
+// contains evidence for both DD/MM and MM/DD, so neither order can be assumed
+var ErrAmbiguousDate = errors.New("ambiguous date")
+
+// Options controls how a column of dates is interpreted
+type Options struct {
+	Order        string // force DMY, MDY or YMD instead of inferring it; "" to infer
+	DefaultOrder string // used when no value decides the order; DMY if empty
+	DateSystem   string // System1900 (default) or System1904
+}
+
+// ColumnFormat is the interpretation chosen for a date column
+type ColumnFormat struct {
+	Order       string `json:"order,omitempty"`    // Order of numeric text dates
+	DateSystem  string `json:"date_system"`        // Date system of serial numbers
+	Serial      bool   `json:"serial"`             // Column contains Excel serial numbers
+	Compact     bool   `json:"compact,omitempty"`  // Numbers are YYYYMMDD rather than serials
+	Inferred    bool   `json:"inferred"`           // Order was decided by the values themselves
+	Ambiguous   bool   `json:"ambiguous"`          // Numeric dates exist but none decided the order, the default was used
+	Conflicting bool   `json:"conflicting"`        // Values need both DMY and MDY; ambiguous ones are rejected
+	Conflict    string `json:"conflict,omitempty"` // Example rows of a conflicting column
+}
+
+// ValidateOptions checks a forced order and date system
+func ValidateOptions(opts Options) error {
+	for _, order := range []string{opts.Order, opts.DefaultOrder} {
+		switch order {
+		case "", OrderDMY, OrderMDY, OrderYMD:
+		default:
+			return fmt.Errorf("invalid date order '%s' (valid: DMY, MDY, YMD)", order)
+		}
+	}
+	switch opts.DateSystem {
+	case "", System1900, System1904:
+	default:
+		return fmt.Errorf("invalid date system '%s' (valid: 1900, 1904)", opts.DateSystem)
+	}
+	return nil
+}
+
+// InferColumn decides how to parse a column from all of its values
+func InferColumn(values []string, opts Options) ColumnFormat {
+	format := ColumnFormat{DateSystem: opts.DateSystem}
+	if format.DateSystem == "" {
+		format.DateSystem = System1900
+	}
+
+	var dmyRow, mdyRow int
+	var dmyValue, mdyValue string
+	numbers, compactNumbers, textDates := 0, 0, 0
+	for i, value := range values {
+		value = cleanValue(value)
+		if value == "" {
+			continue
+		}
+
+		if isNumber(value) {
+			numbers++
+			if isCompactDate(value) {
+				compactNumbers++
+			}
+			continue
+		}
+
+		parts, ok := numericParts(value)
+		if !ok || len(parts[0]) == 4 {
+			continue
+		}
+		textDates++
+		first, _ := strconv.Atoi(parts[0])
+		second, _ := strconv.Atoi(parts[1])
+		if first > 12 && second <= 12 && dmyValue == "" {
+			dmyRow, dmyValue = i+1, value
+		}
+		if second > 12 && first <= 12 && mdyValue == "" {
+			mdyRow, mdyValue = i+1, value
+		}
+	}
+
+	format.Serial = numbers > 0
+	format.Compact = numbers > 0 && compactNumbers == numbers
+
+	switch {
+	case opts.Order != "":
+		format.Order = opts.Order
+	case dmyValue != "" && mdyValue != "":
+		format.Conflicting = true
+		format.Conflict = fmt.Sprintf("value %d '%s' is day-first but value %d '%s' is month-first", dmyRow, dmyValue, mdyRow, mdyValue)
+	case dmyValue != "":
+		format.Order = OrderDMY
+		format.Inferred = true
+	case mdyValue != "":
+		format.Order = OrderMDY
+		format.Inferred = true
+	default:
+		format.Order = opts.DefaultOrder
+		if format.Order == "" {
+			format.Order = OrderDMY
+		}
+		format.Ambiguous = textDates > 0
+	}
+	return format
+}
+
+// Parse parses a single value with default options
+func Parse(value string) (time.Time, error) {
+	return InferColumn([]string{value}, Options{}).Parse(value)
+}
+
+// Parse parses one value of the column. Supported are Excel serial numbers,
+// YYYYMMDD numbers, numeric dates separated by / - or . in the column order,
+// ISO dates, and dates with Turkish or English month names.
+func (f ColumnFormat) Parse(value string) (time.Time, error) {
+	value = cleanValue(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("date is empty")
+	}
+
+	if isNumber(value) {
+		if f.Compact && isCompactDate(value) {
+			return time.Parse("20060102", value)
+		}
+		serial, _ := strconv.ParseFloat(value, 64)
+		return fromSerial(serial, f.DateSystem)
+	}
+
+	if date, ok, err := parseMonthName(value); ok {
+		return date, err
+	}
+
+	parts, ok := numericParts(value)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unrecognised date '%s' (supported: DD/MM/YYYY, YYYY-MM-DD, DD Ocak YYYY, Excel serial numbers)", value)
+	}
+
+	// Four-digit first parts are always year-month-day
+	order := f.Order
+	if len(parts[0]) == 4 {
+		order = OrderYMD
+	} else if f.Conflicting {
+		// Only values that read the same way in both orders can be trusted
+		first, _ := strconv.Atoi(parts[0])
+		second, _ := strconv.Atoi(parts[1])
+		switch {
+		case first > 12:
+			order = OrderDMY
+		case second > 12:
+			order = OrderMDY
+		case first == second:
+			order = OrderDMY
+		default:
+			return time.Time{}, fmt.Errorf("%w '%s': %s", ErrAmbiguousDate, value, f.Conflict)
+		}
+	} else if order == "" || order == OrderYMD {
+		order = OrderDMY
+	}
+
+	var day, month, year string
+	switch order {
+	case OrderYMD:
+		year, month, day = parts[0], parts[1], parts[2]
+	case OrderMDY:
+		month, day, year = parts[0], parts[1], parts[2]
+	default:
+		day, month, year = parts[0], parts[1], parts[2]
+	}
+	return buildDate(day, month, year, value)
+}
+
+// fromSerial converts an Excel serial number, keeping the time of day
+func fromSerial(serial float64, system string) (time.Time, error) {
+	if serial < 0 || serial > 2958465 {
+		return time.Time{}, fmt.Errorf("serial date %v is out of range", serial)
+	}
+
+	days := int(serial)
+	var date time.Time
+	if system == System1904 {
+		date = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, days)
+	} else {
+		// Excel treats 1900 as a leap year: serial 60 is the non-existent
+		// 1900-02-29 and every later serial is one day ahead
+		switch {
+		case days == 60:
+			return time.Time{}, fmt.Errorf("serial date 60 is 1900-02-29, which does not exist")
+		case days > 60:
+			date = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, days)
+		default:
+			date = time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC).AddDate(0, 0, days)
+		}
+	}
+
+	seconds := int((serial-float64(days))*86400 + 0.5)
+	return date.Add(time.Duration(seconds) * time.Second), nil
+}
+
+// monthNames maps ASCII-folded Turkish and English month names and
+// abbreviations to month numbers
+var monthNames = map[string]time.Month{
+	"ocak": time.January, "oca": time.January, "january": time.January, "jan": time.January,
+	"subat": time.February, "sub": time.February, "february": time.February, "feb": time.February,
+	"mart": time.March, "mar": time.March, "march": time.March,
+	"nisan": time.April, "nis": time.April, "april": time.April, "apr": time.April,
+	"mayis": time.May, "may": time.May,
+	"haziran": time.June, "haz": time.June, "june": time.June, "jun": time.June,
+	"temmuz": time.July, "tem": time.July, "july": time.July, "jul": time.July,
+	"agustos": time.August, "agu": time.August, "august": time.August, "aug": time.August,
+	"eylul": time.September, "eyl": time.September, "september": time.September, "sep": time.September, "sept": time.September,
+	"ekim": time.October, "eki": time.October, "october": time.October, "oct": time.October,
+	"kasim": time.November, "kas": time.November, "november": time.November, "nov": time.November,
+	"aralik": time.December, "ara": time.December, "december": time.December, "dec": time.December,
+}
+
+// parseMonthName parses dates such as "02/ocak/2025", "31 January 2025" or
+// "January 31, 2025". ok is false if the value has no month name.
+func parseMonthName(value string) (time.Time, bool, error) {
+	tokens := strings.FieldsFunc(value, func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == ',' || unicode.IsSpace(r)
+	})
+
+	month := time.Month(0)
+	var numbers []string
+	for _, token := range tokens {
+		if m, ok := monthNames[foldMonth(token)]; ok && month == 0 {
+			month = m
+			continue
+		}
+		if !isDigits(token) {
+			return time.Time{}, false, nil
+		}
+		numbers = append(numbers, token)
+	}
+	if month == 0 {
+		return time.Time{}, false, nil
+	}
+	if len(numbers) != 2 {
+		return time.Time{}, true, fmt.Errorf("date '%s' needs a day and a year next to the month name", value)
+	}
+
+	// The year is the four-digit number, or the last one
+	day, year := numbers[0], numbers[1]
+	if len(day) == 4 {
+		day, year = year, day
+	}
+	date, err := buildDate(day, strconv.Itoa(int(month)), year, value)
+	return date, true, err
+}
+
+// buildDate validates the parts of a date; two-digit years are 20xx
+func buildDate(dayStr, monthStr, yearStr, value string) (time.Time, error) {
+	day, _ := strconv.Atoi(dayStr)
+	month, _ := strconv.Atoi(monthStr)
+	year, _ := strconv.Atoi(yearStr)
+	if len(yearStr) <= 2 {
+		year += 2000
+	}
+
+	if month < 1 || month > 12 {
+		return time.Time{}, fmt.Errorf("invalid month %d in date '%s'", month, value)
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if day < 1 || date.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid day %d in date '%s'", day, value)
+	}
+	return date, nil
+}
+
+// numericParts splits a date like 05/03/2025, 2025-03-05 or 5.3.25 into its three numbers
+func numericParts(value string) ([]string, bool) {
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == '/' || r == '-' || r == '.'
+	})
+	if len(parts) != 3 {
+		return nil, false
+	}
+	for _, part := range parts {
+		if !isDigits(part) || len(part) > 4 {
+			return nil, false
+		}
+	}
+	return parts, true
+}
+
+// cleanValue trims the value and drops a trailing time such as " 00:00:00"
+func cleanValue(value string) string {
+	value = strings.TrimSpace(value)
+	if i := strings.LastIndex(value, " "); i > 0 && strings.Contains(value[i:], ":") {
+		value = strings.TrimSpace(value[:i])
+	}
+	if i := strings.Index(value, "T"); i == 10 && strings.Contains(value[i:], ":") {
+		value = value[:i]
+	}
+	return value
+}
+
+// foldMonth lowercases a month token with Turkish rules and strips accents
+func foldMonth(token string) string {
+	token = strings.ToLowerSpecial(unicode.TurkishCase, token)
+	return strings.NewReplacer("ı", "i", "ş", "s", "ğ", "g", "ü", "u", "ö", "o", "ç", "c").Replace(token)
+}
+
+// isNumber reports whether value is a plain number such as an Excel serial:
+// ASCII digits with an optional fraction, so "NaN", "Inf" and hex floats are not
+func isNumber(value string) bool {
+	integer, fraction, hasFraction := strings.Cut(value, ".")
+	return isDigits(integer) && (!hasFraction || isDigits(fraction))
+}
+
+// isCompactDate reports whether a number reads as a YYYYMMDD date
+func isCompactDate(value string) bool {
+	if len(value) != 8 || !isDigits(value) {
+		return false
+	}
+	_, err := time.Parse("20060102", value)
+	return err == nil
+}
+
+// isDigits reports whether s is a non-empty run of ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,233 @@
+package dateparse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestInferColumnOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      []string
+		opts        Options
+		order       string
+		inferred    bool
+		ambiguous   bool
+		conflicting bool
+	}{
+		{"turkish day first", []string{"05/03/2025", "25/03/2025"}, Options{}, OrderDMY, true, false, false},
+		{"english month first", []string{"05/03/2025", "03/25/2025"}, Options{}, OrderMDY, true, false, false},
+		{"dotted turkish", []string{"13.01.2025", "01.02.2025"}, Options{}, OrderDMY, true, false, false},
+		{"undecided defaults to DMY", []string{"05/03/2025", "01/02/2025"}, Options{}, OrderDMY, false, true, false},
+		{"undecided uses default order", []string{"05/03/2025"}, Options{DefaultOrder: OrderMDY}, OrderMDY, false, true, false},
+		{"both orders", []string{"13/01/2025", "01/13/2025"}, Options{}, "", false, false, true},
+		{"forced order wins", []string{"13/01/2025"}, Options{Order: OrderMDY}, OrderMDY, false, false, false},
+		{"ISO dates decide nothing", []string{"2025-03-05"}, Options{}, OrderDMY, false, false, false},
+		{"serials decide nothing", []string{"45000", ""}, Options{}, OrderDMY, false, false, false},
+	}
+	for _, tt := range tests {
+		format := InferColumn(tt.values, tt.opts)
+		if format.Order != tt.order || format.Inferred != tt.inferred || format.Ambiguous != tt.ambiguous || format.Conflicting != tt.conflicting {
+			t.Errorf("%s: InferColumn(%q) = order %q inferred %v ambiguous %v conflicting %v, want %q %v %v %v",
+				tt.name, tt.values, format.Order, format.Inferred, format.Ambiguous, format.Conflicting,
+				tt.order, tt.inferred, tt.ambiguous, tt.conflicting)
+		}
+	}
+}
+
+func TestParseNumericDates(t *testing.T) {
+	tests := []struct {
+		order string
+		value string
+		want  time.Time
+	}{
+		{OrderDMY, "05/03/2025", date(2025, time.March, 5)},
+		{OrderMDY, "05/03/2025", date(2025, time.May, 3)},
+		{OrderDMY, "5.3.25", date(2025, time.March, 5)},
+		{OrderMDY, "5-3-25", date(2025, time.May, 3)},
+		{OrderDMY, "2025-03-05", date(2025, time.March, 5)},
+		{OrderMDY, "2025/03/05", date(2025, time.March, 5)},
+		{OrderDMY, "05/03/2025 00:00:00", date(2025, time.March, 5)},
+		{OrderDMY, "2025-03-05T10:30:00", date(2025, time.March, 5)},
+		{OrderDMY, "29/02/2024", date(2024, time.February, 29)},
+	}
+	for _, tt := range tests {
+		got, err := ColumnFormat{Order: tt.order}.Parse(tt.value)
+		if err != nil {
+			t.Errorf("Parse(%q) with %s returned error: %v", tt.value, tt.order, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) with %s = %s, want %s", tt.value, tt.order, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestParseConflictingColumn(t *testing.T) {
+	format := InferColumn([]string{"13/01/2025", "01/13/2025", "05/03/2025", "07/07/2025"}, Options{})
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"13/01/2025", date(2025, time.January, 13)},
+		{"01/13/2025", date(2025, time.January, 13)},
+		{"07/07/2025", date(2025, time.July, 7)},
+	}
+	for _, tt := range tests {
+		got, err := format.Parse(tt.value)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) = %s, want %s", tt.value, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+
+	if _, err := format.Parse("05/03/2025"); !errors.Is(err, ErrAmbiguousDate) {
+		t.Errorf("Parse(05/03/2025) error = %v, want ErrAmbiguousDate", err)
+	}
+}
+
+func TestParseSerial(t *testing.T) {
+	tests := []struct {
+		system string
+		value  string
+		want   time.Time
+	}{
+		{System1900, "1", date(1900, time.January, 1)},
+		{System1900, "59", date(1900, time.February, 28)},
+		{System1900, "61", date(1900, time.March, 1)},
+		{System1900, "45000", date(2023, time.March, 15)},
+		{System1900, "45000.5", date(2023, time.March, 15).Add(12 * time.Hour)},
+		{System1904, "0", date(1904, time.January, 1)},
+		{System1904, "59", date(1904, time.February, 29)},
+		{System1904, "43538", date(2023, time.March, 15)},
+		{System1904, "45000", date(2027, time.March, 16)},
+	}
+	for _, tt := range tests {
+		format := InferColumn([]string{tt.value}, Options{DateSystem: tt.system})
+		got, err := format.Parse(tt.value)
+		if err != nil {
+			t.Errorf("Parse(%s) in %s returned error: %v", tt.value, tt.system, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Parse(%s) in %s = %s, want %s", tt.value, tt.system, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		system string
+		value  string
+	}{
+		{System1900, "60"},
+		{System1900, "-1"},
+		{System1900, "2958466"},
+		{System1900, "NaN"},
+		{System1900, "Inf"},
+		{System1900, "+Inf"},
+		{System1900, "0x10"},
+		{System1900, "0x1p4"},
+		{System1900, "45000."},
+	} {
+		format := InferColumn([]string{tt.value}, Options{DateSystem: tt.system})
+		if got, err := format.Parse(tt.value); err == nil {
+			t.Errorf("Parse(%s) in %s = %s, want an error", tt.value, tt.system, got)
+		}
+	}
+}
+
+func TestParseCompactNumbers(t *testing.T) {
+	format := InferColumn([]string{"20250305", "20250401"}, Options{})
+	if !format.Compact {
+		t.Fatalf("InferColumn did not detect YYYYMMDD numbers")
+	}
+	got, err := format.Parse("20250305")
+	if err != nil || !got.Equal(date(2025, time.March, 5)) {
+		t.Errorf("Parse(20250305) = %s, %v, want 2025-03-05", got, err)
+	}
+
+	// A column mixing YYYYMMDD-looking numbers with serials is all serials
+	format = InferColumn([]string{"20250305", "45000"}, Options{})
+	if format.Compact {
+		t.Errorf("InferColumn took a column with serials for YYYYMMDD numbers")
+	}
+}
+
+func TestParseMonthNames(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"02/ocak/2025", date(2025, time.January, 2)},
+		{"2 Ocak 2025", date(2025, time.January, 2)},
+		{"14 ŞUBAT 2025", date(2025, time.February, 14)},
+		{"14 Şub 2025", date(2025, time.February, 14)},
+		{"1 Mayıs 2025", date(2025, time.May, 1)},
+		{"30 AĞUSTOS 2025", date(2025, time.August, 30)},
+		{"1 Eylül 2025", date(2025, time.September, 1)},
+		{"3 KASIM 2025", date(2025, time.November, 3)},
+		{"31 Aralık 2025", date(2025, time.December, 31)},
+		{"31 January 2025", date(2025, time.January, 31)},
+		{"January 31, 2025", date(2025, time.January, 31)},
+		{"2025 Mar 5", date(2025, time.March, 5)},
+		{"5-Sept-25", date(2025, time.September, 5)},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.value)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) = %s, want %s", tt.value, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"yesterday",
+		"31/02/2025",
+		"05/13/2025 extra",
+		"Ocak 2025",
+		"32 Ocak 2025",
+		"2025-13-01",
+	} {
+		if got, err := (ColumnFormat{Order: OrderDMY}).Parse(value); err == nil {
+			t.Errorf("Parse(%q) = %s, want an error", value, got)
+		}
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	valid := []Options{
+		{},
+		{Order: OrderMDY, DateSystem: System1904},
+		{DefaultOrder: OrderYMD, DateSystem: System1900},
+	}
+	for _, opts := range valid {
+		if err := ValidateOptions(opts); err != nil {
+			t.Errorf("ValidateOptions(%+v) returned error: %v", opts, err)
+		}
+	}
+
+	invalid := []Options{
+		{Order: "DDMM"},
+		{DefaultOrder: "dmy"},
+		{DateSystem: "1901"},
+	}
+	for _, opts := range invalid {
+		if err := ValidateOptions(opts); err == nil {
+			t.Errorf("ValidateOptions(%+v) accepted invalid options", opts)
+		}
+	}
+}
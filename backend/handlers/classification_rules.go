@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// settingClassificationRulesSeeded records that the default rules were saved
+// once, so deleting them all does not bring them back on the next start
+const settingClassificationRulesSeeded = "classification_rules_seeded"
+
+// ClassificationHandler manages the rules that classify payments
+type ClassificationHandler struct {
+	db *sql.DB
+}
+
+// NewClassificationHandler creates a new classification handler
+func NewClassificationHandler(db *sql.DB) *ClassificationHandler {
+	return &ClassificationHandler{db: db}
+}
+
+// ListRules returns all classification rules, optionally only those of ?field=,
+// in the order they are evaluated
+func (h *ClassificationHandler) ListRules(c *gin.Context) {
+	rules, err := loadClassificationRules(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	field := c.Query("field")
+	filtered := []models.ClassificationRule{}
+	for _, rule := range rules {
+		if field == "" || rule.Field == field {
+			filtered = append(filtered, rule)
+		}
+	}
+	c.JSON(http.StatusOK, filtered)
+}
+
+// CreateRule adds a classification rule. Stored payments keep their
+// classification until they are reprocessed.
+func (h *ClassificationHandler) CreateRule(c *gin.Context) {
+	rule, ok := bindClassificationRule(c)
+	if !ok {
+		return
+	}
+	if err := checkRuleValue(h.db, rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	result, err := h.db.Exec(`
+		INSERT INTO classification_rules (field, source, match_type, pattern, value, priority, enabled, note, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rule.Field, rule.Source, rule.MatchType, rule.Pattern, rule.Value, rule.Priority, rule.Enabled, rule.Note, now, now)
+	if err != nil {
+		log.Printf("Error creating classification rule: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	rule.ID, _ = result.LastInsertId()
+	rule.CreatedAt, rule.UpdatedAt = now, now
+
+	log.Printf("Classification rule %d created: %s %s '%s' -> %s = %s", rule.ID, rule.Source, rule.MatchType, rule.Pattern, rule.Field, rule.Value)
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule replaces a classification rule
+func (h *ClassificationHandler) UpdateRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+	rule, ok := bindClassificationRule(c)
+	if !ok {
+		return
+	}
+	if err := checkRuleValue(h.db, rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule.ID = id
+	rule.UpdatedAt = time.Now()
+	result, err := h.db.Exec(`
+		UPDATE classification_rules SET field = ?, source = ?, match_type = ?, pattern = ?, value = ?, priority = ?, enabled = ?, note = ?, updated_at = ?
+		WHERE id = ?
+	`, rule.Field, rule.Source, rule.MatchType, rule.Pattern, rule.Value, rule.Priority, rule.Enabled, rule.Note, rule.UpdatedAt, id)
+	if err != nil {
+		log.Printf("Error updating classification rule %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+	h.db.QueryRow(`SELECT created_at FROM classification_rules WHERE id = ?`, id).Scan(&rule.CreatedAt)
+
+	log.Printf("Classification rule %d updated", id)
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule removes a classification rule
+func (h *ClassificationHandler) DeleteRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+
+	result, err := h.db.Exec(`DELETE FROM classification_rules WHERE id = ?`, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+
+	log.Printf("Classification rule %d deleted", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted", "id": id})
+}
+
+// SimulateRules classifies all stored payments with a proposed rule set and
+// returns the payments and report totals that would change. Nothing is saved.
+func (h *ClassificationHandler) SimulateRules(c *gin.Context) {
+	var req models.ClassificationSimulationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+	for i, rule := range req.Rules {
+		if err := services.ValidateClassificationRule(rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("rule %d: %v", i+1, err)})
+			return
+		}
+	}
+	projects, err := loadProjects(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	locations, err := loadLocations(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	rules, err := services.NewRuleSet(req.Rules, projects, locations)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := `SELECT id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name, amount_usd, exchange_rate, raw_data, kind, original_payment_id, gold_grams, COALESCE(review_status, ''), review_reasons FROM payments ORDER BY payment_date, id`
+	payments, err := loadPaymentsForReprocess(h.db, query, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	simulation := services.SimulateClassification(rules, payments)
+	log.Printf("Simulated %d classification rules: %d of %d payments would change", len(req.Rules), simulation.Changed, simulation.Matched)
+	c.JSON(http.StatusOK, simulation)
+}
+
+// bindClassificationRule reads and validates a rule from the request body,
+// writing the error response if it is not valid
+func bindClassificationRule(c *gin.Context) (models.ClassificationRule, bool) {
+	rule := models.ClassificationRule{Enabled: true}
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return rule, false
+	}
+	if err := services.ValidateClassificationRule(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return rule, false
+	}
+	return rule, true
+}
+
+// loadClassificationRules returns all rules ordered by field, priority and ID
+func loadClassificationRules(db sqlExecutor) ([]models.ClassificationRule, error) {
+	rows, err := db.Query(`
+		SELECT id, field, source, match_type, pattern, value, priority, enabled, COALESCE(note, ''), created_at, updated_at
+		FROM classification_rules ORDER BY field, priority, id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.ClassificationRule{}
+	for rows.Next() {
+		var rule models.ClassificationRule
+		err := rows.Scan(&rule.ID, &rule.Field, &rule.Source, &rule.MatchType, &rule.Pattern, &rule.Value,
+			&rule.Priority, &rule.Enabled, &rule.Note, &rule.CreatedAt, &rule.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// checkRuleValue verifies that a project or location rule sets a configured
+// project or location, and that a payment method rule sets one of the payment
+// methods the reports total
+func checkRuleValue(db *sql.DB, rule models.ClassificationRule) error {
+	var table string
+	switch rule.Field {
+	case models.RuleFieldPaymentMethod:
+		validMethods := []string{models.PaymentMethodCash, models.PaymentMethodTransfer, models.PaymentMethodCheck}
+		if !contains(validMethods, rule.Value) {
+			return fmt.Errorf("invalid payment method '%s' (valid: %s)", rule.Value, strings.Join(validMethods, ", "))
+		}
+		return nil
+	case models.RuleFieldProject:
+		table = "projects"
+	case models.RuleFieldLocation:
+		table = "locations"
+	default:
+		return nil
+	}
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM `+table+` WHERE code = ?)`, rule.Value).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%s %s does not exist", strings.TrimSuffix(table, "s"), rule.Value)
+	}
+	return nil
+}
+
+// setClassificationRules gives the processor the saved classification rules.
+// On error the processor keeps the default rules.
+func setClassificationRules(db *sql.DB, processor *services.PaymentProcessor) {
+	rules, err := loadClassificationRules(db)
+	if err == nil {
+		err = processor.SetClassificationRules(rules)
+	}
+	if err != nil {
+		log.Printf("Error loading classification rules, using defaults: %v", err)
+	}
+}
+
+// SeedClassificationRules saves the default rules the first time the rules
+// table is used, so they can be edited like any other rule
+func SeedClassificationRules(db *sql.DB) error {
+	var seeded bool
+	if _, err := loadSetting(db, settingClassificationRulesSeeded, &seeded); err != nil || seeded {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	defaults := services.DefaultClassificationRules()
+	for _, rule := range defaults {
+		_, err := tx.Exec(`
+			INSERT INTO classification_rules (field, source, match_type, pattern, value, priority, enabled, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, rule.Field, rule.Source, rule.MatchType, rule.Pattern, rule.Value, rule.Priority, rule.Enabled, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to save default rule '%s': %v", rule.Pattern, err)
+		}
+	}
+	if err := saveSetting(tx, settingClassificationRulesSeeded, true); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Saved %d default classification rules", len(defaults))
+	return nil
+}
+
+// matchedRulesJSON encodes the rules that classified a payment for the
+// matched_rules column, NULL when no saved rule matched
+func matchedRulesJSON(payment models.PaymentRecord) sql.NullString {
+	if len(payment.MatchedRules) == 0 {
+		return sql.NullString{}
+	}
+	data, err := json.Marshal(payment.MatchedRules)
+	if err != nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(data), Valid: true}
+}
@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomerHandler manages the customer master table
+type CustomerHandler struct {
+	db *sql.DB
+}
+
+// NewCustomerHandler creates a new customer handler
+func NewCustomerHandler(db *sql.DB) *CustomerHandler {
+	return &CustomerHandler{db: db}
+}
+
+// ListCustomers returns the customers with their payment counts. ?q= returns
+// only customers with a name similar to q, most similar first, and
+// ?include_merged=true also lists customers that were merged into another.
+func (h *CustomerHandler) ListCustomers(c *gin.Context) {
+	rows, err := h.db.Query(`
+		SELECT c.id, c.name, c.normalized_name, c.merged_into, c.created_at, COUNT(p.id)
+		FROM customers c LEFT JOIN payments p ON p.customer_id = c.id
+		GROUP BY c.id ORDER BY c.name
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	includeMerged := c.Query("include_merged") == "true"
+	customers := []models.Customer{}
+	for rows.Next() {
+		var customer models.Customer
+		if err := rows.Scan(&customer.ID, &customer.Name, &customer.NormalizedName, &customer.MergedInto, &customer.CreatedAt, &customer.PaymentCount); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if customer.MergedInto == nil || includeMerged {
+			customers = append(customers, customer)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if q := c.Query("q"); q != "" {
+		customers = append([]models.Customer{}, services.SimilarCustomers(q, customers)...)
+	}
+	c.JSON(http.StatusOK, customers)
+}
+
+// MergeCustomers merges duplicate customers into the customer in the URL: their
+// payments are moved to it and take its name, and their spellings match it on
+// later imports.
+func (h *CustomerHandler) MergeCustomers(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	var req models.CustomerMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	canonical, status, err := loadMergeableCustomer(tx, id)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Listing a customer twice merges it once
+	seen := make(map[int64]bool)
+	duplicateIDs := req.DuplicateIDs[:0]
+	for _, duplicateID := range req.DuplicateIDs {
+		if !seen[duplicateID] {
+			seen[duplicateID] = true
+			duplicateIDs = append(duplicateIDs, duplicateID)
+		}
+	}
+	req.DuplicateIDs = duplicateIDs
+
+	var moved int64
+	now := time.Now()
+	for _, duplicateID := range req.DuplicateIDs {
+		if duplicateID == id {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A customer cannot be merged into itself"})
+			return
+		}
+		if _, status, err := loadMergeableCustomer(tx, duplicateID); err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := tx.Exec(`UPDATE payments SET customer_id = ?, customer_name = ? WHERE customer_id = ?`, id, canonical.Name, duplicateID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		n, _ := result.RowsAffected()
+		moved += n
+
+		// Customers merged into the duplicate earlier now point to the canonical one too
+		if _, err := tx.Exec(`UPDATE customers SET merged_into = ?, updated_at = ? WHERE id = ? OR merged_into = ?`, id, now, duplicateID, duplicateID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := refreshCustomerFingerprints(tx, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM payments WHERE customer_id = ?`, id).Scan(&canonical.PaymentCount); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Merged customers %v into %d (%s), %d payments moved", req.DuplicateIDs, id, canonical.Name, moved)
+	c.JSON(http.StatusOK, gin.H{
+		"message":        fmt.Sprintf("%d customers merged into %s", len(req.DuplicateIDs), canonical.Name),
+		"customer":       canonical,
+		"merged":         len(req.DuplicateIDs),
+		"payments_moved": moved,
+	})
+}
+
+// refreshCustomerFingerprints recomputes the fingerprints of a customer's
+// payments from the name they are stored under, so that re-uploads of payments
+// moved to it by a merge are still detected as duplicates
+func refreshCustomerFingerprints(db sqlExecutor, customerID int64) error {
+	rows, err := db.Query(`SELECT id, customer_name, payment_date, amount, currency, account_name, project FROM payments WHERE customer_id = ?`, customerID)
+	if err != nil {
+		return err
+	}
+	fingerprints := make(map[int]string)
+	for rows.Next() {
+		var payment models.PaymentRecord
+		if err := rows.Scan(&payment.ID, &payment.CustomerName, &payment.PaymentDate, &payment.Amount, &payment.Currency, &payment.AccountName, &payment.Project); err != nil {
+			rows.Close()
+			return err
+		}
+		fingerprints[payment.ID] = services.PaymentFingerprint(payment)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, fingerprint := range fingerprints {
+		if _, err := db.Exec(`UPDATE payments SET fingerprint = ? WHERE id = ?`, fingerprint, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadMergeableCustomer loads a customer that takes part in a merge, with the
+// HTTP status to report if it does not exist or was already merged
+func loadMergeableCustomer(db sqlExecutor, id int64) (models.Customer, int, error) {
+	var customer models.Customer
+	err := db.QueryRow(`SELECT id, name, normalized_name, merged_into, created_at FROM customers WHERE id = ?`, id).Scan(
+		&customer.ID, &customer.Name, &customer.NormalizedName, &customer.MergedInto, &customer.CreatedAt)
+	if err == sql.ErrNoRows {
+		return customer, http.StatusNotFound, fmt.Errorf("customer %d not found", id)
+	}
+	if err != nil {
+		return customer, http.StatusInternalServerError, err
+	}
+	if customer.MergedInto != nil {
+		return customer, http.StatusConflict, fmt.Errorf("customer %d was already merged into %d", id, *customer.MergedInto)
+	}
+	return customer, http.StatusOK, nil
+}
+
+// customerDirectory resolves customer names to the customer master table. It
+// is loaded once per import and creates the customers that are new.
+type customerDirectory struct {
+	db     sqlExecutor
+	active []models.Customer          // Customers that were not merged into another
+	byName map[string]models.Customer // Normalized name -> customer the payments belong to
+}
+
+// loadCustomerDirectory reads all customers, following merges to the customer
+// each name now belongs to
+func loadCustomerDirectory(db sqlExecutor) (*customerDirectory, error) {
+	rows, err := db.Query(`SELECT id, name, normalized_name, merged_into, created_at FROM customers`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var all []models.Customer
+	byID := make(map[int64]models.Customer)
+	for rows.Next() {
+		var customer models.Customer
+		if err := rows.Scan(&customer.ID, &customer.Name, &customer.NormalizedName, &customer.MergedInto, &customer.CreatedAt); err != nil {
+			return nil, err
+		}
+		all = append(all, customer)
+		byID[customer.ID] = customer
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	directory := &customerDirectory{db: db, byName: make(map[string]models.Customer)}
+	for _, customer := range all {
+		target := customer
+		// A merge re-points everything merged into the duplicate, so there is one step at most
+		if customer.MergedInto != nil {
+			if canonical, ok := byID[*customer.MergedInto]; ok {
+				target = canonical
+			}
+		} else {
+			directory.active = append(directory.active, customer)
+		}
+		directory.byName[customer.NormalizedName] = target
+	}
+	return directory, nil
+}
+
+// resolve returns the customer payments of name belong to. A new name gets a
+// customer with ID 0 that save creates once one of its payments is saved; for
+// it resolve also returns the existing customers with a similar name.
+func (d *customerDirectory) resolve(name string) (models.Customer, []models.Customer) {
+	key := services.NormalizeCustomerName(name)
+	if customer, ok := d.byName[key]; ok {
+		return customer, nil
+	}
+
+	similar := services.SimilarCustomers(name, d.active)
+	customer := models.Customer{
+		Name:           services.CustomerDisplayName(name),
+		NormalizedName: key,
+		CreatedAt:      time.Now(),
+	}
+	d.active = append(d.active, customer)
+	d.byName[key] = customer
+	return customer, similar
+}
+
+// save creates a customer resolve returned that is not saved yet, setting its
+// ID, and reports whether it did
+func (d *customerDirectory) save(customer *models.Customer) (bool, error) {
+	if customer.ID > 0 {
+		return false, nil
+	}
+	result, err := d.db.Exec(`INSERT INTO customers (name, normalized_name, created_at, updated_at) VALUES (?, ?, ?, ?)`,
+		customer.Name, customer.NormalizedName, customer.CreatedAt, customer.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to create customer %s: %v", customer.Name, err)
+	}
+	customer.ID, _ = result.LastInsertId()
+	d.update(*customer)
+	return true, nil
+}
+
+// discard deletes a customer save just created, for a payment that could not
+// be saved, so that no customer is left without payments
+func (d *customerDirectory) discard(customer *models.Customer) error {
+	if _, err := d.db.Exec(`DELETE FROM customers WHERE id = ?`, customer.ID); err != nil {
+		return err
+	}
+	customer.ID = 0
+	d.update(*customer)
+	return nil
+}
+
+// update replaces the directory's entry for a new customer
+func (d *customerDirectory) update(customer models.Customer) {
+	d.byName[customer.NormalizedName] = customer
+	for i := range d.active {
+		if d.active[i].NormalizedName == customer.NormalizedName {
+			d.active[i] = customer
+		}
+	}
+}
+
+// BackfillPaymentCustomers links payments stored before the customer master
+// table existed to their customers. Spellings that differ only in case or
+// whitespace become one customer and take its name, and their fingerprints are
+// recomputed from it.
+func BackfillPaymentCustomers(db *sql.DB) error {
+	rows, err := db.Query(`SELECT DISTINCT customer_name FROM payments WHERE customer_id IS NULL`)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	directory, err := loadCustomerDirectory(tx)
+	if err != nil {
+		return err
+	}
+	linked := make(map[int64]bool)
+	for _, name := range names {
+		customer, _ := directory.resolve(name)
+		if _, err := directory.save(&customer); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE payments SET customer_id = ?, customer_name = ? WHERE customer_id IS NULL AND customer_name = ?`,
+			customer.ID, customer.Name, name); err != nil {
+			return err
+		}
+		linked[customer.ID] = true
+	}
+	for customerID := range linked {
+		if err := refreshCustomerFingerprints(tx, customerID); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Linked payments of %d customer names to the customer table", len(names))
+	return nil
+}
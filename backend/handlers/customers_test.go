@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+)
+
+func TestBackfillPaymentCustomersRefreshesFingerprints(t *testing.T) {
+	db := newTestDB(t)
+	date := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	// Payments stored before fingerprints existed have none
+	for _, name := range []string{"Ahmet Yılmaz", "AHMET  YILMAZ"} {
+		_, err := db.Exec(`
+			INSERT INTO payments (customer_name, payment_date, amount, currency, payment_method, location, project,
+				account_name, amount_usd, exchange_rate)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, name, date, 100, "USD", "Nakit", "OFIS", "MKM", "Kasa", 100, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := BackfillPaymentCustomers(db); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Query(`SELECT customer_id, customer_name, fingerprint FROM payments`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	fingerprints := make(map[string]bool)
+	for rows.Next() {
+		var customerID *int64
+		var name string
+		var fingerprint sql.NullString
+		if err := rows.Scan(&customerID, &name, &fingerprint); err != nil {
+			t.Fatal(err)
+		}
+		if customerID == nil {
+			t.Errorf("payment of %s was not linked to a customer", name)
+		}
+		want := services.PaymentFingerprint(models.PaymentRecord{CustomerName: name, PaymentDate: date, Amount: 10000, Currency: "USD", AccountName: "Kasa", Project: "MKM"})
+		if fingerprint.String != want {
+			t.Errorf("payment of %s has fingerprint %q, want %s", name, fingerprint.String, want)
+		}
+		fingerprints[fingerprint.String] = true
+	}
+	if len(fingerprints) != 1 {
+		t.Errorf("backfilled payments have %d fingerprints, want 1", len(fingerprints))
+	}
+}
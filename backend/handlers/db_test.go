@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// testSchema creates the tables the handlers under test read and write, as
+// main.initDB creates them
+var testSchema = []string{
+	`CREATE TABLE payments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		customer_name TEXT NOT NULL,
+		payment_date DATETIME NOT NULL,
+		amount REAL NOT NULL,
+		currency TEXT NOT NULL,
+		payment_method TEXT NOT NULL,
+		location TEXT NOT NULL,
+		project TEXT NOT NULL,
+		account_name TEXT NOT NULL,
+		amount_usd REAL NOT NULL,
+		exchange_rate REAL NOT NULL,
+		raw_data TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		batch_id INTEGER,
+		fingerprint TEXT,
+		duplicate_of INTEGER,
+		kind TEXT NOT NULL DEFAULT 'collection',
+		original_payment_id INTEGER,
+		gold_grams REAL NOT NULL DEFAULT 0,
+		customer_id INTEGER,
+		matched_rules TEXT,
+		review_status TEXT,
+		review_reasons TEXT,
+		review_note TEXT,
+		reviewed_at DATETIME
+	)`,
+	`CREATE TABLE settings (key TEXT PRIMARY KEY, value TEXT NOT NULL, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+	`CREATE TABLE gold_prices (price_date TEXT PRIMARY KEY, usd_per_gram REAL NOT NULL, note TEXT, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+	`CREATE TABLE customers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		normalized_name TEXT NOT NULL UNIQUE,
+		merged_into INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE classification_rules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		field TEXT NOT NULL,
+		source TEXT NOT NULL,
+		match_type TEXT NOT NULL,
+		pattern TEXT NOT NULL,
+		value TEXT NOT NULL,
+		priority INTEGER NOT NULL DEFAULT 0,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		note TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE projects (
+		code TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		patterns TEXT NOT NULL,
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE locations (
+		code TEXT PRIMARY KEY,
+		label TEXT NOT NULL,
+		account_patterns TEXT NOT NULL,
+		method_overrides TEXT NOT NULL,
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+}
+
+// newTestDB opens an empty in-memory database with the test schema
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: is a database of its own
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	for _, statement := range testSchema {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("failed to create test schema: %v", err)
+		}
+	}
+	return db
+}
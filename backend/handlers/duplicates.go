@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+)
+
+// findDuplicatePayment returns the ID of a payment from an earlier upload with
+// the same fingerprint, or 0 if there is none. Rows of the current batch are
+// ignored so that identical lines within one file are still imported.
+func findDuplicatePayment(db sqlExecutor, fingerprint string, batchID int64) (int64, error) {
+	var existingID int64
+	query := `SELECT id FROM payments WHERE fingerprint = ? AND (batch_id IS NULL OR batch_id != ?) ORDER BY id LIMIT 1`
+	err := db.QueryRow(query, fingerprint, batchID).Scan(&existingID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return existingID, err
+}
+
+// BackfillPaymentFingerprints computes fingerprints for payments stored before
+// duplicate detection existed, so that re-uploads of old files are detected too
+func BackfillPaymentFingerprints(db *sql.DB) error {
+	rows, err := db.Query(`SELECT id, customer_name, payment_date, amount, currency, account_name, project FROM payments WHERE fingerprint IS NULL OR fingerprint = ''`)
+	if err != nil {
+		return err
+	}
+
+	fingerprints := make(map[int]string)
+	for rows.Next() {
+		var payment models.PaymentRecord
+		var paymentDate time.Time
+		if err := rows.Scan(&payment.ID, &payment.CustomerName, &paymentDate, &payment.Amount, &payment.Currency, &payment.AccountName, &payment.Project); err != nil {
+			rows.Close()
+			return err
+		}
+		payment.PaymentDate = paymentDate
+		fingerprints[payment.ID] = services.PaymentFingerprint(payment)
+	}
+	rows.Close()
+
+	if len(fingerprints) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for id, fingerprint := range fingerprints {
+		if _, err := tx.Exec(`UPDATE payments SET fingerprint = ? WHERE id = ?`, fingerprint, id); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Backfilled fingerprints for %d existing payments", len(fingerprints))
+	return nil
+}
@@ -3,10 +3,15 @@ package handlers
 import (
 	"database/sql"
 	"fmt"
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"unicode"
 
 	"tahsilat-raporu/models"
+	"tahsilat-raporu/money"
 	"tahsilat-raporu/services"
 
 	"github.com/gin-gonic/gin"
@@ -33,6 +38,12 @@ func (h *ExportHandler) ExportExcel(c *gin.Context) {
 		return
 	}
 
+	projects, err := loadProjects(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Generate reports
 	weeklyReports := services.GenerateWeeklyReports(payments)
 
@@ -49,7 +60,7 @@ func (h *ExportHandler) ExportExcel(c *gin.Context) {
 			f.NewSheet(sheetName)
 		}
 
-		h.writeWeeklyReportToExcel(f, sheetName, report)
+		h.writeWeeklyReportToExcel(f, sheetName, report, projects)
 	}
 
 	// Set response headers
@@ -72,6 +83,12 @@ func (h *ExportHandler) ExportPDF(c *gin.Context) {
 		return
 	}
 
+	projects, err := loadProjects(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Generate reports
 	weeklyReports := services.GenerateWeeklyReports(payments)
 
@@ -83,7 +100,7 @@ func (h *ExportHandler) ExportPDF(c *gin.Context) {
 		if i > 0 {
 			pdf.AddPage()
 		}
-		h.writeWeeklyReportToPDF(pdf, report)
+		h.writeWeeklyReportToPDF(pdf, report, projects)
 	}
 
 	// Set response headers
@@ -98,11 +115,12 @@ func (h *ExportHandler) ExportPDF(c *gin.Context) {
 }
 
 // writeWeeklyReportToExcel writes a weekly report to an Excel sheet
-func (h *ExportHandler) writeWeeklyReportToExcel(f *excelize.File, sheetName string, report models.WeeklyReport) {
+func (h *ExportHandler) writeWeeklyReportToExcel(f *excelize.File, sheetName string, report models.WeeklyReport, projects []models.Project) {
 	row := 1
 
 	// Title
-	title := fmt.Sprintf("MODEL KUYUM-MODEL SANAYİ MERKEZİ TAHSİLATLAR TABLOSU %s-%s",
+	title := fmt.Sprintf("%s TAHSİLATLAR TABLOSU %s-%s",
+		projectsTitle(projects),
 		report.StartDate.Format("02/01/2006"),
 		report.EndDate.Format("02/01/2006"))
 	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), title)
@@ -114,40 +132,46 @@ func (h *ExportHandler) writeWeeklyReportToExcel(f *excelize.File, sheetName str
 	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), "TOPLAM (USD)")
 	row++
 
-	totalCustomerUSD := 0.0
+	var totalCustomerUSD money.Amount
 	for customer, amount := range report.CustomerSummary {
 		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), customer)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), amount)
+		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), amount.Float64())
 		totalCustomerUSD += amount
 		row++
 	}
 
 	// Customer total row
 	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "TOPLAM")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), totalCustomerUSD)
+	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), totalCustomerUSD.Float64())
 	row += 2
 
-	// Payment Method Summary Table
+	// Payment Method Summary Table: one column per currency collected, in that currency
+	currencies := methodCurrencies(report.PaymentMethods)
+	goldCol := len(currencies) + 2
 	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "Ödeme Şekli")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), "Toplam TL")
-	f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), "Toplam USD")
+	for i, currency := range currencies {
+		f.SetCellValue(sheetName, excelCell(i+2, row), "Toplam "+currency)
+	}
+	f.SetCellValue(sheetName, excelCell(goldCol, row), "Altın (Has gr)")
 	row++
 
-	totalMethodTL := 0.0
-	totalMethodUSD := 0.0
+	var methodTotal models.PaymentMethodTotal
 	for method, totals := range report.PaymentMethods {
 		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), method)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), totals.TL)
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), totals.USD)
-		totalMethodTL += totals.TL
-		totalMethodUSD += totals.USD
+		for i, currency := range currencies {
+			f.SetCellValue(sheetName, excelCell(i+2, row), totals.Currencies[currency].Float64())
+		}
+		f.SetCellValue(sheetName, excelCell(goldCol, row), totals.GoldGrams)
+		methodTotal = sumMethodTotals(methodTotal, totals)
 		row++
 	}
 
 	// Payment method total row
 	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "Genel Toplam")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), totalMethodTL)
-	f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), totalMethodUSD)
+	for i, currency := range currencies {
+		f.SetCellValue(sheetName, excelCell(i+2, row), methodTotal.Currencies[currency].Float64())
+	}
+	f.SetCellValue(sheetName, excelCell(goldCol, row), methodTotal.GoldGrams)
 	row += 2
 
 	// Project Summary Table
@@ -155,22 +179,307 @@ func (h *ExportHandler) writeWeeklyReportToExcel(f *excelize.File, sheetName str
 	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), "Tutar (USD)")
 	row++
 
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "HAFTALIK MKM")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), report.ProjectSummary.MKM)
+	for _, project := range services.ReportProjects(projects, report.ProjectSummary) {
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "HAFTALIK "+projectLabel(project))
+		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), report.ProjectSummary[project.Code].Float64())
+		row++
+	}
+
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "TOPLAM")
+	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), sumProjectTotal(report.ProjectSummary).Float64())
+	row += 2
+
+	row = h.writeKindTotalsToExcel(f, sheetName, row, "İşlem Türü", report.KindSummary, 0)
+	row = h.writeUnconfirmedToExcel(f, sheetName, row, report.Unconfirmed)
 	row++
 
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "HAFTALIK MSM")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), report.ProjectSummary.MSM)
+	h.writeGoldTotalsToExcel(f, sheetName, row, "Altın Tahsilatları", report.GoldSummary, 0)
+}
+
+// kindTotalLine is one labelled line of a KindTotal
+type kindTotalLine struct {
+	label  string
+	amount money.Amount
+}
+
+// kindTotalLines lists gross collections, refunds, reversals and the net total
+// as separate lines, in the order the exports show them
+func kindTotalLines(totals models.KindTotal) []kindTotalLine {
+	return []kindTotalLine{
+		{"Tahsilat (Brüt)", totals.Collections},
+		{fmt.Sprintf("İade (%d)", totals.RefundCount), totals.Refunds},
+		{fmt.Sprintf("İptal / Karşılıksız (%d)", totals.ReversalCount), totals.Reversals},
+		{"NET TAHSİLAT", totals.Net},
+	}
+}
+
+// writeKindTotalsToExcel writes a titled table of the kind totals starting at
+// row and returns the row after it. headerStyle 0 leaves the header unstyled.
+func (h *ExportHandler) writeKindTotalsToExcel(f *excelize.File, sheetName string, row int, title string, totals models.KindTotal, headerStyle int) int {
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), title)
+	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), "Tutar (USD)")
+	if headerStyle != 0 {
+		f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
+	}
 	row++
 
+	for _, line := range kindTotalLines(totals) {
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), line.label)
+		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), line.amount.Float64())
+		row++
+	}
+	if headerStyle != 0 {
+		f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row-1), fmt.Sprintf("B%d", row-1), headerStyle)
+	}
+	return row
+}
+
+// unconfirmedLine describes the payments still awaiting review the way the
+// report pages do; ok is false if there are none
+func unconfirmedLine(total models.ReviewTotal) (line string, ok bool) {
+	if total.Count == 0 {
+		return "", false
+	}
+	return fmt.Sprintf("Onay bekleyen %d ödeme: $%.2f (toplam içinde %%%.2f)", total.Count, total.AmountUSD, total.Share), true
+}
+
+// writeUnconfirmedToExcel writes the count, USD total and share of the
+// payments still awaiting review at row and returns the row after it. Nothing
+// is written if there are none.
+func (h *ExportHandler) writeUnconfirmedToExcel(f *excelize.File, sheetName string, row int, total models.ReviewTotal) int {
+	if total.Count == 0 {
+		return row
+	}
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("Onay bekleyen (%d ödeme)", total.Count))
+	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), total.AmountUSD.Float64())
+	f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), fmt.Sprintf("toplam içinde %%%.2f", total.Share))
+	return row + 1
+}
+
+// writeGoldTotalsToExcel writes a titled table of the gold totals by unit,
+// with quantity and pure gold content next to the USD value, starting at row
+// and returns the row after it. Nothing is written if there is no gold.
+func (h *ExportHandler) writeGoldTotalsToExcel(f *excelize.File, sheetName string, row int, title string, totals map[string]models.GoldTotal, headerStyle int) int {
+	if len(totals) == 0 {
+		return row
+	}
+
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), title)
+	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), "Miktar")
+	f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), "Has Altın (gr)")
+	f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), "Tutar (USD)")
+	if headerStyle != 0 {
+		f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row), headerStyle)
+	}
+	row++
+
+	var totalGrams float64
+	var totalUSD money.Amount
+	for _, unit := range sortedGoldUnits(totals) {
+		total := totals[unit]
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), unit)
+		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), total.Quantity.Float64())
+		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), total.FineGrams)
+		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), total.USD.Float64())
+		totalGrams += total.FineGrams
+		totalUSD += total.USD
+		row++
+	}
+
 	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "TOPLAM")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), report.ProjectSummary.MKM+report.ProjectSummary.MSM)
+	f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), math.Round(totalGrams*1000)/1000)
+	f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), totalUSD.Float64())
+	if headerStyle != 0 {
+		f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row), headerStyle)
+	}
+	return row + 1
+}
+
+// sortedGoldUnits returns the unit codes of gold totals in a stable order
+func sortedGoldUnits(totals map[string]models.GoldTotal) []string {
+	units := make([]string, 0, len(totals))
+	for unit := range totals {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	return units
+}
+
+// methodCurrencies returns the currencies collected in the given payment method
+// tables: TL and USD always, then any other currency in alphabetical order
+func methodCurrencies(tables ...map[string]models.PaymentMethodTotal) []string {
+	others := []string{}
+	seen := map[string]bool{models.CurrencyTL: true, models.CurrencyUSD: true}
+	for _, table := range tables {
+		for _, totals := range table {
+			for currency := range totals.Currencies {
+				if !seen[currency] {
+					seen[currency] = true
+					others = append(others, currency)
+				}
+			}
+		}
+	}
+	sort.Strings(others)
+	return append([]string{models.CurrencyTL, models.CurrencyUSD}, others...)
+}
+
+// sumMethodTotals adds up payment method lines, currency by currency
+func sumMethodTotals(lines ...models.PaymentMethodTotal) models.PaymentMethodTotal {
+	sum := models.PaymentMethodTotal{Currencies: map[string]money.Amount{}}
+	for _, line := range lines {
+		for currency, amount := range line.Currencies {
+			sum.Currencies[currency] += amount
+		}
+		sum.GoldGrams = math.Round((sum.GoldGrams+line.GoldGrams)*1000) / 1000
+		sum.TotalUSD += line.TotalUSD
+	}
+	return sum
+}
+
+// formatCurrencyAmount formats an amount for the PDF with its currency sign or code
+func formatCurrencyAmount(amount money.Amount, currency string) string {
+	switch currency {
+	case models.CurrencyTL:
+		return fmt.Sprintf("₺%.2f", amount)
+	case models.CurrencyUSD:
+		return fmt.Sprintf("$%.2f", amount)
+	default:
+		return fmt.Sprintf("%.2f %s", amount, currency)
+	}
+}
+
+// excelCell returns the name of the cell in column col (1 = A) of row
+func excelCell(col, row int) string {
+	cell, _ := excelize.CoordinatesToCellName(col, row)
+	return cell
+}
+
+// projectsTitle names the projects in a report title, e.g. "MODEL KUYUM
+// MERKEZİ-MODEL SANAYİ MERKEZİ"
+func projectsTitle(projects []models.Project) string {
+	labels := make([]string, len(projects))
+	for i, project := range projects {
+		labels[i] = projectLabel(project)
+	}
+	return strings.Join(labels, "-")
+}
+
+// projectLabel is the upper case display name of a project used in the exports
+func projectLabel(project models.Project) string {
+	if project.Name == "" {
+		return project.Code
+	}
+	return strings.ToUpperSpecial(unicode.TurkishCase, project.Name)
+}
+
+// sumProjectTotal adds up the totals of all projects
+func sumProjectTotal(totals models.ProjectTotal) money.Amount {
+	var sum money.Amount
+	for _, amount := range totals {
+		sum += amount
+	}
+	return sum
+}
+
+// projectMethodTables returns the payment method table of each project with
+// its title, followed by the title of the combined table
+func projectMethodTables(projects []models.Project, tables map[string]map[string]models.PaymentMethodTotal, titleFormat, generalTitle string) ([]string, []map[string]models.PaymentMethodTotal) {
+	var titles []string
+	var byProject []map[string]models.PaymentMethodTotal
+	for _, project := range projects {
+		titles = append(titles, fmt.Sprintf(titleFormat, projectLabel(project)))
+		byProject = append(byProject, tables[project.Code])
+	}
+	return append(titles, generalTitle), byProject
+}
+
+// writeMethodTablesToExcel writes the payment method table of each project and
+// the combined table side by side starting at row and returns the row after
+// them. titles has one title per project and then the combined table's. Each
+// table shows the amounts collected per currency, the pure gold content if
+// there was gold, and the USD total of everything.
+func (h *ExportHandler) writeMethodTablesToExcel(f *excelize.File, sheetName string, row int, titles []string, byProject []map[string]models.PaymentMethodTotal, withTotals bool, headerStyle int) int {
+	currencies := methodCurrencies(byProject...)
+	withGold := false
+	for _, table := range byProject {
+		for _, line := range table {
+			withGold = withGold || line.GoldGrams != 0
+		}
+	}
+	width := len(currencies) + 2
+	if withGold {
+		width++
+	}
+
+	methods := []string{"Banka Havalesi", "Nakit", "Çek"}
+	tables := make([][]models.PaymentMethodTotal, len(byProject)+1)
+	for _, method := range methods {
+		var lines []models.PaymentMethodTotal
+		for t, table := range byProject {
+			tables[t] = append(tables[t], table[method])
+			lines = append(lines, table[method])
+		}
+		tables[len(byProject)] = append(tables[len(byProject)], sumMethodTotals(lines...))
+	}
+	lastCol, _ := excelize.ColumnNumberToName(len(tables) * width)
+
+	for t, lines := range tables {
+		first := t*width + 1
+		f.SetCellValue(sheetName, excelCell(first, row), titles[t])
+		f.SetCellStyle(sheetName, excelCell(first, row), excelCell(first, row), headerStyle)
+
+		// Header
+		col := first
+		f.SetCellValue(sheetName, excelCell(col, row+1), "Ödeme Nedeni")
+		for _, currency := range currencies {
+			col++
+			f.SetCellValue(sheetName, excelCell(col, row+1), "Tutar "+currency)
+		}
+		if withGold {
+			col++
+			f.SetCellValue(sheetName, excelCell(col, row+1), "Altın (Has gr)")
+		}
+		f.SetCellValue(sheetName, excelCell(col+1, row+1), "Toplam USD")
+
+		lineRow := row + 2
+		writeLine := func(label string, line models.PaymentMethodTotal) {
+			col := first
+			f.SetCellValue(sheetName, excelCell(col, lineRow), label)
+			for _, currency := range currencies {
+				col++
+				f.SetCellValue(sheetName, excelCell(col, lineRow), fmt.Sprintf("%.2f", line.Currencies[currency]))
+			}
+			if withGold {
+				col++
+				f.SetCellValue(sheetName, excelCell(col, lineRow), fmt.Sprintf("%.3f", line.GoldGrams))
+			}
+			f.SetCellValue(sheetName, excelCell(col+1, lineRow), fmt.Sprintf("%.2f", line.TotalUSD))
+			lineRow++
+		}
+		for i, method := range methods {
+			writeLine(method, lines[i])
+		}
+		if withTotals {
+			writeLine("Genel Toplam", sumMethodTotals(lines...))
+		}
+	}
+	f.SetCellStyle(sheetName, excelCell(1, row+1), excelCell(len(tables)*width, row+1), headerStyle)
+
+	row += 2 + len(methods)
+	if withTotals {
+		f.SetCellStyle(sheetName, excelCell(1, row), excelCell(len(tables)*width, row), headerStyle)
+		row++
+	}
+	f.SetColWidth(sheetName, "A", lastCol, 15)
+	return row
 }
 
 // writeWeeklyReportToPDF writes a weekly report to PDF
-func (h *ExportHandler) writeWeeklyReportToPDF(pdf *gofpdf.Fpdf, report models.WeeklyReport) {
+func (h *ExportHandler) writeWeeklyReportToPDF(pdf *gofpdf.Fpdf, report models.WeeklyReport, projects []models.Project) {
 	// Title
-	title := fmt.Sprintf("MODEL KUYUM-MODEL SANAYİ MERKEZİ TAHSİLATLAR TABLOSU %s-%s",
+	title := fmt.Sprintf("%s TAHSİLATLAR TABLOSU %s-%s",
+		projectsTitle(projects),
 		report.StartDate.Format("02/01/2006"),
 		report.EndDate.Format("02/01/2006"))
 	pdf.SetFont("Arial", "B", 14)
@@ -187,7 +496,7 @@ func (h *ExportHandler) writeWeeklyReportToPDF(pdf *gofpdf.Fpdf, report models.W
 	pdf.CellFormat(30, 6, "TOPLAM (USD)", "1", 0, "C", false, 0, "")
 	pdf.Ln(6)
 
-	totalCustomerUSD := 0.0
+	var totalCustomerUSD money.Amount
 	for customer, amount := range report.CustomerSummary {
 		pdf.CellFormat(80, 6, customer, "1", 0, "L", false, 0, "")
 		pdf.CellFormat(30, 6, fmt.Sprintf("$%.2f", amount), "1", 0, "R", false, 0, "")
@@ -206,28 +515,36 @@ func (h *ExportHandler) writeWeeklyReportToPDF(pdf *gofpdf.Fpdf, report models.W
 	pdf.Cell(0, 8, "Ödeme Şekli Özeti")
 	pdf.Ln(8)
 
+	// One column per currency collected, in that currency, narrowed to fit the page
+	currencies := methodCurrencies(report.PaymentMethods)
+	colWidth := math.Min(30, 140/float64(len(currencies)+1))
+
 	pdf.SetFont("Arial", "", 10)
 	pdf.CellFormat(50, 6, "Ödeme Şekli", "1", 0, "C", false, 0, "")
-	pdf.CellFormat(30, 6, "Toplam TL", "1", 0, "C", false, 0, "")
-	pdf.CellFormat(30, 6, "Toplam USD", "1", 0, "C", false, 0, "")
+	for _, currency := range currencies {
+		pdf.CellFormat(colWidth, 6, "Toplam "+currency, "1", 0, "C", false, 0, "")
+	}
+	pdf.CellFormat(colWidth, 6, "Altın (Has gr)", "1", 0, "C", false, 0, "")
 	pdf.Ln(6)
 
-	totalMethodTL := 0.0
-	totalMethodUSD := 0.0
+	var methodTotal models.PaymentMethodTotal
 	for method, totals := range report.PaymentMethods {
 		pdf.CellFormat(50, 6, method, "1", 0, "L", false, 0, "")
-		pdf.CellFormat(30, 6, fmt.Sprintf("₺%.2f", totals.TL), "1", 0, "R", false, 0, "")
-		pdf.CellFormat(30, 6, fmt.Sprintf("$%.2f", totals.USD), "1", 0, "R", false, 0, "")
+		for _, currency := range currencies {
+			pdf.CellFormat(colWidth, 6, formatCurrencyAmount(totals.Currencies[currency], currency), "1", 0, "R", false, 0, "")
+		}
+		pdf.CellFormat(colWidth, 6, fmt.Sprintf("%.3f g", totals.GoldGrams), "1", 0, "R", false, 0, "")
 		pdf.Ln(6)
-		totalMethodTL += totals.TL
-		totalMethodUSD += totals.USD
+		methodTotal = sumMethodTotals(methodTotal, totals)
 	}
 
 	// Payment method total row
 	pdf.SetFont("Arial", "B", 10)
 	pdf.CellFormat(50, 6, "Genel Toplam", "1", 0, "C", false, 0, "")
-	pdf.CellFormat(30, 6, fmt.Sprintf("₺%.2f", totalMethodTL), "1", 0, "R", false, 0, "")
-	pdf.CellFormat(30, 6, fmt.Sprintf("$%.2f", totalMethodUSD), "1", 0, "R", false, 0, "")
+	for _, currency := range currencies {
+		pdf.CellFormat(colWidth, 6, formatCurrencyAmount(methodTotal.Currencies[currency], currency), "1", 0, "R", false, 0, "")
+	}
+	pdf.CellFormat(colWidth, 6, fmt.Sprintf("%.3f g", methodTotal.GoldGrams), "1", 0, "R", false, 0, "")
 	pdf.Ln(10)
 
 	// Project Summary Table
@@ -240,22 +557,68 @@ func (h *ExportHandler) writeWeeklyReportToPDF(pdf *gofpdf.Fpdf, report models.W
 	pdf.CellFormat(30, 6, "Tutar (USD)", "1", 0, "C", false, 0, "")
 	pdf.Ln(6)
 
-	pdf.CellFormat(50, 6, "HAFTALIK MKM", "1", 0, "L", false, 0, "")
-	pdf.CellFormat(30, 6, fmt.Sprintf("$%.2f", report.ProjectSummary.MKM), "1", 0, "R", false, 0, "")
-	pdf.Ln(6)
-
-	pdf.CellFormat(50, 6, "HAFTALIK MSM", "1", 0, "L", false, 0, "")
-	pdf.CellFormat(30, 6, fmt.Sprintf("$%.2f", report.ProjectSummary.MSM), "1", 0, "R", false, 0, "")
-	pdf.Ln(6)
+	for _, project := range services.ReportProjects(projects, report.ProjectSummary) {
+		pdf.CellFormat(50, 6, "HAFTALIK "+projectLabel(project), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 6, fmt.Sprintf("$%.2f", report.ProjectSummary[project.Code]), "1", 0, "R", false, 0, "")
+		pdf.Ln(6)
+	}
 
 	pdf.SetFont("Arial", "B", 10)
 	pdf.CellFormat(50, 6, "TOPLAM", "1", 0, "C", false, 0, "")
-	pdf.CellFormat(30, 6, fmt.Sprintf("$%.2f", report.ProjectSummary.MKM+report.ProjectSummary.MSM), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(30, 6, fmt.Sprintf("$%.2f", sumProjectTotal(report.ProjectSummary)), "1", 0, "R", false, 0, "")
+	pdf.Ln(10)
+
+	// Refunds and reversals, already netted out of the totals above
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(0, 8, "İşlem Türü Özeti")
+	pdf.Ln(8)
+
+	lines := kindTotalLines(report.KindSummary)
+	for i, line := range lines {
+		if i == len(lines)-1 {
+			pdf.SetFont("Arial", "B", 10)
+		} else {
+			pdf.SetFont("Arial", "", 10)
+		}
+		pdf.CellFormat(50, 6, line.label, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 6, fmt.Sprintf("$%.2f", line.amount), "1", 0, "R", false, 0, "")
+		pdf.Ln(6)
+	}
+	if line, ok := unconfirmedLine(report.Unconfirmed); ok {
+		pdf.SetFont("Arial", "I", 10)
+		pdf.Cell(0, 6, line)
+		pdf.Ln(6)
+	}
+
+	// Gold payments by unit, with their USD value at the gold price of the day
+	if len(report.GoldSummary) == 0 {
+		return
+	}
+	pdf.Ln(4)
+	pdf.SetFont("Arial", "B", 12)
+	pdf.Cell(0, 8, "Altın Tahsilatları")
+	pdf.Ln(8)
+
+	pdf.SetFont("Arial", "", 10)
+	pdf.CellFormat(50, 6, "Birim", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(30, 6, "Miktar", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(30, 6, "Has Altın (gr)", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(30, 6, "Tutar (USD)", "1", 0, "C", false, 0, "")
+	pdf.Ln(6)
+
+	for _, unit := range sortedGoldUnits(report.GoldSummary) {
+		total := report.GoldSummary[unit]
+		pdf.CellFormat(50, 6, unit, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 6, fmt.Sprintf("%.2f", total.Quantity), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(30, 6, fmt.Sprintf("%.3f g", total.FineGrams), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(30, 6, fmt.Sprintf("$%.2f", total.USD), "1", 0, "R", false, 0, "")
+		pdf.Ln(6)
+	}
 }
 
 // getAllPayments retrieves all payments from the database
 func (h *ExportHandler) getAllPayments() ([]models.PaymentRecord, error) {
-	query := `SELECT id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name, amount_usd, exchange_rate, created_at, raw_data FROM payments ORDER BY payment_date`
+	query := `SELECT id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name, amount_usd, exchange_rate, created_at, raw_data, kind, original_payment_id, gold_grams, COALESCE(review_status, '') FROM payments ORDER BY payment_date`
 	rows, err := h.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -279,6 +642,10 @@ func (h *ExportHandler) getAllPayments() ([]models.PaymentRecord, error) {
 			&payment.ExchangeRate,
 			&payment.CreatedAt,
 			&payment.RawData,
+			&payment.Kind,
+			&payment.OriginalID,
+			&payment.GoldGrams,
+			&payment.ReviewStatus,
 		)
 		if err != nil {
 			return nil, err
@@ -302,7 +669,8 @@ func (h *ExportHandler) ExportYearlyExcel(c *gin.Context) {
 	var payments []models.PaymentRecord
 	query := `
 		SELECT id, customer_name, amount, currency, payment_method, payment_date, 
-		       account_name, project, location, amount_usd, exchange_rate, created_at, raw_data
+		       account_name, project, location, amount_usd, exchange_rate, created_at, raw_data, kind, original_payment_id, gold_grams,
+		       COALESCE(review_status, '')
 		FROM payments 
 		WHERE strftime('%Y', payment_date) = ?
 		ORDER BY payment_date ASC`
@@ -331,6 +699,10 @@ func (h *ExportHandler) ExportYearlyExcel(c *gin.Context) {
 			&payment.ExchangeRate,
 			&payment.CreatedAt,
 			&rawData,
+			&payment.Kind,
+			&payment.OriginalID,
+			&payment.GoldGrams,
+			&payment.ReviewStatus,
 		)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -355,10 +727,25 @@ func (h *ExportHandler) ExportYearlyExcel(c *gin.Context) {
 			AmountUSD:     pr.AmountUSD,
 			ExchangeRate:  pr.ExchangeRate,
 			CreatedAt:     pr.CreatedAt,
+			Kind:          pr.Kind,
+			OriginalID:    pr.OriginalID,
+			GoldGrams:     pr.GoldGrams,
+			ReviewStatus:  pr.ReviewStatus,
 		}
 		paymentsForReport = append(paymentsForReport, payment)
 	}
 
+	projects, err := loadProjects(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	locations, err := loadLocations(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Generate yearly report
 	yearlyReport := services.GenerateYearlyReport(paymentsForReport, year)
 
@@ -370,7 +757,7 @@ func (h *ExportHandler) ExportYearlyExcel(c *gin.Context) {
 	sheetName := fmt.Sprintf("%d Yılı Tahsilat Raporu", year)
 	f.SetSheetName("Sheet1", sheetName)
 	
-	h.writeYearlyReportToExcel(f, sheetName, yearlyReport)
+	h.writeYearlyReportToExcel(f, sheetName, yearlyReport, projects, locations)
 
 	// Create monthly sheets
 	if yearlyReport.MonthlyReports != nil {
@@ -401,7 +788,7 @@ func (h *ExportHandler) ExportYearlyExcel(c *gin.Context) {
 			}
 			
 			f.NewSheet(monthName)
-			h.writeMonthlyReportToExcel(f, monthName, monthReport)
+			h.writeMonthlyReportToExcel(f, monthName, monthReport, projects)
 		}
 	}
 
@@ -418,7 +805,7 @@ func (h *ExportHandler) ExportYearlyExcel(c *gin.Context) {
 }
 
 // writeYearlyReportToExcel writes a yearly report to an Excel sheet
-func (h *ExportHandler) writeYearlyReportToExcel(f *excelize.File, sheetName string, report models.YearlyReport) {
+func (h *ExportHandler) writeYearlyReportToExcel(f *excelize.File, sheetName string, report models.YearlyReport, projects []models.Project, locations []models.Location) {
 	row := 1
 
 	// Title
@@ -447,138 +834,80 @@ func (h *ExportHandler) writeYearlyReportToExcel(f *excelize.File, sheetName str
 	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
 	row++
 
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "YILLIK MKM")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", report.ProjectSummary.MKM))
-	row++
-
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "YILLIK MSM")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", report.ProjectSummary.MSM))
-	row++
+	projects = services.ReportProjects(projects, report.ProjectSummary)
+	for _, project := range projects {
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "YILLIK "+projectLabel(project))
+		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", report.ProjectSummary[project.Code]))
+		row++
+	}
 
 	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "TOPLAM")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", report.ProjectSummary.MKM+report.ProjectSummary.MSM))
+	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", sumProjectTotal(report.ProjectSummary)))
 	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
 	row += 3
 
-	// Payment Methods Summary - Three tables side by side
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "YILLIK PROJE BAZINDA ÖDEME ŞEKLİ DAĞILIMI")
-	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), headerStyle)
+	// Refunds and reversals, already netted out of every total in the sheet
+	row = h.writeKindTotalsToExcel(f, sheetName, row, "YILLIK İŞLEM TÜRÜ ÖZETİ", report.KindSummary, headerStyle)
+	row = h.writeUnconfirmedToExcel(f, sheetName, row, report.Unconfirmed)
 	row += 2
 
-	// MKM Table
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("%d YILI MKM TAHSİLATLAR", report.Year))
-	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), headerStyle)
-	
-	// MSM Table
-	f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), fmt.Sprintf("%d YILI MSM TAHSİLATLAR", report.Year))
-	f.SetCellStyle(sheetName, fmt.Sprintf("D%d", row), fmt.Sprintf("D%d", row), headerStyle)
-	
-	// GENEL Table
-	f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), fmt.Sprintf("%d YILI GENEL", report.Year))
-	f.SetCellStyle(sheetName, fmt.Sprintf("G%d", row), fmt.Sprintf("G%d", row), headerStyle)
-	row++
-
-	// Headers for all three tables
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "Ödeme Nedeni")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), "Toplam TL")
-	f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), "Toplam USD")
-	
-	f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), "Ödeme Nedeni")
-	f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), "Toplam TL")
-	f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), "Toplam USD")
-	
-	f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), "Ödeme Nedeni")
-	f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), "Toplam TL")
-	f.SetCellValue(sheetName, fmt.Sprintf("I%d", row), "Toplam USD")
-	
-	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("I%d", row), headerStyle)
-	row++
-
-	// Payment method data
-	methods := []string{"Banka Havalesi", "Nakit", "Çek"}
-	for _, method := range methods {
-		// MKM data
-		mkmData := report.MKMPaymentMethods[method]
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), method)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", mkmData.TL))
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), fmt.Sprintf("%.2f", mkmData.TotalUSD))
-		
-		// MSM data
-		msmData := report.MSMPaymentMethods[method]
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), method)
-		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), fmt.Sprintf("%.2f", msmData.TL))
-		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), fmt.Sprintf("%.2f", msmData.TotalUSD))
-		
-		// GENEL data (combined)
-		f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), method)
-		f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), fmt.Sprintf("%.2f", mkmData.TL+msmData.TL))
-		f.SetCellValue(sheetName, fmt.Sprintf("I%d", row), fmt.Sprintf("%.2f", mkmData.TotalUSD+msmData.TotalUSD))
-		
-		row++
+	// Gold payments by unit; their USD value is included in the totals above
+	if len(report.GoldSummary) > 0 {
+		row = h.writeGoldTotalsToExcel(f, sheetName, row, "YILLIK ALTIN TAHSİLATLARI", report.GoldSummary, headerStyle)
+		row += 2
 	}
 
-	// Totals row
-	var mkmTotalTL, mkmTotalUSD, msmTotalTL, msmTotalUSD float64
-	for _, data := range report.MKMPaymentMethods {
-		mkmTotalTL += data.TL
-		mkmTotalUSD += data.TotalUSD
-	}
-	for _, data := range report.MSMPaymentMethods {
-		msmTotalTL += data.TL
-		msmTotalUSD += data.TotalUSD
-	}
+	// Payment Methods Summary - One table per project and the combined table side by side
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "YILLIK PROJE BAZINDA ÖDEME ŞEKLİ DAĞILIMI")
+	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), headerStyle)
+	row += 2
 
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "Genel Toplam")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", mkmTotalTL))
-	f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), fmt.Sprintf("%.2f", mkmTotalUSD))
-	
-	f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), "Toplam")
-	f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), fmt.Sprintf("%.2f", msmTotalTL))
-	f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), fmt.Sprintf("%.2f", msmTotalUSD))
-	
-	f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), "Toplam")
-	f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), fmt.Sprintf("%.2f", mkmTotalTL+msmTotalTL))
-	f.SetCellValue(sheetName, fmt.Sprintf("I%d", row), fmt.Sprintf("%.2f", mkmTotalUSD+msmTotalUSD))
-	
-	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("I%d", row), headerStyle)
-	row += 3
+	titles, byProject := projectMethodTables(projects, report.ProjectPaymentMethods,
+		fmt.Sprintf("%d YILI %%s TAHSİLATLAR", report.Year), fmt.Sprintf("%d YILI GENEL", report.Year))
+	row = h.writeMethodTablesToExcel(f, sheetName, row, titles, byProject, true, headerStyle)
+	row += 2
 
 	// Location Summary
 	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "YILLIK LOKASYON BAZLI TAHSİLAT DETAYLARI")
 	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), headerStyle)
 	row++
 
+	// One column per project, then the location total
+	totalCol := len(projects) + 2
 	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "Lokasyon")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), "MKM YILLIK (USD)")
-	f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), "MSM YILLIK (USD)")
-	f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), "TOPLAM (USD)")
-	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row), headerStyle)
+	for i, project := range projects {
+		f.SetCellValue(sheetName, excelCell(i+2, row), projectLabel(project)+" YILLIK (USD)")
+	}
+	f.SetCellValue(sheetName, excelCell(totalCol, row), "TOPLAM (USD)")
+	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), excelCell(totalCol, row), headerStyle)
 	row++
 
-	var totalMKM, totalMSM float64
-	for location, summary := range report.LocationSummary {
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), location)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", summary.MKM))
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), fmt.Sprintf("%.2f", summary.MSM))
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), fmt.Sprintf("%.2f", summary.Total))
-		totalMKM += summary.MKM
-		totalMSM += summary.MSM
+	totals := models.ProjectTotal{}
+	for _, location := range services.ReportLocations(locations, services.LocationCodes(report.LocationSummary)) {
+		summary := report.LocationSummary[location.Code]
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), location.Label)
+		for i, project := range projects {
+			f.SetCellValue(sheetName, excelCell(i+2, row), fmt.Sprintf("%.2f", summary.Projects[project.Code]))
+			totals[project.Code] += summary.Projects[project.Code]
+		}
+		f.SetCellValue(sheetName, excelCell(totalCol, row), fmt.Sprintf("%.2f", summary.Total))
 		row++
 	}
 
 	// Location totals
 	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "TOPLAM")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", totalMKM))
-	f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), fmt.Sprintf("%.2f", totalMSM))
-	f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), fmt.Sprintf("%.2f", totalMKM+totalMSM))
-	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row), headerStyle)
+	for i, project := range projects {
+		f.SetCellValue(sheetName, excelCell(i+2, row), fmt.Sprintf("%.2f", totals[project.Code]))
+	}
+	f.SetCellValue(sheetName, excelCell(totalCol, row), fmt.Sprintf("%.2f", sumProjectTotal(totals)))
+	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), excelCell(totalCol, row), headerStyle)
 
 	// Auto-fit columns
 	f.SetColWidth(sheetName, "A", "I", 15)
 }
 
 // writeMonthlyReportToExcel writes a monthly report to an Excel sheet
-func (h *ExportHandler) writeMonthlyReportToExcel(f *excelize.File, sheetName string, report models.MonthlyReport) {
+func (h *ExportHandler) writeMonthlyReportToExcel(f *excelize.File, sheetName string, report models.MonthlyReport, projects []models.Project) {
 	row := 1
 
 	// Title
@@ -607,76 +936,34 @@ func (h *ExportHandler) writeMonthlyReportToExcel(f *excelize.File, sheetName st
 	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
 	row++
 
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "AYLIK MKM")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", report.ProjectSummary.MKM))
-	row++
-
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "AYLIK MSM")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", report.ProjectSummary.MSM))
-	row++
+	projects = services.ReportProjects(projects, report.ProjectSummary)
+	for _, project := range projects {
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "AYLIK "+projectLabel(project))
+		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", report.ProjectSummary[project.Code]))
+		row++
+	}
 
 	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "TOPLAM")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", report.ProjectSummary.MKM+report.ProjectSummary.MSM))
+	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", sumProjectTotal(report.ProjectSummary)))
 	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
 	row += 3
 
-	// Payment Methods Summary - Three tables side by side
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "AYLIK PROJE BAZINDA ÖDEME ŞEKLİ DAĞILIMI")
-	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), headerStyle)
+	// Refunds and reversals, already netted out of every total in the sheet
+	row = h.writeKindTotalsToExcel(f, sheetName, row, "AYLIK İŞLEM TÜRÜ ÖZETİ", report.KindSummary, headerStyle)
+	row = h.writeUnconfirmedToExcel(f, sheetName, row, report.Unconfirmed)
 	row += 2
 
-	// MKM Table
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "MKM TAHSİLATLAR")
-	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), headerStyle)
-	
-	// MSM Table
-	f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), "MSM TAHSİLATLAR")
-	f.SetCellStyle(sheetName, fmt.Sprintf("D%d", row), fmt.Sprintf("D%d", row), headerStyle)
-	
-	// GENEL Table
-	f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), "GENEL")
-	f.SetCellStyle(sheetName, fmt.Sprintf("G%d", row), fmt.Sprintf("G%d", row), headerStyle)
-	row++
-
-	// Headers for all three tables
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "Ödeme Nedeni")
-	f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), "Toplam TL")
-	f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), "Toplam USD")
-	
-	f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), "Ödeme Nedeni")
-	f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), "Toplam TL")
-	f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), "Toplam USD")
-	
-	f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), "Ödeme Nedeni")
-	f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), "Toplam TL")
-	f.SetCellValue(sheetName, fmt.Sprintf("I%d", row), "Toplam USD")
-	
-	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("I%d", row), headerStyle)
-	row++
-
-	// Payment method data
-	methods := []string{"Banka Havalesi", "Nakit", "Çek"}
-	for _, method := range methods {
-		// MKM data
-		mkmData := report.MKMPaymentMethods[method]
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), method)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), fmt.Sprintf("%.2f", mkmData.TL))
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), fmt.Sprintf("%.2f", mkmData.TotalUSD))
-		
-		// MSM data
-		msmData := report.MSMPaymentMethods[method]
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), method)
-		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), fmt.Sprintf("%.2f", msmData.TL))
-		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), fmt.Sprintf("%.2f", msmData.TotalUSD))
-		
-		// GENEL data (combined)
-		f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), method)
-		f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), fmt.Sprintf("%.2f", mkmData.TL+msmData.TL))
-		f.SetCellValue(sheetName, fmt.Sprintf("I%d", row), fmt.Sprintf("%.2f", mkmData.TotalUSD+msmData.TotalUSD))
-		
-		row++
+	// Gold payments by unit; their USD value is included in the totals above
+	if len(report.GoldSummary) > 0 {
+		row = h.writeGoldTotalsToExcel(f, sheetName, row, "AYLIK ALTIN TAHSİLATLARI", report.GoldSummary, headerStyle)
+		row += 2
 	}
 
-	// Auto-fit columns
-	f.SetColWidth(sheetName, "A", "I", 15)
+	// Payment Methods Summary - One table per project and the combined table side by side
+	f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), "AYLIK PROJE BAZINDA ÖDEME ŞEKLİ DAĞILIMI")
+	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), headerStyle)
+	row += 2
+
+	titles, byProject := projectMethodTables(projects, report.ProjectPaymentMethods, "%s TAHSİLATLAR", "GENEL")
+	h.writeMethodTablesToExcel(f, sheetName, row, titles, byProject, false, headerStyle)
 }
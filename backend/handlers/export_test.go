@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExportExcelUnconfirmed(t *testing.T) {
+	db := newTestDB(t)
+	for _, status := range []any{models.ReviewStatusPending, models.ReviewStatusConfirmed, nil} {
+		_, err := db.Exec(`
+			INSERT INTO payments (customer_name, payment_date, amount, currency, payment_method, location, project,
+				account_name, amount_usd, exchange_rate, raw_data, review_status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, "Ahmet Yılmaz", time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), 100, "USD", "Nakit", "OFIS", "MKM",
+			"Kasa", 100, 1, "{}", status)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := NewExportHandler(db)
+	payments, err := h.getAllPayments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := services.GenerateWeeklyReports(payments)
+	if len(reports) != 1 {
+		t.Fatalf("got %d weekly reports, want 1", len(reports))
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	h.writeWeeklyReportToExcel(f, "Sheet1", reports[0], nil)
+	rows, err := f.GetRows("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if len(row) == 0 || row[0] != "Onay bekleyen (1 ödeme)" {
+			continue
+		}
+		if got := fmt.Sprint(row[1:]); got != "[100 toplam içinde %33.33]" {
+			t.Errorf("unconfirmed row = %s, want [100 toplam içinde %%33.33]", got)
+		}
+		return
+	}
+	t.Errorf("weekly sheet has no unconfirmed row: %v", rows)
+}
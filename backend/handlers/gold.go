@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// settingGoldUnits is the settings key of the gold units and their purity
+const settingGoldUnits = "gold_units"
+
+// GetGoldUnits returns the gold units payments can be made in
+func (h *SettingsHandler) GetGoldUnits(c *gin.Context) {
+	units, err := loadGoldUnits(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, units)
+}
+
+// UpdateGoldUnits replaces the gold units, e.g. to change a purity factor.
+// Stored payments keep their value until they are reprocessed.
+func (h *SettingsHandler) UpdateGoldUnits(c *gin.Context) {
+	var units []models.GoldUnit
+	if err := c.ShouldBindJSON(&units); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+	if err := services.ValidateGoldUnits(units); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := saveSetting(h.db, settingGoldUnits, units); err != nil {
+		log.Printf("Error saving gold units: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Gold units updated: %d units", len(units))
+	c.JSON(http.StatusOK, units)
+}
+
+// GetGoldPrices returns the gold price table, optionally limited to ?from= and ?to= (YYYY-MM-DD)
+func (h *SettingsHandler) GetGoldPrices(c *gin.Context) {
+	prices, err := loadGoldPrices(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, to := c.Query("from"), c.Query("to")
+	filtered := []models.GoldPrice{}
+	for _, price := range prices {
+		if (from == "" || price.Date >= from) && (to == "" || price.Date <= to) {
+			filtered = append(filtered, price)
+		}
+	}
+	c.JSON(http.StatusOK, filtered)
+}
+
+// UpdateGoldPrices adds or replaces the gold prices of the given dates
+func (h *SettingsHandler) UpdateGoldPrices(c *gin.Context) {
+	var prices []models.GoldPrice
+	if err := c.ShouldBindJSON(&prices); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+	for _, price := range prices {
+		if err := services.ValidateGoldPrice(price); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	for _, price := range prices {
+		_, err := tx.Exec(`
+			INSERT INTO gold_prices (price_date, usd_per_gram, note, updated_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(price_date) DO UPDATE SET usd_per_gram = excluded.usd_per_gram, note = excluded.note, updated_at = excluded.updated_at
+		`, price.Date, price.USDPerGram, price.Note, time.Now())
+		if err != nil {
+			log.Printf("Error saving gold price for %s: %v", price.Date, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Gold prices updated: %d dates", len(prices))
+	c.JSON(http.StatusOK, gin.H{"message": "Gold prices saved", "saved": len(prices)})
+}
+
+// DeleteGoldPrice removes the gold price of one date
+func (h *SettingsHandler) DeleteGoldPrice(c *gin.Context) {
+	date := c.Param("date")
+	result, err := h.db.Exec(`DELETE FROM gold_prices WHERE price_date = ?`, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No gold price for " + date})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Gold price deleted", "date": date})
+}
+
+// loadGoldUnits returns the saved gold units, or the defaults if none were saved
+func loadGoldUnits(db *sql.DB) ([]models.GoldUnit, error) {
+	units := services.DefaultGoldUnits()
+	_, err := loadSetting(db, settingGoldUnits, &units)
+	return units, err
+}
+
+// loadGoldPrices returns the whole gold price table sorted by date
+func loadGoldPrices(db *sql.DB) ([]models.GoldPrice, error) {
+	rows, err := db.Query(`SELECT price_date, usd_per_gram, COALESCE(note, '') FROM gold_prices ORDER BY price_date`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []models.GoldPrice{}
+	for rows.Next() {
+		var price models.GoldPrice
+		if err := rows.Scan(&price.Date, &price.USDPerGram, &price.Note); err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+// setGoldValuation gives the processor the saved gold units and price table.
+// On error the processor keeps the default units and cannot value gold.
+func setGoldValuation(db *sql.DB, processor *services.PaymentProcessor) {
+	units, err := loadGoldUnits(db)
+	if err != nil {
+		log.Printf("Error loading gold units, using defaults: %v", err)
+		units = services.DefaultGoldUnits()
+	}
+	prices, err := loadGoldPrices(db)
+	if err != nil {
+		log.Printf("Error loading gold prices: %v", err)
+	}
+	processor.SetGoldValuation(units, prices)
+}
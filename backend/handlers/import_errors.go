@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/money"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// saveRowErrors stores the errors of an import batch together with the
+// original cell values of the failed rows
+func saveRowErrors(db sqlExecutor, batchID int64, rowErrors []models.RowError) error {
+	query := `
+		INSERT INTO import_row_errors (
+			batch_id, row_number, column_name, raw_value, customer_name, code, message, message_en, detail, row_data
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	for _, rowError := range rowErrors {
+		var rowData interface{}
+		if rowError.RowData != nil {
+			data, _ := json.Marshal(rowError.RowData)
+			rowData = string(data)
+		}
+		_, err := db.Exec(query, batchID, rowError.Row, rowError.Column, rowError.Value, rowError.Customer,
+			rowError.Code, rowError.Message, rowError.MessageEN, rowError.Detail, rowData)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordRejectedImport stores a rejected batch and its row errors for a strict
+// upload that failed validation, so the failed rows can still be downloaded
+func (h *UploadHandler) recordRejectedImport(input importInput, rowErrors []models.RowError) (int64, error) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	totalRows := len(input.rawPayments) + len(input.readErrors)
+	batchID, err := createImportBatch(tx, input, totalRows, models.ImportStatusRejected)
+	if err != nil {
+		return 0, err
+	}
+	if err := saveRowErrors(tx, batchID, rowErrors); err != nil {
+		return 0, err
+	}
+	if err := finishImportBatch(tx, batchID, 0, len(rowErrors)); err != nil {
+		return 0, err
+	}
+	return batchID, tx.Commit()
+}
+
+// ExportFailedRows returns the failed rows of an import batch as an Excel file
+// in the upload layout with an extra error column, so they can be fixed and
+// uploaded again on their own
+func (h *UploadHandler) ExportFailedRows(c *gin.Context) {
+	batchID := c.Param("id")
+
+	var fileName sql.NullString
+	err := h.db.QueryRow(`SELECT file_name FROM import_batches WHERE id = ?`, batchID).Scan(&fileName)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import batch not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT row_number, column_name, raw_value, message, row_data FROM import_row_errors
+		WHERE batch_id = ? AND row_number > 0 AND row_data IS NOT NULL
+		ORDER BY row_number, id
+	`, batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	// Several errors on the same row become one line with all messages
+	type failedRow struct {
+		rowNumber int
+		values    map[string]string
+		messages  []string
+	}
+	var failedRows []*failedRow
+	byRow := make(map[int]*failedRow)
+	for rows.Next() {
+		var rowNumber int
+		var column, value sql.NullString
+		var message, rowData string
+		if err := rows.Scan(&rowNumber, &column, &value, &message, &rowData); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		row, ok := byRow[rowNumber]
+		if !ok {
+			row = &failedRow{rowNumber: rowNumber}
+			if err := json.Unmarshal([]byte(rowData), &row.values); err != nil {
+				log.Printf("Invalid row data for import batch %s row %d: %v", batchID, rowNumber, err)
+			}
+			byRow[rowNumber] = row
+			failedRows = append(failedRows, row)
+		}
+		if value.String != "" {
+			message += fmt.Sprintf(" ('%s')", value.String)
+		}
+		if column.String != "" {
+			message = column.String + ": " + message
+		}
+		row.messages = append(row.messages, message)
+	}
+
+	if len(failedRows) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import batch has no failed rows"})
+		return
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "Hatalı Satırlar"
+	f.SetSheetName("Sheet1", sheetName)
+
+	// Optional columns are kept too so that refunds can be uploaded again
+	fields := append(append([]string{}, models.RawPaymentFields...), models.OptionalRawPaymentFields...)
+	headers := make([]interface{}, 0, len(fields)+2)
+	for _, field := range fields {
+		headers = append(headers, models.RawPaymentFieldHeaders[field])
+	}
+	headers = append(headers, "Kaynak Satır", "Hata")
+	f.SetSheetRow(sheetName, "A1", &headers)
+
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+	})
+	lastColumn, _ := excelize.ColumnNumberToName(len(headers))
+	f.SetCellStyle(sheetName, "A1", lastColumn+"1", headerStyle)
+
+	for i, row := range failedRows {
+		cells := make([]interface{}, 0, len(headers))
+		for _, field := range fields {
+			value := row.values[field]
+			if field == models.FieldOdenenTutar {
+				// Keep amounts numeric so the sheet can be uploaded again as is
+				if amount, err := money.ParseAmount(value); err == nil {
+					cells = append(cells, amount.Float64())
+					continue
+				}
+			}
+			cells = append(cells, value)
+		}
+		cells = append(cells, row.rowNumber, strings.Join(row.messages, "; "))
+		f.SetSheetRow(sheetName, fmt.Sprintf("A%d", i+2), &cells)
+	}
+
+	name := strings.TrimSuffix(fileName.String, ".xlsx")
+	name = strings.TrimSuffix(name, ".csv")
+	if name == "" {
+		name = "import-" + batchID
+	}
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-hatali-satirlar.xlsx\"", name))
+
+	if err := f.Write(c.Writer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write Excel file"})
+		return
+	}
+}
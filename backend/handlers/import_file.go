@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fileReader turns an uploaded file into raw payments plus row-level read errors,
+// using the column mapping of the selected import profile (nil for defaults)
+// and the amount locale of the upload ("" for the reader's default)
+type fileReader func(r io.Reader, columns map[string]string, amountLocale string) ([]models.RawPaymentData, []models.RowError, error)
+
+// UploadXLSX imports payments from a multipart Excel upload (form field "file").
+// An optional "sheet" form field selects the worksheet; the first sheet is used otherwise.
+// An optional "profile" form field (ID or name) selects an import profile.
+func (h *UploadHandler) UploadXLSX(c *gin.Context) {
+	sheet := c.PostForm("sheet")
+	// Numeric cells have no locale; amount_locale only applies to amounts typed as text
+	h.importUploadedFile(c, "Excel", "xlsx", func(r io.Reader, columns map[string]string, amountLocale string) ([]models.RawPaymentData, []models.RowError, error) {
+		return services.ReadXLSXPayments(r, sheet, columns, amountLocale)
+	})
+}
+
+// UploadCSV imports payments from a multipart CSV upload (form field "file").
+// Encoding and delimiter are detected automatically; "profile" works as for UploadXLSX.
+func (h *UploadHandler) UploadCSV(c *gin.Context) {
+	h.importUploadedFile(c, "CSV", "csv", services.ReadCSVPayments)
+}
+
+// importUploadedFile reads the multipart "file" field with the given reader and imports the rows.
+// kind names the file type in messages and source is recorded on the import batch.
+func (h *UploadHandler) importUploadedFile(c *gin.Context, kind, source string, read fileReader) {
+	input, ok := bindImportQuery(c)
+	if !ok {
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.UploadResponse{
+			Success: false,
+			Message: kind + " file is required (form field 'file')",
+			Errors:  uploadErrors(services.ErrCodeInvalidRequest, err),
+		})
+		return
+	}
+	defer file.Close()
+
+	log.Printf("Received %s upload: %s (%d bytes)", kind, header.Filename, header.Size)
+
+	var columns map[string]string
+	if ref := c.PostForm("profile"); ref != "" {
+		profile, err := loadImportProfile(h.db, ref)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.UploadResponse{
+				Success: false,
+				Message: "Import profile not found",
+				Errors:  uploadErrors(services.ErrCodeInvalidRequest, err),
+			})
+			return
+		}
+		log.Printf("Using import profile %d (%s)", profile.ID, profile.Name)
+		columns = profile.Columns
+	}
+
+	rawPayments, readErrors, err := read(file, columns, input.amountLocale)
+	if err != nil {
+		log.Printf("Failed to read %s upload %s: %v", kind, header.Filename, err)
+		c.JSON(http.StatusBadRequest, models.UploadResponse{
+			Success: false,
+			Message: "Failed to read " + kind + " file",
+			Errors:  uploadErrors(services.ErrCodeFileError, err),
+		})
+		return
+	}
+
+	if len(rawPayments) == 0 {
+		c.JSON(http.StatusBadRequest, models.UploadResponse{
+			Success: false,
+			Message: "No payment data found in " + kind + " file",
+			Errors:  readErrors,
+		})
+		return
+	}
+
+	input.fileName = header.Filename
+	input.source = source
+	input.rawPayments = rawPayments
+	input.readErrors = readErrors
+
+	h.respondImport(c, input)
+}
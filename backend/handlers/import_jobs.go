@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"tahsilat-raporu/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jobRetention is how long finished import jobs stay queryable
+const jobRetention = time.Hour
+
+// importJob is a background upload together with a channel that is closed and
+// replaced whenever its state changes, so SSE streams can wait for updates
+type importJob struct {
+	mu      sync.Mutex
+	info    models.ImportJob
+	changed chan struct{}
+}
+
+// snapshot returns a copy of the job state and the channel signalling the next change
+func (j *importJob) snapshot() (models.ImportJob, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.info, j.changed
+}
+
+// update applies fn to the job state and wakes up all waiting streams
+func (j *importJob) update(fn func(info *models.ImportJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.info)
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// importJobStore keeps import jobs in memory; they do not survive a restart
+type importJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*importJob
+}
+
+// newImportJobStore creates an empty job store
+func newImportJobStore() *importJobStore {
+	return &importJobStore{jobs: make(map[string]*importJob)}
+}
+
+// create registers a queued job for the upload and drops expired finished jobs
+func (s *importJobStore) create(input importInput) *importJob {
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+
+	job := &importJob{
+		info: models.ImportJob{
+			ID:        hex.EncodeToString(idBytes),
+			FileName:  input.fileName,
+			Source:    input.source,
+			Status:    models.JobStatusQueued,
+			Total:     len(input.rawPayments),
+			CreatedAt: time.Now(),
+		},
+		changed: make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, existing := range s.jobs {
+		info, _ := existing.snapshot()
+		if info.FinishedAt != nil && time.Since(*info.FinishedAt) > jobRetention {
+			delete(s.jobs, id)
+		}
+	}
+	s.jobs[job.info.ID] = job
+	return job
+}
+
+// get returns the job with the given ID, or nil if it is unknown or expired
+func (s *importJobStore) get(id string) *importJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[id]
+}
+
+// respondImport runs the import and writes the response, or with ?async=true
+// starts it as a background job and answers 202 with the job ID straight away
+func (h *UploadHandler) respondImport(c *gin.Context, input importInput) {
+	if !input.async {
+		response := h.importPayments(input)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	job := h.jobs.create(input)
+	jobID := job.info.ID
+	input.progress = func(stage string, done, total int) {
+		job.update(func(info *models.ImportJob) {
+			info.Status = models.JobStatusRunning
+			info.Stage = stage
+			info.Done = done
+			info.Total = total
+		})
+	}
+
+	go func() {
+		response := h.importPayments(input)
+		job.update(func(info *models.ImportJob) {
+			now := time.Now()
+			info.Status = models.JobStatusCompleted
+			if !response.Success {
+				info.Status = models.JobStatusFailed
+			}
+			info.FinishedAt = &now
+			info.Done = info.Total
+			info.Result = &response
+		})
+		log.Printf("Import job %s finished: Success=%t, Processed=%d", jobID, response.Success, response.Processed)
+	}()
+
+	log.Printf("Started import job %s for %s (%d rows)", jobID, input.fileName, len(input.rawPayments))
+	c.JSON(http.StatusAccepted, gin.H{
+		"success":    true,
+		"message":    "Import started",
+		"job_id":     jobID,
+		"status_url": "/api/imports/jobs/" + jobID,
+		"events_url": "/api/imports/jobs/" + jobID + "/events",
+	})
+}
+
+// GetImportJob returns the status of a background import, including the
+// upload response once it has finished
+func (h *UploadHandler) GetImportJob(c *gin.Context) {
+	job := h.jobs.get(c.Param("id"))
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+		return
+	}
+
+	info, _ := job.snapshot()
+	c.JSON(http.StatusOK, info)
+}
+
+// StreamImportJob streams the progress of a background import as server-sent
+// events. A "progress" event is sent on every change and a final "done" event
+// carries the finished job, after which the stream is closed.
+func (h *UploadHandler) StreamImportJob(c *gin.Context) {
+	job := h.jobs.get(c.Param("id"))
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		info, changed := job.snapshot()
+		if info.FinishedAt != nil {
+			c.SSEvent("done", info)
+			return false
+		}
+		c.SSEvent("progress", info)
+
+		select {
+		case <-changed:
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
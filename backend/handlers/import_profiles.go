@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportProfileHandler manages stored column-mapping profiles for file imports
+type ImportProfileHandler struct {
+	db *sql.DB
+}
+
+// NewImportProfileHandler creates a new import profile handler
+func NewImportProfileHandler(db *sql.DB) *ImportProfileHandler {
+	return &ImportProfileHandler{db: db}
+}
+
+// ListProfiles returns all import profiles
+func (h *ImportProfileHandler) ListProfiles(c *gin.Context) {
+	rows, err := h.db.Query(`SELECT id, name, description, columns, created_at, updated_at FROM import_profiles ORDER BY name`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	profiles := []models.ImportProfile{}
+	for rows.Next() {
+		profile, err := scanImportProfile(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		profiles = append(profiles, *profile)
+	}
+
+	c.JSON(http.StatusOK, profiles)
+}
+
+// GetProfile returns a single import profile by ID or name
+func (h *ImportProfileHandler) GetProfile(c *gin.Context) {
+	profile, err := loadImportProfile(h.db, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// CreateProfile stores a new import profile
+func (h *ImportProfileHandler) CreateProfile(c *gin.Context) {
+	req, ok := bindImportProfileRequest(c)
+	if !ok {
+		return
+	}
+
+	columnsJSON, _ := json.Marshal(req.Columns)
+	now := time.Now()
+	result, err := h.db.Exec(`INSERT INTO import_profiles (name, description, columns, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+		strings.TrimSpace(req.Name), req.Description, string(columnsJSON), now, now)
+	if err != nil {
+		log.Printf("Error creating import profile %s: %v", req.Name, err)
+		c.JSON(http.StatusConflict, gin.H{"error": "Failed to create import profile: " + err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	log.Printf("Created import profile %d (%s) with %d columns", id, req.Name, len(req.Columns))
+
+	profile, err := loadImportProfile(h.db, strconv.FormatInt(id, 10))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, profile)
+}
+
+// UpdateProfile replaces the name, description and columns of an import profile
+func (h *ImportProfileHandler) UpdateProfile(c *gin.Context) {
+	profileID := c.Param("id")
+	req, ok := bindImportProfileRequest(c)
+	if !ok {
+		return
+	}
+
+	columnsJSON, _ := json.Marshal(req.Columns)
+	result, err := h.db.Exec(`UPDATE import_profiles SET name = ?, description = ?, columns = ?, updated_at = ? WHERE id = ?`,
+		strings.TrimSpace(req.Name), req.Description, string(columnsJSON), time.Now(), profileID)
+	if err != nil {
+		log.Printf("Error updating import profile %s: %v", profileID, err)
+		c.JSON(http.StatusConflict, gin.H{"error": "Failed to update import profile: " + err.Error()})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import profile not found"})
+		return
+	}
+
+	profile, err := loadImportProfile(h.db, profileID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// DeleteProfile removes an import profile
+func (h *ImportProfileHandler) DeleteProfile(c *gin.Context) {
+	profileID := c.Param("id")
+	result, err := h.db.Exec(`DELETE FROM import_profiles WHERE id = ?`, profileID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import profile not found"})
+		return
+	}
+
+	log.Printf("Deleted import profile %s", profileID)
+	c.JSON(http.StatusOK, gin.H{"message": "Import profile deleted successfully"})
+}
+
+// bindImportProfileRequest parses and validates a profile request, writing the error response if invalid
+func bindImportProfileRequest(c *gin.Context) (*models.ImportProfileRequest, bool) {
+	var req models.ImportProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return nil, false
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Profile name is required"})
+		return nil, false
+	}
+	if err := services.ValidateColumnMapping(req.Columns); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &req, true
+}
+
+// loadImportProfile fetches an import profile by numeric ID or by name
+func loadImportProfile(db *sql.DB, ref string) (*models.ImportProfile, error) {
+	query := `SELECT id, name, description, columns, created_at, updated_at FROM import_profiles WHERE name = ?`
+	if _, err := strconv.Atoi(ref); err == nil {
+		query = `SELECT id, name, description, columns, created_at, updated_at FROM import_profiles WHERE id = ?`
+	}
+
+	profile, err := scanImportProfile(db.QueryRow(query, ref))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("import profile '%s' not found", ref)
+	}
+	return profile, err
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanImportProfile reads an import profile row and decodes its column mapping
+func scanImportProfile(row rowScanner) (*models.ImportProfile, error) {
+	var profile models.ImportProfile
+	var description sql.NullString
+	var columnsJSON string
+	if err := row.Scan(&profile.ID, &profile.Name, &description, &columnsJSON, &profile.CreatedAt, &profile.UpdatedAt); err != nil {
+		return nil, err
+	}
+	profile.Description = description.String
+
+	if err := json.Unmarshal([]byte(columnsJSON), &profile.Columns); err != nil {
+		return nil, fmt.Errorf("invalid column mapping stored for profile %d: %v", profile.ID, err)
+	}
+	return &profile, nil
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"tahsilat-raporu/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sqlExecutor is implemented by both *sql.DB and *sql.Tx, so the import helpers
+// can run either directly or inside a strict-mode transaction
+type sqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// createImportBatch inserts the import_batches row for an upload and returns its ID
+func createImportBatch(db sqlExecutor, input importInput, totalRows int, status string) (int64, error) {
+	query := `
+		INSERT INTO import_batches (file_name, source, uploaded_by, total_rows, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	result, err := db.Exec(query, input.fileName, input.source, input.uploadedBy, totalRows, status, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// finishImportBatch stores the final row counts of an import batch
+func finishImportBatch(db sqlExecutor, batchID int64, importedRows, errorCount int) error {
+	_, err := db.Exec(`UPDATE import_batches SET imported_rows = ?, error_count = ? WHERE id = ?`, importedRows, errorCount, batchID)
+	return err
+}
+
+// ListImports returns all import batches, newest first
+func (h *UploadHandler) ListImports(c *gin.Context) {
+	query := `
+		SELECT b.id, b.file_name, b.source, b.uploaded_by, b.total_rows, b.imported_rows, b.error_count,
+		       b.status, b.created_at, b.rolled_back_at, COUNT(p.id)
+		FROM import_batches b
+		LEFT JOIN payments p ON p.batch_id = b.id
+		GROUP BY b.id
+		ORDER BY b.created_at DESC, b.id DESC
+	`
+	rows, err := h.db.Query(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	batches := []models.ImportBatch{}
+	for rows.Next() {
+		var batch models.ImportBatch
+		var fileName, uploadedBy sql.NullString
+		var rolledBackAt sql.NullTime
+		err := rows.Scan(
+			&batch.ID,
+			&fileName,
+			&batch.Source,
+			&uploadedBy,
+			&batch.TotalRows,
+			&batch.ImportedRows,
+			&batch.ErrorCount,
+			&batch.Status,
+			&batch.CreatedAt,
+			&rolledBackAt,
+			&batch.PaymentCount,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		batch.FileName = fileName.String
+		batch.UploadedBy = uploadedBy.String
+		if rolledBackAt.Valid {
+			batch.RolledBackAt = &rolledBackAt.Time
+		}
+		batches = append(batches, batch)
+	}
+
+	c.JSON(http.StatusOK, batches)
+}
+
+// RollbackImport deletes every payment created by an import batch in a single
+// transaction and marks the batch as rolled back. The batch row is kept for audit.
+func (h *UploadHandler) RollbackImport(c *gin.Context) {
+	batchID := c.Param("id")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM import_batches WHERE id = ?`, batchID).Scan(&status)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import batch not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if status == models.ImportStatusRolledBack {
+		c.JSON(http.StatusConflict, gin.H{"error": "Import batch has already been rolled back"})
+		return
+	}
+	if status == models.ImportStatusRejected {
+		c.JSON(http.StatusConflict, gin.H{"error": "Import batch was rejected, there is nothing to roll back"})
+		return
+	}
+
+	// Refunds booked later must not lose the collection they net out
+	var refunds int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM payments
+		WHERE original_payment_id IN (SELECT id FROM payments WHERE batch_id = ?)
+		  AND (batch_id IS NULL OR batch_id != ?)`, batchID, batchID).Scan(&refunds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if refunds > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%d refunds or reversals outside this import reference its payments, delete them first", refunds)})
+		return
+	}
+
+	result, err := tx.Exec(`DELETE FROM payments WHERE batch_id = ?`, batchID)
+	if err != nil {
+		log.Printf("Error deleting payments for import batch %s: %v", batchID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	deleted, _ := result.RowsAffected()
+
+	if _, err := tx.Exec(`UPDATE import_batches SET status = ?, rolled_back_at = ? WHERE id = ?`,
+		models.ImportStatusRolledBack, time.Now(), batchID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing rollback of import batch %s: %v", batchID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Rolled back import batch %s: deleted %d payments", batchID, deleted)
+	c.JSON(http.StatusOK, gin.H{
+		"message":  fmt.Sprintf("Import batch %s rolled back, %d payments deleted", batchID, deleted),
+		"batch_id": batchID,
+		"deleted":  deleted,
+	})
+}
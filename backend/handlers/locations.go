@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// settingLocationsSeeded records that the default locations were saved and
+// stored payments re-labelled once
+const settingLocationsSeeded = "locations_seeded"
+
+// LocationHandler manages the locations payments are classified into
+type LocationHandler struct {
+	db *sql.DB
+}
+
+// NewLocationHandler creates a new location handler
+func NewLocationHandler(db *sql.DB) *LocationHandler {
+	return &LocationHandler{db: db}
+}
+
+// ListLocations returns the locations in report order
+func (h *LocationHandler) ListLocations(c *gin.Context) {
+	locations, err := loadLocations(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, locations)
+}
+
+// CreateLocation adds a location. Stored payments are classified into it only
+// when they are reprocessed.
+func (h *LocationHandler) CreateLocation(c *gin.Context) {
+	location, ok := bindLocation(c)
+	if !ok {
+		return
+	}
+
+	var exists bool
+	if err := h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM locations WHERE code = ?)`, location.Code).Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Location %s already exists", location.Code)})
+		return
+	}
+
+	location.CreatedAt = time.Now()
+	location.UpdatedAt = location.CreatedAt
+	if err := saveLocation(h.db, location); err != nil {
+		log.Printf("Error creating location %s: %v", location.Code, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Location %s created with %d account patterns", location.Code, len(location.AccountPatterns))
+	c.JSON(http.StatusCreated, location)
+}
+
+// UpdateLocation replaces the label, patterns, method overrides and sort order
+// of a location. The code is kept, as stored payments refer to it.
+func (h *LocationHandler) UpdateLocation(c *gin.Context) {
+	code := services.NormalizeLocationCode(c.Param("code"))
+	location, ok := bindLocation(c)
+	if !ok {
+		return
+	}
+	if location.Code != code {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The location code cannot be changed"})
+		return
+	}
+
+	err := h.db.QueryRow(`SELECT created_at FROM locations WHERE code = ?`, code).Scan(&location.CreatedAt)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Location not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	location.UpdatedAt = time.Now()
+	if err := saveLocation(h.db, location); err != nil {
+		log.Printf("Error updating location %s: %v", code, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Location %s updated", code)
+	c.JSON(http.StatusOK, location)
+}
+
+// DeleteLocation removes a location that no payment or classification rule uses
+func (h *LocationHandler) DeleteLocation(c *gin.Context) {
+	code := services.NormalizeLocationCode(c.Param("code"))
+
+	var payments, rules int
+	if err := h.db.QueryRow(`SELECT COUNT(*) FROM payments WHERE location = ?`, code).Scan(&payments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.db.QueryRow(`SELECT COUNT(*) FROM classification_rules WHERE field = ? AND value = ?`, models.RuleFieldLocation, code).Scan(&rules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if payments > 0 || rules > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    fmt.Sprintf("Location %s is used by %d payments and %d classification rules", code, payments, rules),
+			"payments": payments,
+			"rules":    rules,
+		})
+		return
+	}
+
+	result, err := h.db.Exec(`DELETE FROM locations WHERE code = ?`, code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Location not found"})
+		return
+	}
+
+	log.Printf("Location %s deleted", code)
+	c.JSON(http.StatusOK, gin.H{"message": "Location deleted", "code": code})
+}
+
+// bindLocation reads and validates a location from the request body, writing
+// the error response if it is not valid
+func bindLocation(c *gin.Context) (models.Location, bool) {
+	var location models.Location
+	if err := c.ShouldBindJSON(&location); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return location, false
+	}
+	if location.Code == "" {
+		location.Code = c.Param("code")
+	}
+	if err := services.ValidateLocation(location); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return location, false
+	}
+	location.Code = services.NormalizeLocationCode(location.Code)
+	location.Label = strings.TrimSpace(location.Label)
+	if location.AccountPatterns == nil {
+		location.AccountPatterns = []string{}
+	}
+	for i, method := range location.MethodOverrides {
+		location.MethodOverrides[i] = strings.TrimSpace(method)
+	}
+	if location.MethodOverrides == nil {
+		location.MethodOverrides = []string{}
+	}
+	return location, true
+}
+
+// saveLocation inserts or replaces a location
+func saveLocation(db sqlExecutor, location models.Location) error {
+	patterns, err := json.Marshal(location.AccountPatterns)
+	if err != nil {
+		return err
+	}
+	overrides, err := json.Marshal(location.MethodOverrides)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO locations (code, label, account_patterns, method_overrides, sort_order, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(code) DO UPDATE SET label = excluded.label, account_patterns = excluded.account_patterns,
+			method_overrides = excluded.method_overrides, sort_order = excluded.sort_order, updated_at = excluded.updated_at
+	`, location.Code, location.Label, string(patterns), string(overrides), location.SortOrder, location.CreatedAt, location.UpdatedAt)
+	return err
+}
+
+// loadLocations returns the locations in report order
+func loadLocations(db sqlExecutor) ([]models.Location, error) {
+	rows, err := db.Query(`SELECT code, label, account_patterns, method_overrides, sort_order, created_at, updated_at FROM locations ORDER BY sort_order, code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := []models.Location{}
+	for rows.Next() {
+		var location models.Location
+		var patterns, overrides string
+		if err := rows.Scan(&location.Code, &location.Label, &patterns, &overrides, &location.SortOrder, &location.CreatedAt, &location.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(patterns), &location.AccountPatterns); err != nil {
+			return nil, fmt.Errorf("invalid account patterns of location %s: %v", location.Code, err)
+		}
+		if err := json.Unmarshal([]byte(overrides), &location.MethodOverrides); err != nil {
+			return nil, fmt.Errorf("invalid method overrides of location %s: %v", location.Code, err)
+		}
+		locations = append(locations, location)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// setLocations gives the processor the saved locations. On error the processor
+// keeps the default locations.
+func setLocations(db *sql.DB, processor *services.PaymentProcessor) {
+	locations, err := loadLocations(db)
+	if err == nil {
+		err = processor.SetLocations(locations)
+	}
+	if err != nil {
+		log.Printf("Error loading locations, using defaults: %v", err)
+	}
+}
+
+// SeedLocations saves the default locations the first time the location table
+// is used and re-labels what was stored before it existed. Location rules that
+// only repeated a default account pattern are removed, the other location
+// rules set location codes instead of labels, and every stored payment gets
+// the location the location table gives it, which is also what reports used to
+// derive on their own.
+func SeedLocations(db *sql.DB) error {
+	var seeded bool
+	if _, err := loadSetting(db, settingLocationsSeeded, &seeded); err != nil || seeded {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	defaults := services.DefaultLocations()
+	for _, location := range defaults {
+		location.CreatedAt, location.UpdatedAt = now, now
+		if err := saveLocation(tx, location); err != nil {
+			return fmt.Errorf("failed to save default location %s: %v", location.Code, err)
+		}
+	}
+	if err := relabelLocationRules(tx, defaults); err != nil {
+		return err
+	}
+	relabelled, err := relabelPaymentLocations(tx)
+	if err != nil {
+		return err
+	}
+	if err := saveSetting(tx, settingLocationsSeeded, true); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Saved %d default locations, re-labelled %d payments", len(defaults), relabelled)
+	return nil
+}
+
+// relabelLocationRules removes the location rules the default account patterns
+// replace and points the others at location codes
+func relabelLocationRules(tx *sql.Tx, locations []models.Location) error {
+	rules, err := loadClassificationRules(tx)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.Field != models.RuleFieldLocation {
+			continue
+		}
+		code, ok := services.FindLocationCode(locations, rule.Value)
+		if !ok {
+			log.Printf("Location rule %d sets unknown location %s, left unchanged", rule.ID, rule.Value)
+			continue
+		}
+		if rule.Source == models.FieldHesapAdi && rule.MatchType == models.RuleMatchContains && hasAccountPattern(locations, code, rule.Pattern) {
+			if _, err := tx.Exec(`DELETE FROM classification_rules WHERE id = ?`, rule.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		if code != rule.Value {
+			if _, err := tx.Exec(`UPDATE classification_rules SET value = ?, updated_at = ? WHERE id = ?`, code, time.Now(), rule.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasAccountPattern reports whether location code has pattern among its
+// account patterns
+func hasAccountPattern(locations []models.Location, code, pattern string) bool {
+	for _, location := range locations {
+		if location.Code != code {
+			continue
+		}
+		for _, own := range location.AccountPatterns {
+			if own == pattern {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// relabelPaymentLocations classifies the location of every stored payment with
+// the saved rules, projects and locations and stores it where it differs.
+// Payments saved before the full input row was kept are classified from their
+// stored payment method and account name.
+func relabelPaymentLocations(tx *sql.Tx) (int, error) {
+	rules, err := loadClassificationRules(tx)
+	if err != nil {
+		return 0, err
+	}
+	projects, err := loadProjects(tx)
+	if err != nil {
+		return 0, err
+	}
+	locations, err := loadLocations(tx)
+	if err != nil {
+		return 0, err
+	}
+	ruleSet, err := services.NewRuleSet(rules, projects, locations)
+	if err != nil {
+		return 0, err
+	}
+
+	type storedLocation struct {
+		id           int64
+		location     string
+		method       string
+		account      string
+		rawData      sql.NullString
+		matchedRules sql.NullString
+	}
+	rows, err := tx.Query(`SELECT id, location, payment_method, account_name, raw_data, matched_rules FROM payments`)
+	if err != nil {
+		return 0, err
+	}
+	var stored []storedLocation
+	for rows.Next() {
+		var payment storedLocation
+		if err := rows.Scan(&payment.id, &payment.location, &payment.method, &payment.account, &payment.rawData, &payment.matchedRules); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		stored = append(stored, payment)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	relabelled := 0
+	for _, payment := range stored {
+		var rawData models.PaymentRawData
+		raw := models.RawPaymentData{TahsilatSekli: payment.method, HesapAdi: payment.account}
+		if json.Unmarshal([]byte(payment.rawData.String), &rawData) == nil && rawData.Original != (models.RawPaymentData{}) {
+			raw = rawData.Original
+		}
+		location, rule := ruleSet.Classify(models.RuleFieldLocation, raw)
+		if location == payment.location {
+			continue
+		}
+
+		record := models.PaymentRecord{MatchedRules: map[string]int64{}}
+		if payment.matchedRules.Valid {
+			json.Unmarshal([]byte(payment.matchedRules.String), &record.MatchedRules)
+		}
+		delete(record.MatchedRules, models.RuleFieldLocation)
+		if rule != nil && rule.ID > 0 {
+			record.MatchedRules[models.RuleFieldLocation] = rule.ID
+		}
+		if _, err := tx.Exec(`UPDATE payments SET location = ?, matched_rules = ? WHERE id = ?`, location, matchedRulesJSON(record), payment.id); err != nil {
+			return relabelled, err
+		}
+		relabelled++
+	}
+	return relabelled, nil
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"tahsilat-raporu/models"
+)
+
+func TestSeedLocationsRelabelsStoredData(t *testing.T) {
+	db := newTestDB(t)
+	// Startup seeds the default rules first; they classify the payment methods
+	if err := SeedClassificationRules(db); err != nil {
+		t.Fatal(err)
+	}
+
+	rules := []struct{ pattern, value string }{
+		{"kuyumcukent", "KUYUMCUKENT"}, // only repeats the default account pattern
+		{"merkez", "OFİS"},
+	}
+	for _, rule := range rules {
+		_, err := db.Exec(`
+			INSERT INTO classification_rules (field, source, match_type, pattern, value, priority)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, models.RuleFieldLocation, models.FieldHesapAdi, models.RuleMatchContains, rule.pattern, rule.value, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkRaw, _ := json.Marshal(models.PaymentRawData{Original: models.RawPaymentData{
+		MusteriAdiSoyadi: "Ahmet Yılmaz",
+		TahsilatSekli:    models.PaymentMethodCheck,
+		HesapAdi:         "Yapı Kredi TL",
+	}})
+	payments := []struct {
+		location, method, account string
+		rawData                   any
+		want                      string
+	}{
+		{"ÇARŞI", "Nakit", "Çarşı Tahsilat", nil, "CARSI"},
+		{"DİĞER", "Nakit", "Diğer Hesap", nil, "DIGER"},
+		{"BANKA HAVALESİ", models.PaymentMethodCheck, "Yapı Kredi TL", string(checkRaw), "CEK"},
+		{"KUYUMCUKENT", "Nakit", "Kuyumcukent Mağaza", nil, "KUYUMCUKENT"},
+		{"DİĞER", "Nakit", "Merkez", nil, "OFIS"},
+	}
+	for _, payment := range payments {
+		_, err := db.Exec(`
+			INSERT INTO payments (customer_name, payment_date, amount, currency, payment_method, location, project,
+				account_name, amount_usd, exchange_rate, raw_data)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, "Ahmet Yılmaz", time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), 100, "USD", payment.method, payment.location, "MKM",
+			payment.account, 100, 1, payment.rawData)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := SeedLocations(db); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, payment := range payments {
+		var location string
+		if err := db.QueryRow(`SELECT location FROM payments WHERE id = ?`, i+1).Scan(&location); err != nil {
+			t.Fatal(err)
+		}
+		if location != payment.want {
+			t.Errorf("payment with location %s, account %s: got %s, want %s", payment.location, payment.account, location, payment.want)
+		}
+	}
+
+	stored, err := loadClassificationRules(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var locationRules []models.ClassificationRule
+	for _, rule := range stored {
+		if rule.Field == models.RuleFieldLocation {
+			locationRules = append(locationRules, rule)
+		}
+	}
+	if len(locationRules) != 1 || locationRules[0].Pattern != "merkez" || locationRules[0].Value != "OFIS" {
+		t.Errorf("location rules after seeding = %+v, want only merkez -> OFIS", locationRules)
+	}
+
+	var seeded bool
+	if _, err := loadSetting(db, settingLocationsSeeded, &seeded); err != nil || !seeded {
+		t.Errorf("locations seeded setting = %v (%v), want true", seeded, err)
+	}
+}
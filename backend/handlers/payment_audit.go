@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPaymentAudit returns a payment together with the row it was imported from
+// and how each classified field was derived from that row. Payments saved
+// before the full row was kept only return their old raw_data text.
+func (h *UploadHandler) GetPaymentAudit(c *gin.Context) {
+	paymentID := c.Param("id")
+
+	var payment models.PaymentRecord
+	var rawData, matchedRules sql.NullString
+	query := `SELECT id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name, amount_usd, exchange_rate, created_at, raw_data, kind, original_payment_id, gold_grams, batch_id, duplicate_of, matched_rules FROM payments WHERE id = ?`
+	err := h.db.QueryRow(query, paymentID).Scan(
+		&payment.ID,
+		&payment.CustomerName,
+		&payment.PaymentDate,
+		&payment.Amount,
+		&payment.Currency,
+		&payment.PaymentMethod,
+		&payment.Location,
+		&payment.Project,
+		&payment.AccountName,
+		&payment.AmountUSD,
+		&payment.ExchangeRate,
+		&payment.CreatedAt,
+		&rawData,
+		&payment.Kind,
+		&payment.OriginalID,
+		&payment.GoldGrams,
+		&payment.BatchID,
+		&payment.DuplicateOf,
+		&matchedRules,
+	)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving payment %s for audit: %v", paymentID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	payment.RawData = rawData.String
+	if matchedRules.Valid {
+		json.Unmarshal([]byte(matchedRules.String), &payment.MatchedRules)
+	}
+
+	audit := models.PaymentAudit{Payment: payment, Decisions: []models.ClassificationDecision{}}
+
+	var stored models.PaymentRawData
+	if err := json.Unmarshal([]byte(rawData.String), &stored); err == nil && stored.Original != (models.RawPaymentData{}) {
+		audit.RawData = &stored
+		processor := services.NewPaymentProcessor()
+		setGoldValuation(h.db, processor)
+		setClassificationRules(h.db, processor)
+		setProjects(h.db, processor)
+		setLocations(h.db, processor)
+		audit.Decisions = services.ExplainClassification(processor, payment, stored)
+	} else {
+		audit.LegacyRawData = rawData.String
+	}
+
+	c.JSON(http.StatusOK, audit)
+}
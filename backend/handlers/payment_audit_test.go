@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tahsilat-raporu/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetPaymentAuditConfiguredGoldUnit(t *testing.T) {
+	db := newTestDB(t)
+	units := []models.GoldUnit{{Code: "RESAT", Name: "Reşat Altın", Aliases: []string{"REŞAT ALTIN"}, Grams: 7.2, Purity: 0.916}}
+	if err := saveSetting(db, settingGoldUnits, units); err != nil {
+		t.Fatal(err)
+	}
+
+	raw := models.RawPaymentData{
+		MusteriAdiSoyadi: "Ahmet Yılmaz",
+		Tarih:            "15/01/2025",
+		TahsilatSekli:    "Nakit",
+		HesapAdi:         "Kasa",
+		OdenenTutar:      200,
+		OdenenDoviz:      "Reşat Altın",
+		ProjeAdi:         "MKM",
+	}
+	rawData, _ := json.Marshal(models.PaymentRawData{Original: raw, DateOrder: "DMY"})
+	_, err := db.Exec(`
+		INSERT INTO payments (customer_name, payment_date, amount, currency, payment_method, location, project,
+			account_name, amount_usd, exchange_rate, raw_data, gold_grams)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, "Ahmet Yılmaz", time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), 2, "RESAT", "Nakit", "OFIS", "MKM",
+		"Kasa", 1000, 500, string(rawData), 13.19)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+	NewUploadHandler(db).GetPaymentAudit(c)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GetPaymentAudit returned %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var audit models.PaymentAudit
+	if err := json.Unmarshal(recorder.Body.Bytes(), &audit); err != nil {
+		t.Fatal(err)
+	}
+	for _, decision := range audit.Decisions {
+		if decision.Field != "currency" {
+			continue
+		}
+		if decision.Current != "RESAT" || decision.Changed {
+			t.Errorf("currency decision: current %q, changed %v; want RESAT, unchanged", decision.Current, decision.Changed)
+		}
+		return
+	}
+	t.Errorf("audit has no currency decision: %+v", audit.Decisions)
+}
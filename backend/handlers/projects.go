@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// settingProjectsSeeded records that the default projects were saved once
+const settingProjectsSeeded = "projects_seeded"
+
+// ProjectHandler manages the projects payments are classified into
+type ProjectHandler struct {
+	db *sql.DB
+}
+
+// NewProjectHandler creates a new project handler
+func NewProjectHandler(db *sql.DB) *ProjectHandler {
+	return &ProjectHandler{db: db}
+}
+
+// ListProjects returns the projects in report order
+func (h *ProjectHandler) ListProjects(c *gin.Context) {
+	projects, err := loadProjects(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, projects)
+}
+
+// CreateProject adds a project. Stored payments are classified into it only
+// when they are reprocessed.
+func (h *ProjectHandler) CreateProject(c *gin.Context) {
+	project, ok := bindProject(c)
+	if !ok {
+		return
+	}
+
+	var exists bool
+	if err := h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM projects WHERE code = ?)`, project.Code).Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Project %s already exists", project.Code)})
+		return
+	}
+
+	project.CreatedAt = time.Now()
+	project.UpdatedAt = project.CreatedAt
+	if err := saveProject(h.db, project); err != nil {
+		log.Printf("Error creating project %s: %v", project.Code, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Project %s created with %d patterns", project.Code, len(project.Patterns))
+	c.JSON(http.StatusCreated, project)
+}
+
+// UpdateProject replaces the name, patterns and sort order of a project. The
+// code is kept, as stored payments refer to it.
+func (h *ProjectHandler) UpdateProject(c *gin.Context) {
+	code := services.NormalizeProjectCode(c.Param("code"))
+	project, ok := bindProject(c)
+	if !ok {
+		return
+	}
+	if project.Code != code {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The project code cannot be changed"})
+		return
+	}
+
+	err := h.db.QueryRow(`SELECT created_at FROM projects WHERE code = ?`, code).Scan(&project.CreatedAt)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	project.UpdatedAt = time.Now()
+	if err := saveProject(h.db, project); err != nil {
+		log.Printf("Error updating project %s: %v", code, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Project %s updated", code)
+	c.JSON(http.StatusOK, project)
+}
+
+// DeleteProject removes a project that no payment or classification rule uses
+func (h *ProjectHandler) DeleteProject(c *gin.Context) {
+	code := services.NormalizeProjectCode(c.Param("code"))
+
+	var payments, rules int
+	if err := h.db.QueryRow(`SELECT COUNT(*) FROM payments WHERE project = ?`, code).Scan(&payments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.db.QueryRow(`SELECT COUNT(*) FROM classification_rules WHERE field = ? AND value = ?`, models.RuleFieldProject, code).Scan(&rules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if payments > 0 || rules > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    fmt.Sprintf("Project %s is used by %d payments and %d classification rules", code, payments, rules),
+			"payments": payments,
+			"rules":    rules,
+		})
+		return
+	}
+
+	result, err := h.db.Exec(`DELETE FROM projects WHERE code = ?`, code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	log.Printf("Project %s deleted", code)
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted", "code": code})
+}
+
+// bindProject reads and validates a project from the request body, writing
+// the error response if it is not valid
+func bindProject(c *gin.Context) (models.Project, bool) {
+	var project models.Project
+	if err := c.ShouldBindJSON(&project); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return project, false
+	}
+	if project.Code == "" {
+		project.Code = c.Param("code")
+	}
+	if err := services.ValidateProject(project); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return project, false
+	}
+	project.Code = services.NormalizeProjectCode(project.Code)
+	project.Name = strings.TrimSpace(project.Name)
+	if project.Patterns == nil {
+		project.Patterns = []string{}
+	}
+	return project, true
+}
+
+// saveProject inserts or replaces a project
+func saveProject(db sqlExecutor, project models.Project) error {
+	patterns, err := json.Marshal(project.Patterns)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO projects (code, name, patterns, sort_order, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(code) DO UPDATE SET name = excluded.name, patterns = excluded.patterns, sort_order = excluded.sort_order, updated_at = excluded.updated_at
+	`, project.Code, project.Name, string(patterns), project.SortOrder, project.CreatedAt, project.UpdatedAt)
+	return err
+}
+
+// loadProjects returns the projects in report order
+func loadProjects(db sqlExecutor) ([]models.Project, error) {
+	rows, err := db.Query(`SELECT code, name, patterns, sort_order, created_at, updated_at FROM projects ORDER BY sort_order, code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		var project models.Project
+		var patterns string
+		if err := rows.Scan(&project.Code, &project.Name, &patterns, &project.SortOrder, &project.CreatedAt, &project.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(patterns), &project.Patterns); err != nil {
+			return nil, fmt.Errorf("invalid patterns of project %s: %v", project.Code, err)
+		}
+		projects = append(projects, project)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// setProjects gives the processor the saved projects. On error the processor
+// keeps the default projects.
+func setProjects(db *sql.DB, processor *services.PaymentProcessor) {
+	projects, err := loadProjects(db)
+	if err == nil {
+		err = processor.SetProjects(projects)
+	}
+	if err != nil {
+		log.Printf("Error loading projects, using defaults: %v", err)
+	}
+}
+
+// SeedProjects saves the default projects the first time the project table is
+// used. Project rules that only repeated the default patterns are removed, as
+// the project table now holds them.
+func SeedProjects(db *sql.DB) error {
+	var seeded bool
+	if _, err := loadSetting(db, settingProjectsSeeded, &seeded); err != nil || seeded {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	defaults := services.DefaultProjects()
+	for _, project := range defaults {
+		project.CreatedAt, project.UpdatedAt = now, now
+		if err := saveProject(tx, project); err != nil {
+			return fmt.Errorf("failed to save default project %s: %v", project.Code, err)
+		}
+		for _, pattern := range project.Patterns {
+			_, err := tx.Exec(`DELETE FROM classification_rules WHERE field = ? AND source = ? AND match_type = ? AND pattern = ? AND value = ?`,
+				models.RuleFieldProject, models.FieldProjeAdi, models.RuleMatchContains, pattern, project.Code)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := saveSetting(tx, settingProjectsSeeded, true); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Saved %d default projects", len(defaults))
+	return nil
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tahsilat-raporu/dateparse"
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/money"
+	"tahsilat-raporu/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errInvalidOriginal marks problems with the payment a refund or reversal references
+var errInvalidOriginal = errors.New("invalid original payment")
+
+// bookedAgainst returns the (negative) sum of the refunds and reversals of a
+// payment, leaving out the payment with ID excludeID
+func bookedAgainst(db sqlExecutor, originalID int64, excludeID int) (money.Amount, error) {
+	var booked int64
+	query := `SELECT ` + money.SumSQL("amount") + ` FROM payments WHERE original_payment_id = ? AND id != ?`
+	if err := db.QueryRow(query, originalID, excludeID).Scan(&booked); err != nil {
+		return 0, err
+	}
+	return money.Amount(booked), nil
+}
+
+// checkOriginalPayment verifies that a refund or reversal can be booked against
+// the collection it references: same currency, and no more than is left of it.
+// A reversal cancels the whole collection, so it takes over the original's USD
+// amount and rate and nets it out exactly.
+func checkOriginalPayment(db sqlExecutor, payment *models.PaymentRecord) error {
+	return checkOriginalPaymentPending(db, payment, 0)
+}
+
+// checkOriginalPaymentPending is checkOriginalPayment for a payment that
+// follows unsaved refunds of the same collection, such as earlier rows of a
+// dry run; pending is their (negative) sum
+func checkOriginalPaymentPending(db sqlExecutor, payment *models.PaymentRecord, pending money.Amount) error {
+	if payment.OriginalID == nil {
+		return nil
+	}
+	originalID := *payment.OriginalID
+
+	var original models.PaymentRecord
+	err := db.QueryRow(`SELECT id, amount, currency, amount_usd, exchange_rate, kind FROM payments WHERE id = ?`, originalID).Scan(
+		&original.ID,
+		&original.Amount,
+		&original.Currency,
+		&original.AmountUSD,
+		&original.ExchangeRate,
+		&original.Kind,
+	)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: payment %d does not exist", errInvalidOriginal, originalID)
+	}
+	if err != nil {
+		return err
+	}
+	if original.Kind != models.PaymentKindCollection {
+		return fmt.Errorf("%w: payment %d is a %s, not a collection", errInvalidOriginal, originalID, original.Kind)
+	}
+	if original.Currency != payment.Currency {
+		return fmt.Errorf("%w: payment %d is in %s, not %s", errInvalidOriginal, originalID, original.Currency, payment.Currency)
+	}
+
+	booked, err := bookedAgainst(db, originalID, payment.ID)
+	if err != nil {
+		return err
+	}
+	booked += pending
+	remaining := original.Amount + booked
+	if payment.Amount.Abs() > remaining {
+		return fmt.Errorf("%w: only %s %s of payment %d is left to refund", errInvalidOriginal, remaining, original.Currency, originalID)
+	}
+
+	if payment.Kind == models.PaymentKindReversal {
+		if booked != 0 || payment.Amount != -original.Amount {
+			return fmt.Errorf("%w: a reversal must cancel the whole of payment %d (%s %s)", errInvalidOriginal, originalID, original.Amount, original.Currency)
+		}
+		payment.AmountUSD = -original.AmountUSD
+		payment.ExchangeRate = original.ExchangeRate
+	}
+	return nil
+}
+
+// originalPaymentError reports a failed checkOriginalPayment for an imported row
+func originalPaymentError(payment models.PaymentRecord, err error) models.RowError {
+	if !errors.Is(err, errInvalidOriginal) {
+		return paymentDatabaseError(payment, err)
+	}
+	rowError := services.NewRowError(payment.RowNumber, models.FieldOrijinalOdemeNo, strconv.FormatInt(*payment.OriginalID, 10),
+		services.ErrCodeInvalidOriginal, err)
+	rowError.Customer = payment.CustomerName
+	return rowError
+}
+
+// CreateRefund books a refund or reversal of an existing collection. The new
+// payment takes the customer, account and project of the original and is
+// converted to USD like an imported row; a reversal reuses the original's rate.
+func (h *UploadHandler) CreateRefund(c *gin.Context) {
+	originalID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	var req models.RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = models.PaymentKindRefund
+	}
+	if !services.IsNegativeKind(req.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be refund or reversal"})
+		return
+	}
+	if req.Date == "" {
+		req.Date = time.Now().Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	var original models.PaymentRecord
+	var rawData sql.NullString
+	err = h.db.QueryRow(`SELECT customer_name, amount, currency, payment_method, account_name, project, raw_data, kind, customer_id FROM payments WHERE id = ?`, originalID).Scan(
+		&original.CustomerName,
+		&original.Amount,
+		&original.Currency,
+		&original.PaymentMethod,
+		&original.AccountName,
+		&original.Project,
+		&rawData,
+		&original.Kind,
+		&original.CustomerID,
+	)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error retrieving payment %d for refund: %v", originalID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if original.Kind != models.PaymentKindCollection {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Payment %d is a %s, only collections can be refunded", originalID, original.Kind)})
+		return
+	}
+
+	if req.Amount == nil {
+		booked, err := bookedAgainst(h.db, originalID, 0)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		remaining := original.Amount + booked
+		if remaining <= 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Payment %d has already been refunded in full", originalID)})
+			return
+		}
+		req.Amount = &remaining
+	}
+	if *req.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be greater than 0"})
+		return
+	}
+
+	// Classify the refund from the original's input row where there is one, so
+	// that reprocessing treats both the same way
+	raw := models.RawPaymentData{
+		MusteriAdiSoyadi: original.CustomerName,
+		Tarih:            req.Date,
+		TahsilatSekli:    original.PaymentMethod,
+		HesapAdi:         original.AccountName,
+		OdenenTutar:      *req.Amount,
+		OdenenDoviz:      original.Currency,
+		ProjeAdi:         original.Project,
+		IslemTuru:        req.Kind,
+		OrijinalOdemeNo:  strconv.FormatInt(originalID, 10),
+	}
+	var stored models.PaymentRawData
+	if err := json.Unmarshal([]byte(rawData.String), &stored); err == nil && stored.Original != (models.RawPaymentData{}) {
+		raw.TahsilatSekli = stored.Original.TahsilatSekli
+		raw.HesapAdi = stored.Original.HesapAdi
+		raw.ProjeAdi = stored.Original.ProjeAdi
+		raw.OdenenDoviz = stored.Original.OdenenDoviz
+	}
+
+	processor := services.NewPaymentProcessor()
+	datePolicy, err := loadDatePolicy(h.db)
+	if err != nil {
+		log.Printf("Error loading date policy, using defaults: %v", err)
+	}
+	setGoldValuation(h.db, processor)
+	setClassificationRules(h.db, processor)
+	setProjects(h.db, processor)
+	setLocations(h.db, processor)
+	payment, err := processor.Process(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if rowErrors := services.ValidatePayment(payment, raw, datePolicy); len(rowErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refund is not valid", "errors": rowErrors})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := checkOriginalPayment(tx, payment); err != nil {
+		if errors.Is(err, errInvalidOriginal) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	payment.CustomerID = original.CustomerID
+	payment.Original = &raw
+	payment.Fingerprint = services.PaymentFingerprint(*payment)
+	payment.RawData = paymentRawData(*payment, importInput{source: "manual", uploadedBy: c.GetString(gin.AuthUserKey)}, dateparse.ColumnFormat{Order: dateparse.OrderYMD})
+	id, err := savePayment(tx, *payment, 0)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Error saving %s of payment %d: %v", req.Kind, originalID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	payment.ID = int(id)
+
+	log.Printf("Booked %s %d of payment %d: %.2f %s (%.2f USD)", req.Kind, id, originalID, payment.Amount, payment.Currency, payment.AmountUSD)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": fmt.Sprintf("%s of payment %d booked", req.Kind, originalID),
+		"payment": payment,
+	})
+}
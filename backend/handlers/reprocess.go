@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"tahsilat-raporu/dateparse"
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReprocessPayments runs classification and USD conversion again for stored
+// payments, using the input row kept in raw_data. Without "confirm": true it
+// only returns the diff; with it the changes are applied in one transaction.
+// Changed payments are validated like imported rows, with the saved date policy,
+// and those that fail are reported as errors and left as they are.
+func (h *UploadHandler) ReprocessPayments(c *gin.Context) {
+	var req models.ReprocessRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, date := range []struct {
+		name, value, op string
+	}{
+		{"start_date", req.StartDate, ">="},
+		{"end_date", req.EndDate, "<="},
+	} {
+		if date.value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date.value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s format. Use YYYY-MM-DD", date.name)})
+			return
+		}
+		conditions = append(conditions, "substr(payment_date, 1, 10) "+date.op+" ?")
+		args = append(args, date.value)
+	}
+	if req.Project != "" {
+		conditions = append(conditions, "project = ?")
+		args = append(args, strings.ToUpper(strings.TrimSpace(req.Project)))
+	}
+	if req.BatchID > 0 {
+		conditions = append(conditions, "batch_id = ?")
+		args = append(args, req.BatchID)
+	}
+
+	query := `SELECT id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name, amount_usd, exchange_rate, raw_data, kind, original_payment_id, gold_grams, COALESCE(review_status, ''), review_reasons FROM payments`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY payment_date, id"
+
+	payments, err := loadPaymentsForReprocess(h.db, query, args)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	processor := services.NewPaymentProcessor()
+	datePolicy, err := loadDatePolicy(h.db)
+	if err != nil {
+		log.Printf("Error loading date policy, using defaults: %v", err)
+	}
+	setGoldValuation(h.db, processor)
+	setClassificationRules(h.db, processor)
+	setProjects(h.db, processor)
+	setLocations(h.db, processor)
+	response := models.ReprocessResponse{Matched: len(payments), Changes: []models.ReprocessChange{}}
+	var updates []models.PaymentRecord
+	for _, stored := range payments {
+		var rawData models.PaymentRawData
+		if err := json.Unmarshal([]byte(stored.RawData), &rawData); err != nil || rawData.Original == (models.RawPaymentData{}) {
+			response.SkippedLegacy++
+			continue
+		}
+
+		// Parse the date with the order inferred for its upload, not from this value alone
+		processor.SetDateOptions(dateparse.Options{Order: rawData.DateOrder, DateSystem: rawData.DateSystem})
+		updated, err := processor.Process(rawData.Original)
+		if err != nil {
+			response.Errors = append(response.Errors, fmt.Sprintf("Payment %d (%s): %v", stored.ID, stored.CustomerName, err))
+			continue
+		}
+
+		// An operator's review outlives the rules that led to it
+		if services.IsReviewed(stored.ReviewStatus) {
+			services.KeepReviewedClassification(stored, updated)
+		}
+
+		// Reversals keep the USD amount of the collection they cancel
+		updated.ID = stored.ID
+		if err := checkOriginalPayment(h.db, updated); err != nil {
+			response.Errors = append(response.Errors, fmt.Sprintf("Payment %d (%s): %v", stored.ID, stored.CustomerName, err))
+			continue
+		}
+
+		changes := diffReprocessedPayment(stored, *updated)
+		if len(changes) == 0 {
+			continue
+		}
+
+		// A changed payment must pass the checks an import would apply to it
+		if rowErrors := services.ValidatePayment(updated, rawData.Original, datePolicy); len(rowErrors) > 0 {
+			messages := make([]string, len(rowErrors))
+			for i, rowError := range rowErrors {
+				messages[i] = rowError.MessageEN
+			}
+			response.Errors = append(response.Errors, fmt.Sprintf("Payment %d (%s): %s", stored.ID, stored.CustomerName, strings.Join(messages, "; ")))
+			continue
+		}
+
+		// Reprocessing keeps the customer, so the fingerprint uses the stored name as imports do
+		updated.CustomerName = stored.CustomerName
+		updated.Fingerprint = services.PaymentFingerprint(*updated)
+		updates = append(updates, *updated)
+		response.Changes = append(response.Changes, models.ReprocessChange{
+			PaymentID:    stored.ID,
+			CustomerName: stored.CustomerName,
+			PaymentDate:  stored.PaymentDate,
+			Changes:      changes,
+		})
+	}
+	response.Changed = len(updates)
+
+	if len(updates) == 0 {
+		response.Success = true
+		response.Message = fmt.Sprintf("All %d payments are up to date", response.Matched)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	if !req.Confirm {
+		response.Success = true
+		response.Message = fmt.Sprintf("%d of %d payments would change, send confirm=true to apply", response.Changed, response.Matched)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if err := h.applyReprocessedPayments(updates); err != nil {
+		log.Printf("Error applying reprocessed payments: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Reprocessed %d of %d payments", response.Changed, response.Matched)
+	response.Success = true
+	response.Applied = true
+	response.Message = fmt.Sprintf("%d of %d payments updated", response.Changed, response.Matched)
+	c.JSON(http.StatusOK, response)
+}
+
+// loadPaymentsForReprocess reads the stored fields that reprocessing may change
+func loadPaymentsForReprocess(db *sql.DB, query string, args []interface{}) ([]models.PaymentRecord, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []models.PaymentRecord
+	for rows.Next() {
+		var payment models.PaymentRecord
+		var rawData, reviewReasons sql.NullString
+		err := rows.Scan(
+			&payment.ID,
+			&payment.CustomerName,
+			&payment.PaymentDate,
+			&payment.Amount,
+			&payment.Currency,
+			&payment.PaymentMethod,
+			&payment.Location,
+			&payment.Project,
+			&payment.AccountName,
+			&payment.AmountUSD,
+			&payment.ExchangeRate,
+			&rawData,
+			&payment.Kind,
+			&payment.OriginalID,
+			&payment.GoldGrams,
+			&payment.ReviewStatus,
+			&reviewReasons,
+		)
+		if err != nil {
+			return nil, err
+		}
+		payment.RawData = rawData.String
+		if reviewReasons.Valid {
+			json.Unmarshal([]byte(reviewReasons.String), &payment.ReviewReasons)
+		}
+		payments = append(payments, payment)
+	}
+	return payments, rows.Err()
+}
+
+// diffReprocessedPayment lists the fields whose value differs after reprocessing
+func diffReprocessedPayment(stored, updated models.PaymentRecord) []models.FieldChange {
+	fields := []models.FieldChange{
+		{Field: "payment_date", Old: stored.PaymentDate.Format("2006-01-02"), New: updated.PaymentDate.Format("2006-01-02")},
+		{Field: "kind", Old: stored.Kind, New: updated.Kind},
+		{Field: "amount", Old: fmt.Sprintf("%.2f", stored.Amount), New: fmt.Sprintf("%.2f", updated.Amount)},
+		{Field: "currency", Old: stored.Currency, New: updated.Currency},
+		{Field: "payment_method", Old: stored.PaymentMethod, New: updated.PaymentMethod},
+		{Field: "location", Old: stored.Location, New: updated.Location},
+		{Field: "project", Old: stored.Project, New: updated.Project},
+		{Field: "amount_usd", Old: fmt.Sprintf("%.2f", stored.AmountUSD), New: fmt.Sprintf("%.2f", updated.AmountUSD)},
+		{Field: "exchange_rate", Old: fmt.Sprintf("%.4f", stored.ExchangeRate), New: fmt.Sprintf("%.4f", updated.ExchangeRate)},
+		{Field: "gold_grams", Old: fmt.Sprintf("%.3f", stored.GoldGrams), New: fmt.Sprintf("%.3f", updated.GoldGrams)},
+		{Field: "review_status", Old: stored.ReviewStatus, New: updated.ReviewStatus},
+	}
+
+	var changes []models.FieldChange
+	for _, field := range fields {
+		if field.Old != field.New {
+			changes = append(changes, field)
+		}
+	}
+	return changes
+}
+
+// applyReprocessedPayments writes the reprocessed fields in a single transaction
+func (h *UploadHandler) applyReprocessedPayments(payments []models.PaymentRecord) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE payments SET payment_date = ?, amount = ?, currency = ?, payment_method = ?, location = ?,
+			project = ?, account_name = ?, amount_usd = ?, exchange_rate = ?, fingerprint = ?, kind = ?, original_payment_id = ?,
+			gold_grams = ?, matched_rules = ?, review_status = ?, review_reasons = ?
+		WHERE id = ?
+	`
+	for _, payment := range payments {
+		_, err := tx.Exec(query, payment.PaymentDate, payment.Amount, payment.Currency, payment.PaymentMethod, payment.Location,
+			payment.Project, payment.AccountName, payment.AmountUSD, payment.ExchangeRate, payment.Fingerprint, payment.Kind,
+			payment.OriginalID, payment.GoldGrams, matchedRulesJSON(payment), payment.ReviewStatus, reviewReasonsJSON(payment), payment.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update payment %d: %v", payment.ID, err)
+		}
+	}
+	return tx.Commit()
+}
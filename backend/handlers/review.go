@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReviewHandler manages the queue of payments whose classification used a
+// fallback value
+type ReviewHandler struct {
+	db *sql.DB
+}
+
+// NewReviewHandler creates a new review handler
+func NewReviewHandler(db *sql.DB) *ReviewHandler {
+	return &ReviewHandler{db: db}
+}
+
+// reviewPaymentColumns are the columns loadReviewPayments reads
+const reviewPaymentColumns = `id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name,
+	amount_usd, exchange_rate, created_at, kind, matched_rules, review_status, review_reasons, review_note, reviewed_at`
+
+// ListReviews returns the payments awaiting review, oldest first. ?status=
+// selects confirmed or corrected payments instead, or all of them with "all".
+func (h *ReviewHandler) ListReviews(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReviewStatusPending)
+
+	var payments []models.PaymentRecord
+	var err error
+	switch status {
+	case "all":
+		payments, err = loadReviewPayments(h.db, `review_status IS NOT NULL AND review_status != ''`)
+	case models.ReviewStatusPending, models.ReviewStatusConfirmed, models.ReviewStatusCorrected:
+		payments, err = loadReviewPayments(h.db, `review_status = ?`, status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid status '%s' (valid: pending, confirmed, corrected, all)", status)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total models.ReviewTotal
+	for _, payment := range payments {
+		total.Count++
+		total.AmountUSD += payment.AmountUSD
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":     status,
+		"count":      total.Count,
+		"amount_usd": total.AmountUSD,
+		"payments":   payments,
+	})
+}
+
+// ReviewPayment confirms the classification of a payment under review, or
+// corrects its payment method, location or project. A corrected field no
+// longer records the rule that set it.
+func (h *ReviewHandler) ReviewPayment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+	var decision models.ReviewDecision
+	if err := c.ShouldBindJSON(&decision); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+
+	payments, err := loadReviewPayments(h.db, `id = ?`, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(payments) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	payment := payments[0]
+	if payment.ReviewStatus == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Payment %d does not need review", id)})
+		return
+	}
+
+	if payment.MatchedRules == nil {
+		payment.MatchedRules = make(map[string]int64)
+	}
+	corrected := false
+	for _, field := range []struct {
+		name   string
+		value  string
+		stored *string
+	}{
+		{models.RuleFieldPaymentMethod, strings.TrimSpace(decision.PaymentMethod), &payment.PaymentMethod},
+		{models.RuleFieldLocation, services.NormalizeLocationCode(decision.Location), &payment.Location},
+		{models.RuleFieldProject, services.NormalizeProjectCode(decision.Project), &payment.Project},
+	} {
+		if field.value == "" || field.value == *field.stored {
+			continue
+		}
+		if err := checkReviewValue(h.db, field.name, field.value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		*field.stored = field.value
+		delete(payment.MatchedRules, field.name)
+		corrected = true
+	}
+
+	payment.ReviewStatus = models.ReviewStatusConfirmed
+	if corrected {
+		payment.ReviewStatus = models.ReviewStatusCorrected
+	}
+	payment.ReviewNote = strings.TrimSpace(decision.Note)
+	now := time.Now()
+	payment.ReviewedAt = &now
+	payment.Fingerprint = services.PaymentFingerprint(payment)
+
+	_, err = h.db.Exec(`
+		UPDATE payments SET payment_method = ?, location = ?, project = ?, fingerprint = ?, matched_rules = ?,
+			review_status = ?, review_note = ?, reviewed_at = ?
+		WHERE id = ?
+	`, payment.PaymentMethod, payment.Location, payment.Project, payment.Fingerprint, matchedRulesJSON(payment),
+		payment.ReviewStatus, payment.ReviewNote, payment.ReviewedAt, id)
+	if err != nil {
+		log.Printf("Error reviewing payment %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Payment %d reviewed: %s (%s, %s, %s)", id, payment.ReviewStatus, payment.PaymentMethod, payment.Location, payment.Project)
+	c.JSON(http.StatusOK, payment)
+}
+
+// checkReviewValue verifies that a corrected payment method is one the
+// classification rules can give, and that a corrected location or project is
+// configured
+func checkReviewValue(db *sql.DB, field, value string) error {
+	if field != models.RuleFieldPaymentMethod {
+		return checkRuleValue(db, models.ClassificationRule{Field: field, Value: value})
+	}
+	if value == services.ClassificationFallbacks[field] {
+		return nil
+	}
+	rules, err := loadClassificationRules(db)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.Field == field && rule.Value == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("payment method %s is not given by any classification rule", value)
+}
+
+// loadReviewPayments returns the payments matching where, oldest first, with
+// their review
+func loadReviewPayments(db *sql.DB, where string, args ...interface{}) ([]models.PaymentRecord, error) {
+	rows, err := db.Query(`SELECT `+reviewPaymentColumns+` FROM payments WHERE `+where+` ORDER BY payment_date, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []models.PaymentRecord{}
+	for rows.Next() {
+		var payment models.PaymentRecord
+		var matchedRules, reviewStatus, reviewReasons, reviewNote sql.NullString
+		err := rows.Scan(&payment.ID, &payment.CustomerName, &payment.PaymentDate, &payment.Amount, &payment.Currency,
+			&payment.PaymentMethod, &payment.Location, &payment.Project, &payment.AccountName, &payment.AmountUSD,
+			&payment.ExchangeRate, &payment.CreatedAt, &payment.Kind, &matchedRules, &reviewStatus,
+			&reviewReasons, &reviewNote, &payment.ReviewedAt)
+		if err != nil {
+			return nil, err
+		}
+		payment.ReviewStatus = reviewStatus.String
+		payment.ReviewNote = reviewNote.String
+		if matchedRules.Valid {
+			json.Unmarshal([]byte(matchedRules.String), &payment.MatchedRules)
+		}
+		if reviewReasons.Valid {
+			json.Unmarshal([]byte(reviewReasons.String), &payment.ReviewReasons)
+		}
+		payments = append(payments, payment)
+	}
+	return payments, rows.Err()
+}
+
+// reviewReasonsJSON encodes the review reasons of a payment for the
+// review_reasons column, NULL when it has none
+func reviewReasonsJSON(payment models.PaymentRecord) sql.NullString {
+	if len(payment.ReviewReasons) == 0 {
+		return sql.NullString{}
+	}
+	data, err := json.Marshal(payment.ReviewReasons)
+	if err != nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(data), Valid: true}
+}
+
+// BackfillPaymentReviews queues payments stored before the review queue
+// existed whose payment method, location or project holds the fallback value
+// because no rule matches their input row
+func BackfillPaymentReviews(db *sql.DB) error {
+	rules, err := loadClassificationRules(db)
+	if err != nil {
+		return err
+	}
+	projects, err := loadProjects(db)
+	if err != nil {
+		return err
+	}
+	locations, err := loadLocations(db)
+	if err != nil {
+		return err
+	}
+	ruleSet, err := services.NewRuleSet(rules, projects, locations)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`SELECT id, payment_method, location, project, account_name, raw_data FROM payments WHERE review_status IS NULL`)
+	if err != nil {
+		return err
+	}
+	var payments []models.PaymentRecord
+	for rows.Next() {
+		var payment models.PaymentRecord
+		var rawData sql.NullString
+		if err := rows.Scan(&payment.ID, &payment.PaymentMethod, &payment.Location, &payment.Project, &payment.AccountName, &rawData); err != nil {
+			rows.Close()
+			return err
+		}
+		payment.RawData = rawData.String
+		payments = append(payments, payment)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(payments) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queued := 0
+	for _, payment := range payments {
+		// Rows stored before raw_data kept the input are judged by their stored values
+		var rawData models.PaymentRawData
+		raw := models.RawPaymentData{TahsilatSekli: payment.PaymentMethod, HesapAdi: payment.AccountName, ProjeAdi: payment.Project}
+		if json.Unmarshal([]byte(payment.RawData), &rawData) == nil && rawData.Original != (models.RawPaymentData{}) {
+			raw = rawData.Original
+		}
+		payment.ReviewReasons = services.StoredReviewReasons(ruleSet, payment, raw)
+		payment.ReviewStatus = services.ReviewStatusFor(payment.ReviewReasons)
+		if payment.ReviewStatus != "" {
+			queued++
+		}
+		if _, err := tx.Exec(`UPDATE payments SET review_status = ?, review_reasons = ? WHERE id = ?`, payment.ReviewStatus, reviewReasonsJSON(payment), payment.ID); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Queued %d of %d older payments for classification review", queued, len(payments))
+	return nil
+}
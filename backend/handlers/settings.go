@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// settingDatePolicy is the settings key of the import date policy
+const settingDatePolicy = "date_policy"
+
+// SettingsHandler manages application settings stored in the database
+type SettingsHandler struct {
+	db *sql.DB
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(db *sql.DB) *SettingsHandler {
+	return &SettingsHandler{db: db}
+}
+
+// GetDatePolicy returns the date policy applied to imports
+func (h *SettingsHandler) GetDatePolicy(c *gin.Context) {
+	policy, err := loadDatePolicy(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateDatePolicy changes the date policy applied to imports. Fields left
+// out of the request keep their saved value.
+func (h *SettingsHandler) UpdateDatePolicy(c *gin.Context) {
+	policy, err := loadDatePolicy(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format", "details": err.Error()})
+		return
+	}
+	if policy.BlockedPeriods == nil {
+		policy.BlockedPeriods = []models.BlockedPeriod{}
+	}
+	if err := services.ValidateDatePolicy(policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := saveSetting(h.db, settingDatePolicy, policy); err != nil {
+		log.Printf("Error saving date policy: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Date policy updated: %+v", policy)
+	c.JSON(http.StatusOK, policy)
+}
+
+// loadDatePolicy returns the saved date policy, or the default if none was saved
+func loadDatePolicy(db *sql.DB) (models.DatePolicy, error) {
+	policy := services.DefaultDatePolicy()
+	_, err := loadSetting(db, settingDatePolicy, &policy)
+	return policy, err
+}
+
+// loadSetting decodes a JSON setting into value and reports whether it exists
+func loadSetting(db *sql.DB, key string, value interface{}) (bool, error) {
+	var data string
+	err := db.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&data)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(data), value)
+}
+
+// saveSetting stores value as a JSON setting, replacing any previous value
+func saveSetting(db sqlExecutor, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`, key, string(data), time.Now())
+	return err
+}
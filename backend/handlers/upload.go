@@ -2,14 +2,19 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"tahsilat-raporu/dateparse"
 	"tahsilat-raporu/models"
+	"tahsilat-raporu/money"
 	"tahsilat-raporu/services"
 
 	"github.com/gin-gonic/gin"
@@ -17,23 +22,33 @@ import (
 
 // UploadHandler handles file upload and payment processing
 type UploadHandler struct {
-	db *sql.DB
+	db   *sql.DB
+	jobs *importJobStore
 }
 
 // NewUploadHandler creates a new upload handler
 func NewUploadHandler(db *sql.DB) *UploadHandler {
-	return &UploadHandler{db: db}
+	return &UploadHandler{db: db, jobs: newImportJobStore()}
 }
 
-// UploadPayments processes uploaded payment data
+// UploadPayments processes uploaded payment data.
+// The optional ?mode= query parameter (skip, flag, allow) controls duplicate handling,
+// ?dry_run=true previews the result without touching the database,
+// ?strict=true imports all rows in one transaction or none at all and
+// ?async=true runs the import as a background job.
 func (h *UploadHandler) UploadPayments(c *gin.Context) {
+	input, ok := bindImportQuery(c)
+	if !ok {
+		return
+	}
+
 	var req models.UploadRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("Invalid request format: %v", err)
 		c.JSON(http.StatusBadRequest, models.UploadResponse{
 			Success: false,
 			Message: "Invalid request format",
-			Errors:  []string{err.Error()},
+			Errors:  uploadErrors(services.ErrCodeInvalidRequest, err),
 		})
 		return
 	}
@@ -48,45 +63,512 @@ func (h *UploadHandler) UploadPayments(c *gin.Context) {
 		return
 	}
 
-	// Create payment processor
+	input.fileName = req.FileName
+	input.source = "json"
+	input.rawPayments, input.readErrors = parseTextAmounts(req.RawPayments, input.amountLocale)
+
+	h.respondImport(c, input)
+}
+
+// parseTextAmounts parses amounts that a JSON upload sent as text. Rows whose
+// amount cannot be read are returned as row errors instead of payments.
+func parseTextAmounts(rawPayments []models.RawPaymentData, locale string) ([]models.RawPaymentData, []models.RowError) {
+	var parsed []models.RawPaymentData
+	var rowErrors []models.RowError
+	for i, raw := range rawPayments {
+		if raw.RowNumber == 0 {
+			raw.RowNumber = i + 1
+		}
+		if raw.OdenenTutarText != "" {
+			amount, err := services.ParseAmount(raw.OdenenTutarText, locale)
+			if err != nil {
+				code := services.ErrCodeInvalidAmount
+				if errors.Is(err, services.ErrAmbiguousAmount) {
+					code = services.ErrCodeAmbiguousAmount
+				}
+				rowError := services.NewRowError(raw.RowNumber, models.FieldOdenenTutar, raw.OdenenTutarText, code, err)
+				rowError.Customer = raw.MusteriAdiSoyadi
+				rowError.RowData = services.RawPaymentValues(raw)
+				rowErrors = append(rowErrors, rowError)
+				continue
+			}
+			raw.OdenenTutar = amount
+		}
+		parsed = append(parsed, raw)
+	}
+	return parsed, rowErrors
+}
+
+// bindImportQuery reads the import options shared by all upload endpoints from
+// the query string, writing a 400 response if any of them is invalid
+func bindImportQuery(c *gin.Context) (importInput, bool) {
+	input := importInput{uploadedBy: c.GetString(gin.AuthUserKey)}
+
+	mode, err := services.ParseDuplicateMode(c.Query("mode"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.UploadResponse{
+			Success: false,
+			Message: "Invalid import mode",
+			Errors:  uploadErrors(services.ErrCodeInvalidRequest, err),
+		})
+		return input, false
+	}
+	input.duplicateMode = mode
+
+	flags := []struct {
+		name  string
+		value *bool
+	}{
+		{"dry_run", &input.dryRun},
+		{"strict", &input.strict},
+		{"async", &input.async},
+		{"ignore_blocked_periods", &input.dateOverride.IgnoreBlockedPeriods},
+	}
+	for _, flag := range flags {
+		value := c.Query(flag.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.UploadResponse{
+				Success: false,
+				Message: "Invalid " + flag.name + " parameter",
+				Errors:  uploadErrors(services.ErrCodeInvalidRequest, err),
+			})
+			return input, false
+		}
+		*flag.value = parsed
+	}
+
+	input.dateOptions = dateparse.Options{
+		Order:      strings.ToUpper(c.Query("date_order")),
+		DateSystem: c.Query("date_system"),
+	}
+	if err := dateparse.ValidateOptions(input.dateOptions); err != nil {
+		c.JSON(http.StatusBadRequest, models.UploadResponse{
+			Success: false,
+			Message: "Invalid date format parameter",
+			Errors:  uploadErrors(services.ErrCodeInvalidRequest, err),
+		})
+		return input, false
+	}
+
+	input.amountLocale = strings.ToLower(c.Query("amount_locale"))
+	if err := services.ValidateAmountLocale(input.amountLocale); err != nil {
+		c.JSON(http.StatusBadRequest, models.UploadResponse{
+			Success: false,
+			Message: "Invalid amount_locale parameter",
+			Errors:  uploadErrors(services.ErrCodeInvalidRequest, err),
+		})
+		return input, false
+	}
+
+	if err := bindDatePolicyOverride(c, &input.dateOverride); err != nil {
+		c.JSON(http.StatusBadRequest, models.UploadResponse{
+			Success: false,
+			Message: "Invalid date policy override",
+			Errors:  uploadErrors(services.ErrCodeInvalidRequest, err),
+		})
+		return input, false
+	}
+
+	return input, true
+}
+
+// bindDatePolicyOverride reads the per-upload date policy overrides min_date,
+// max_date, max_age_years and future_tolerance_days from the query string
+func bindDatePolicyOverride(c *gin.Context, override *models.DatePolicyOverride) error {
+	for _, date := range []struct {
+		name  string
+		value **string
+	}{
+		{"min_date", &override.MinDate},
+		{"max_date", &override.MaxDate},
+	} {
+		if value, ok := c.GetQuery(date.name); ok {
+			if value != "" {
+				if _, err := time.Parse("2006-01-02", value); err != nil {
+					return fmt.Errorf("invalid %s '%s', use YYYY-MM-DD", date.name, value)
+				}
+			}
+			*date.value = &value
+		}
+	}
+
+	for _, limit := range []struct {
+		name  string
+		value **int
+	}{
+		{"max_age_years", &override.MaxAgeYears},
+		{"future_tolerance_days", &override.FutureToleranceDays},
+	} {
+		if value := c.Query(limit.name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s '%s'", limit.name, value)
+			}
+			*limit.value = &parsed
+		}
+	}
+	return nil
+}
+
+// importInput describes a single upload handed to importPayments
+type importInput struct {
+	fileName      string
+	source        string // json, xlsx, csv
+	uploadedBy    string
+	duplicateMode string // services.DuplicateModeSkip, DuplicateModeFlag or DuplicateModeAllow
+	dryRun        bool   // process and classify only, without writing to the database
+	strict        bool   // reject the whole upload if any row fails, and save it in one transaction
+	async         bool   // run as a background job, see respondImport
+	dateOverride  models.DatePolicyOverride
+	dateOptions   dateparse.Options // forced date order or date system, empty to infer them from the column
+	amountLocale  string            // services.AmountLocaleTR or AmountLocaleEN to settle amounts like "1.234"
+	rawPayments   []models.RawPaymentData
+	readErrors    []models.RowError // problems found while reading the source file
+
+	// progress is called as rows are processed and saved; nil for synchronous uploads
+	progress func(stage string, done, total int)
+}
+
+// reportProgress forwards import progress to the job, if there is one
+func (input importInput) reportProgress(stage string, done, total int) {
+	if input.progress != nil {
+		input.progress(stage, done, total)
+	}
+}
+
+// importPayments runs raw payments through the processor, saves the results
+// under a new import batch and builds the upload response. Read errors are
+// reported alongside processing errors. Rows matching a payment from an earlier
+// upload are skipped, flagged or allowed according to the duplicate mode.
+// In dry-run mode nothing is written and the would-be payments are returned.
+// In strict mode any failed row rejects the whole upload, and the batch and all
+// payments are written in a single transaction.
+func (h *UploadHandler) importPayments(input importInput) models.UploadResponse {
+	// Create payment processor with the saved date policy and this upload's overrides
 	processor := services.NewPaymentProcessor()
+	datePolicy, err := loadDatePolicy(h.db)
+	if err != nil {
+		log.Printf("Error loading date policy, using defaults: %v", err)
+	}
+	datePolicy = services.ApplyDatePolicyOverride(datePolicy, input.dateOverride)
+	if err := services.ValidateDatePolicy(datePolicy); err != nil {
+		return models.UploadResponse{
+			Success: false,
+			Message: "Invalid date policy override",
+			Errors:  uploadErrors(services.ErrCodeInvalidRequest, err),
+		}
+	}
+	processor.SetDatePolicy(datePolicy)
+	processor.SetDateOptions(input.dateOptions)
+	setGoldValuation(h.db, processor)
+	setClassificationRules(h.db, processor)
+	setProjects(h.db, processor)
+	setLocations(h.db, processor)
 
 	// Process all payments
-	processedPayments, errors := processor.ProcessBatch(req.RawPayments)
-	log.Printf("Processed %d payments with %d errors", len(processedPayments), len(errors))
+	processedPayments, processErrors := processor.ProcessBatchWithProgress(input.rawPayments, func(done, total int) {
+		input.reportProgress(models.JobStageProcessing, done, total)
+	})
+	rowErrors := append(append([]models.RowError{}, input.readErrors...), processErrors...)
+	sort.SliceStable(rowErrors, func(i, j int) bool { return rowErrors[i].Row < rowErrors[j].Row })
+	log.Printf("Processed %d payments with %d errors", len(processedPayments), len(rowErrors))
+	dateFormat := processor.DateFormat()
+
+	if input.strict && len(rowErrors) > 0 {
+		response := rejectStrictImport(input, fmt.Sprintf("%d rows failed processing or validation", len(rowErrors)), rowErrors)
+		response.DateFormat = &dateFormat
+		if !input.dryRun {
+			batchID, err := h.recordRejectedImport(input, rowErrors)
+			if err != nil {
+				log.Printf("Error recording rejected import: %v", err)
+			}
+			response.BatchID = batchID
+		}
+		return response
+	}
+
+	// Strict imports write through a transaction, everything else directly
+	var db sqlExecutor = h.db
+	var tx *sql.Tx
+	if input.strict && !input.dryRun {
+		var err error
+		tx, err = h.db.Begin()
+		if err != nil {
+			log.Printf("Error starting import transaction: %v", err)
+			return rejectStrictImport(input, "failed to start transaction", uploadErrors(services.ErrCodeDatabase, err))
+		}
+		defer tx.Rollback()
+		db = tx
+	}
+
+	// Match customer names to the customer master table
+	customers, err := loadCustomerDirectory(db)
+	if err != nil {
+		log.Printf("Error loading customers: %v", err)
+		return models.UploadResponse{
+			Success: false,
+			Message: "Failed to load customers",
+			Strict:  input.strict,
+			Errors:  append(rowErrors, uploadErrors(services.ErrCodeDatabase, err)...),
+		}
+	}
+
+	// Record the batch so the upload can be rolled back later
+	var batchID int64
+	if !input.dryRun {
+		var err error
+		totalRows := len(input.rawPayments) + len(input.readErrors)
+		batchID, err = createImportBatch(db, input, totalRows, models.ImportStatusCompleted)
+		if err != nil {
+			log.Printf("Error creating import batch: %v", err)
+			return models.UploadResponse{
+				Success: false,
+				Message: "Failed to create import batch",
+				Strict:  input.strict,
+				Errors:  append(rowErrors, uploadErrors(services.ErrCodeDatabase, err)...),
+			}
+		}
+	}
 
-	// Save processed payments to database
+	// Save processed payments to database (dry runs only collect them)
 	var savedPayments []models.PaymentRecord
+	var suggestions []models.CustomerSuggestion
+	var duplicates []models.DuplicateRow
+	skipped := 0
+	// Refunds a dry run would book, by original payment, since they are not saved
+	pendingRefunds := make(map[int64]money.Amount)
 	for i, payment := range processedPayments {
-		if err := h.savePayment(payment); err != nil {
-			errors = append(errors, fmt.Sprintf("Database error for %s: %v", payment.CustomerName, err))
+		input.reportProgress(models.JobStageSaving, i, len(processedPayments))
+
+		// Payments are saved and fingerprinted under their customer's name; a new
+		// customer that looks like an existing one is reported so the two can be merged
+		customer, similar := customers.resolve(payment.CustomerName)
+		rawName := payment.CustomerName
+		payment.CustomerName = customer.Name
+		payment.Fingerprint = services.PaymentFingerprint(payment)
+		existingID, err := findDuplicatePayment(db, payment.Fingerprint, batchID)
+		if err != nil {
+			if input.strict {
+				return rejectStrictImport(input, "database error", []models.RowError{paymentDatabaseError(payment, err)})
+			}
+			rowErrors = append(rowErrors, paymentDatabaseError(payment, err))
+			continue
+		}
+
+		if existingID > 0 {
+			duplicate := models.DuplicateRow{
+				RowNumber:    payment.RowNumber,
+				CustomerName: payment.CustomerName,
+				PaymentDate:  payment.PaymentDate,
+				Amount:       payment.Amount,
+				Currency:     payment.Currency,
+				ExistingID:   existingID,
+			}
+			switch input.duplicateMode {
+			case services.DuplicateModeSkip:
+				duplicate.Action = "skipped"
+			case services.DuplicateModeFlag:
+				duplicate.Action = "flagged"
+				payment.DuplicateOf = &existingID
+			default:
+				duplicate.Action = "allowed"
+			}
+			duplicates = append(duplicates, duplicate)
+			log.Printf("Duplicate row %d (%s) matches payment %d: %s", payment.RowNumber, payment.CustomerName, existingID, duplicate.Action)
+
+			if input.duplicateMode == services.DuplicateModeSkip {
+				skipped++
+				continue
+			}
+		}
+
+		// Refunds and reversals must fit what is left of the collection they reference
+		var pending money.Amount
+		if payment.OriginalID != nil {
+			pending = pendingRefunds[*payment.OriginalID]
+		}
+		if err := checkOriginalPaymentPending(db, &payment, pending); err != nil {
+			rowError := originalPaymentError(payment, err)
+			if input.strict {
+				return rejectStrictImport(input, "invalid original payment", []models.RowError{rowError})
+			}
+			rowErrors = append(rowErrors, rowError)
+			continue
+		}
+
+		// A new customer is only created together with its first payment
+		created := false
+		if !input.dryRun {
+			created, err = customers.save(&customer)
+			if err != nil {
+				if input.strict {
+					return rejectStrictImport(input, "database error", []models.RowError{paymentDatabaseError(payment, err)})
+				}
+				rowErrors = append(rowErrors, paymentDatabaseError(payment, err))
+				continue
+			}
+			payment.CustomerID = &customer.ID
+		}
+		if len(similar) > 0 {
+			suggestions = append(suggestions, models.CustomerSuggestion{
+				CustomerName:  rawName,
+				CustomerID:    customer.ID,
+				SuggestedID:   similar[0].ID,
+				SuggestedName: similar[0].Name,
+				Similarity:    similar[0].Similarity,
+			})
+		}
+
+		payment.RawData = paymentRawData(payment, input, dateFormat)
+
+		if input.dryRun {
+			if payment.OriginalID != nil {
+				pendingRefunds[*payment.OriginalID] += payment.Amount
+			}
+			savedPayments = append(savedPayments, payment)
+			continue
+		}
+
+		if _, err := savePayment(db, payment, batchID); err != nil {
+			if input.strict {
+				log.Printf("Strict import failed at row %d, rolling back: %v", payment.RowNumber, err)
+				return rejectStrictImport(input, fmt.Sprintf("database error at row %d", payment.RowNumber),
+					[]models.RowError{paymentDatabaseError(payment, err)})
+			}
+			rowErrors = append(rowErrors, paymentDatabaseError(payment, err))
+			if created {
+				if err := customers.discard(&customer); err != nil {
+					log.Printf("Error removing customer %s created for row %d: %v", customer.Name, payment.RowNumber, err)
+				}
+			}
 			continue
 		}
 		savedPayments = append(savedPayments, payment)
 		log.Printf("Saved payment %d: %s - %s - %.2f %s", i+1, payment.CustomerName, payment.PaymentDate.Format("2006-01-02"), payment.Amount, payment.Currency)
 	}
 
+	if !input.dryRun {
+		if err := saveRowErrors(db, batchID, rowErrors); err != nil {
+			log.Printf("Error saving row errors for import batch %d: %v", batchID, err)
+		}
+		if err := finishImportBatch(db, batchID, len(savedPayments), len(rowErrors)); err != nil {
+			log.Printf("Error updating import batch %d: %v", batchID, err)
+			if input.strict {
+				return rejectStrictImport(input, "failed to update import batch", uploadErrors(services.ErrCodeDatabase, err))
+			}
+		}
+	}
+
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			log.Printf("Error committing strict import: %v", err)
+			return rejectStrictImport(input, "failed to commit transaction", uploadErrors(services.ErrCodeDatabase, err))
+		}
+		log.Printf("Strict import committed: batch %d with %d payments", batchID, len(savedPayments))
+	}
+
 	// Generate reports
 	weeklyReports := services.GenerateWeeklyReports(savedPayments)
 	log.Printf("Generated %d weekly reports", len(weeklyReports))
 
 	message := fmt.Sprintf("Processed %d payments successfully", len(savedPayments))
+	if skipped > 0 {
+		message += fmt.Sprintf(", %d duplicates skipped", skipped)
+	}
 
 	response := models.UploadResponse{
 		Success:       len(savedPayments) > 0,
 		Message:       message,
 		Processed:     len(savedPayments),
-		Errors:        errors,
+		BatchID:       batchID,
+		DryRun:        input.dryRun,
+		Strict:        input.strict,
+		DuplicateMode: input.duplicateMode,
+		Duplicates:    duplicates,
+		Errors:        rowErrors,
+		DateFormat:    &dateFormat,
 		WeeklyReports: weeklyReports,
+
+		CustomerSuggestions: suggestions,
+	}
+
+	if input.dryRun {
+		response.Payments = savedPayments
+		response.Message = fmt.Sprintf("Dry run: %d payments would be imported", len(savedPayments))
+		if skipped > 0 {
+			response.Message += fmt.Sprintf(", %d duplicates would be skipped", skipped)
+		}
+		response.Message += "; weekly totals cover the uploaded rows only, not the stored payments"
 	}
 
 	if len(savedPayments) == 0 {
 		response.Success = false
 		response.Message = "No payments were processed successfully"
+		if skipped > 0 && len(rowErrors) == 0 {
+			// Re-uploading a file that is already in the database is not an error
+			response.Success = true
+			response.Message = fmt.Sprintf("All %d payments already exist, nothing was imported", skipped)
+		}
+	}
+
+	// Report a guessed or inconsistent day/month order instead of hiding it
+	switch {
+	case dateFormat.Conflicting:
+		response.Message += fmt.Sprintf("; the date column mixes DD/MM and MM/DD dates (%s), set date_order to import them", dateFormat.Conflict)
+	case dateFormat.Ambiguous:
+		response.Message += fmt.Sprintf("; no date decides between DD/MM and MM/DD, %s was assumed", dateFormat.Order)
 	}
 
-	log.Printf("Upload response: Success=%t, Processed=%d, Errors=%d", response.Success, response.Processed, len(response.Errors))
-	c.JSON(http.StatusOK, response)
+	log.Printf("Upload response: Success=%t, Processed=%d, Duplicates=%d, Errors=%d", response.Success, response.Processed, len(response.Duplicates), len(response.Errors))
+	return response
+}
+
+// rejectStrictImport builds the response for a strict upload that was refused.
+// Nothing from the upload is kept in the database.
+func rejectStrictImport(input importInput, reason string, errors []models.RowError) models.UploadResponse {
+	log.Printf("Strict import of %s rejected: %s", input.fileName, reason)
+	return models.UploadResponse{
+		Success:       false,
+		Message:       "Strict mode: upload rejected (" + reason + "), nothing was imported",
+		DryRun:        input.dryRun,
+		Strict:        true,
+		DuplicateMode: input.duplicateMode,
+		Errors:        errors,
+	}
+}
+
+// paymentRawData builds the raw_data JSON of a payment: the complete input row
+// plus where it came from and the date format it was parsed with, for auditing
+func paymentRawData(payment models.PaymentRecord, input importInput, dateFormat dateparse.ColumnFormat) string {
+	if payment.Original == nil {
+		return ""
+	}
+	data, _ := json.Marshal(models.PaymentRawData{
+		Original:   *payment.Original,
+		SourceFile: input.fileName,
+		Source:     input.source,
+		RowNumber:  payment.RowNumber,
+		UploadedBy: input.uploadedBy,
+		DateOrder:  dateFormat.Order,
+		DateSystem: dateFormat.DateSystem,
+	})
+	return string(data)
+}
+
+// uploadErrors wraps an error that is not tied to a row for UploadResponse.Errors
+func uploadErrors(code string, err error) []models.RowError {
+	return []models.RowError{services.NewRowError(0, "", "", code, err)}
+}
+
+// paymentDatabaseError reports a failed insert or lookup for a processed payment
+func paymentDatabaseError(payment models.PaymentRecord, err error) models.RowError {
+	rowError := services.NewRowError(payment.RowNumber, "", "", services.ErrCodeDatabase, err)
+	rowError.Customer = payment.CustomerName
+	return rowError
 }
 
 // validatePayment validates a payment record
@@ -98,7 +580,7 @@ func validatePayment(payment models.PaymentRecord) error {
 	if payment.PaymentDate.IsZero() {
 		return fmt.Errorf("payment date is required")
 	}
-	if payment.Amount <= 0 {
+	if payment.Amount == 0 || payment.Kind == models.PaymentKindCollection && payment.Amount < 0 {
 		return fmt.Errorf("amount must be greater than 0")
 	}
 	if payment.Currency == "" {
@@ -115,8 +597,7 @@ func validatePayment(payment models.PaymentRecord) error {
 	}
 
 	// Validate currency
-	validCurrencies := []string{models.CurrencyTL, models.CurrencyUSD, models.CurrencyEUR}
-	if !contains(validCurrencies, payment.Currency) {
+	if !services.IsCurrencyCode(payment.Currency) && payment.GoldGrams == 0 {
 		return fmt.Errorf("invalid currency: %s", payment.Currency)
 	}
 
@@ -126,30 +607,23 @@ func validatePayment(payment models.PaymentRecord) error {
 		return fmt.Errorf("invalid payment method: %s", payment.PaymentMethod)
 	}
 
-	// Validate project
-	validProjects := []string{models.ProjectMKM, models.ProjectMSM}
-	if !contains(validProjects, payment.Project) {
-		return fmt.Errorf("invalid project: %s", payment.Project)
-	}
-
 	return nil
 }
 
-// savePayment saves a payment record to the database (allowing duplicates)
-func (h *UploadHandler) savePayment(payment models.PaymentRecord) error {
-	// Note: Removed duplicate checking to allow duplicate payments in reports
+// savePayment saves a payment record to the database or the import transaction
+// and returns its ID. Duplicate handling is decided by importPayments before
+// this is called. A batchID of 0 saves a payment that was not imported.
+func savePayment(db sqlExecutor, payment models.PaymentRecord, batchID int64) (int64, error) {
 	query := `
 		INSERT INTO payments (
 			customer_name, payment_date, amount, currency, payment_method,
-			location, project, account_name, amount_usd, exchange_rate, raw_data, created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			location, project, account_name, amount_usd, exchange_rate, raw_data, created_at, batch_id,
+			fingerprint, duplicate_of, kind, original_payment_id, gold_grams, customer_id, matched_rules,
+			review_status, review_reasons
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	// Create raw data JSON for audit purposes
-	rawData := fmt.Sprintf(`{"original_date":"%s","processed_date":"%s","amount":%.2f,"currency":"%s"}`, 
-		payment.PaymentDate, payment.PaymentDate, payment.Amount, payment.Currency)
-
-	_, err := h.db.Exec(query,
+	result, err := db.Exec(query,
 		payment.CustomerName,
 		payment.PaymentDate,
 		payment.Amount,
@@ -160,16 +634,28 @@ func (h *UploadHandler) savePayment(payment models.PaymentRecord) error {
 		payment.AccountName,
 		payment.AmountUSD,
 		payment.ExchangeRate,
-		rawData,
+		payment.RawData,
 		payment.CreatedAt,
+		sql.NullInt64{Int64: batchID, Valid: batchID > 0},
+		payment.Fingerprint,
+		payment.DuplicateOf,
+		payment.Kind,
+		payment.OriginalID,
+		payment.GoldGrams,
+		payment.CustomerID,
+		matchedRulesJSON(payment),
+		payment.ReviewStatus,
+		reviewReasonsJSON(payment),
 	)
-
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
 }
 
 // GetPayments retrieves all payments from the database
 func (h *UploadHandler) GetPayments(c *gin.Context) {
-	query := `SELECT id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name, amount_usd, exchange_rate, created_at, raw_data, includes_kdv, kdv_amount, kdv_rate, kdv_note FROM payments ORDER BY payment_date DESC`
+	query := `SELECT id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name, amount_usd, exchange_rate, created_at, raw_data, kind, original_payment_id, gold_grams, includes_kdv, kdv_amount, kdv_rate, kdv_note, batch_id, customer_id, COALESCE(review_status, '') FROM payments ORDER BY payment_date DESC`
 	rows, err := h.db.Query(query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -194,10 +680,16 @@ func (h *UploadHandler) GetPayments(c *gin.Context) {
 			&payment.ExchangeRate,
 			&payment.CreatedAt,
 			&payment.RawData,
+			&payment.Kind,
+			&payment.OriginalID,
+			&payment.GoldGrams,
 			&payment.IncludesKdv,
 			&payment.KdvAmount,
 			&payment.KdvRate,
 			&payment.KdvNote,
+			&payment.BatchID,
+			&payment.CustomerID,
+			&payment.ReviewStatus,
 		)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -217,7 +709,7 @@ func (h *UploadHandler) GetReports(c *gin.Context) {
 	log.Printf("GetReports called from %s, Authorization present: %t", remoteIP, authHdr != "")
 
 	// Get all payments
-	query := `SELECT id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name, amount_usd, exchange_rate, created_at, raw_data, includes_kdv, kdv_amount, kdv_rate, kdv_note FROM payments ORDER BY payment_date`
+	query := `SELECT id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name, amount_usd, exchange_rate, created_at, raw_data, kind, original_payment_id, gold_grams, includes_kdv, kdv_amount, kdv_rate, kdv_note, COALESCE(review_status, '') FROM payments ORDER BY payment_date`
 	rows, err := h.db.Query(query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -242,10 +734,14 @@ func (h *UploadHandler) GetReports(c *gin.Context) {
 			&payment.ExchangeRate,
 			&payment.CreatedAt,
 			&payment.RawData,
+			&payment.Kind,
+			&payment.OriginalID,
+			&payment.GoldGrams,
 			&payment.IncludesKdv,
 			&payment.KdvAmount,
 			&payment.KdvRate,
 			&payment.KdvNote,
+			&payment.ReviewStatus,
 		)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -260,9 +756,33 @@ func (h *UploadHandler) GetReports(c *gin.Context) {
 	weeklyReports := services.GenerateWeeklyReports(payments)
 	monthlyReports := services.GenerateMonthlyReports(payments)
 
+	// Projects in report order, including codes of deleted projects that still have payments
+	projects, err := loadProjects(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	locations, err := loadLocations(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	totals := models.ProjectTotal{}
+	reportedLocations := make(map[string]bool)
+	for _, report := range monthlyReports {
+		for code, amount := range report.ProjectSummary {
+			totals[code] += amount
+		}
+		for code := range report.LocationSummary {
+			reportedLocations[code] = true
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"weekly_reports":  weeklyReports,
 		"monthly_reports": monthlyReports,
+		"projects":        services.ReportProjects(projects, totals),
+		"locations":       services.ReportLocations(locations, reportedLocations),
 	})
 }
 
@@ -316,11 +836,10 @@ func (h *UploadHandler) GetYearlyReport(c *gin.Context) {
 				"start_date": startYear,
 				"end_date": endYear,
 			},
-			"project_summary": models.ProjectTotal{MKM: 0, MSM: 0},
+			"project_summary": models.ProjectTotal{},
 			"location_summary": make(map[string]models.LocationTotal),
 			"payment_methods": make(map[string]models.PaymentMethodTotal),
-			"mkm_payment_methods": make(map[string]models.PaymentMethodTotal),
-			"msm_payment_methods": make(map[string]models.PaymentMethodTotal),
+			"project_payment_methods": make(map[string]map[string]models.PaymentMethodTotal),
 		})
 		return
 	}
@@ -331,7 +850,8 @@ func (h *UploadHandler) GetYearlyReport(c *gin.Context) {
 	if yearCount > 0 {
 		query = `
 		SELECT id, customer_name, amount, currency, payment_method, payment_date, 
-		       account_name, project, location, amount_usd, exchange_rate, created_at, raw_data
+		       account_name, project, location, amount_usd, exchange_rate, created_at, raw_data, kind, original_payment_id, gold_grams,
+		       COALESCE(review_status, '')
 		FROM payments 
 		WHERE strftime('%Y', payment_date) = ?
 		ORDER BY payment_date ASC`
@@ -339,7 +859,8 @@ func (h *UploadHandler) GetYearlyReport(c *gin.Context) {
 	} else {
 		query = `
 		SELECT id, customer_name, amount, currency, payment_method, payment_date, 
-		       account_name, project, location, amount_usd, exchange_rate, created_at, raw_data
+		       account_name, project, location, amount_usd, exchange_rate, created_at, raw_data, kind, original_payment_id, gold_grams,
+		       COALESCE(review_status, '')
 		FROM payments 
 		WHERE payment_date >= ? AND payment_date < ?
 		ORDER BY payment_date ASC`
@@ -371,6 +892,10 @@ func (h *UploadHandler) GetYearlyReport(c *gin.Context) {
 			&payment.ExchangeRate,
 			&payment.CreatedAt,
 			&rawData,
+			&payment.Kind,
+			&payment.OriginalID,
+			&payment.GoldGrams,
+			&payment.ReviewStatus,
 		)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -382,6 +907,19 @@ func (h *UploadHandler) GetYearlyReport(c *gin.Context) {
 	// Generate yearly report
 	yearlyReport := services.GenerateYearlyReport(payments, year)
 
+	projects, err := loadProjects(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	yearlyReport.Projects = services.ReportProjects(projects, yearlyReport.ProjectSummary)
+	locations, err := loadLocations(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	yearlyReport.Locations = services.ReportLocations(locations, services.LocationCodes(yearlyReport.LocationSummary))
+
 	c.JSON(http.StatusOK, yearlyReport)
 }
 
@@ -396,7 +934,7 @@ func (h *UploadHandler) DeletePayment(c *gin.Context) {
 	
 	// First, get the payment details for audit purposes
 	var payment models.PaymentRecord
-	selectQuery := `SELECT id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name, amount_usd, exchange_rate, created_at, raw_data FROM payments WHERE id = ?`
+	selectQuery := `SELECT id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name, amount_usd, exchange_rate, created_at, raw_data, kind, original_payment_id, gold_grams FROM payments WHERE id = ?`
 	err := h.db.QueryRow(selectQuery, paymentID).Scan(
 		&payment.ID,
 		&payment.CustomerName,
@@ -411,6 +949,9 @@ func (h *UploadHandler) DeletePayment(c *gin.Context) {
 		&payment.ExchangeRate,
 		&payment.CreatedAt,
 		&payment.RawData,
+		&payment.Kind,
+		&payment.OriginalID,
+		&payment.GoldGrams,
 	)
 	
 	if err != nil {
@@ -423,6 +964,18 @@ func (h *UploadHandler) DeletePayment(c *gin.Context) {
 		return
 	}
 	
+	// Refunds and reversals would lose the collection they net out
+	var refunds int
+	if err := h.db.QueryRow(`SELECT COUNT(*) FROM payments WHERE original_payment_id = ?`, paymentID).Scan(&refunds); err != nil {
+		log.Printf("Error checking refunds of payment %s: %v", paymentID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if refunds > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Payment has %d refunds or reversals, delete them first", refunds)})
+		return
+	}
+
 	// Delete the payment
 	deleteQuery := `DELETE FROM payments WHERE id = ?`
 	result, err := h.db.Exec(deleteQuery, paymentID)
@@ -498,7 +1051,7 @@ func (h *UploadHandler) UpdatePaymentKDV(c *gin.Context) {
 	
 	// Retrieve the updated payment record
 	var payment models.PaymentRecord
-	selectQuery := `SELECT id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name, amount_usd, exchange_rate, created_at, raw_data, includes_kdv, kdv_amount, kdv_rate, kdv_note FROM payments WHERE id = ?`
+	selectQuery := `SELECT id, customer_name, payment_date, amount, currency, payment_method, location, project, account_name, amount_usd, exchange_rate, created_at, raw_data, kind, original_payment_id, gold_grams, includes_kdv, kdv_amount, kdv_rate, kdv_note FROM payments WHERE id = ?`
 	
 	err = h.db.QueryRow(selectQuery, paymentID).Scan(
 		&payment.ID,
@@ -514,6 +1067,9 @@ func (h *UploadHandler) UpdatePaymentKDV(c *gin.Context) {
 		&payment.ExchangeRate,
 		&payment.CreatedAt,
 		&payment.RawData,
+		&payment.Kind,
+		&payment.OriginalID,
+		&payment.GoldGrams,
 		&payment.IncludesKdv,
 		&payment.KdvAmount,
 		&payment.KdvRate,
@@ -665,7 +1221,7 @@ func (h *UploadHandler) DeletePaymentsByDateRange(c *gin.Context) {
 	var auditRecords []map[string]interface{}
 	for auditRows.Next() {
 		var customerName, paymentMethod, currency, paymentDate, project string
-		var amount float64
+		var amount money.Amount
 		auditRows.Scan(&customerName, &paymentMethod, &amount, &currency, &paymentDate, &project)
 		auditRecords = append(auditRecords, map[string]interface{}{
 			"customer_name": customerName,
@@ -718,8 +1274,8 @@ func (h *UploadHandler) GetDatabaseStats(c *gin.Context) {
 	h.db.QueryRow("SELECT COUNT(*) FROM payments").Scan(&totalRecords)
 	stats["total_records"] = totalRecords
 
-	// Records by currency
-	currencyQuery := `SELECT currency, COUNT(*) as count, SUM(amount) as total_amount FROM payments GROUP BY currency`
+	// Records by currency, summed in minor units so the totals are exact
+	currencyQuery := `SELECT currency, COUNT(*) as count, ` + money.SumSQL("amount") + ` as total_amount FROM payments GROUP BY currency`
 	rows, err := h.db.Query(currencyQuery)
 	if err == nil {
 		defer rows.Close()
@@ -727,11 +1283,11 @@ func (h *UploadHandler) GetDatabaseStats(c *gin.Context) {
 		for rows.Next() {
 			var currency string
 			var count int
-			var totalAmount float64
-			rows.Scan(&currency, &count, &totalAmount)
+			var totalMinor int64
+			rows.Scan(&currency, &count, &totalMinor)
 			currencyStats[currency] = map[string]interface{}{
 				"count":        count,
-				"total_amount": totalAmount,
+				"total_amount": money.Amount(totalMinor),
 			}
 		}
 		stats["by_currency"] = currencyStats
@@ -787,7 +1343,7 @@ func (h *UploadHandler) auditDataBeforeDeletion() map[string]interface{} {
 			var samples []map[string]interface{}
 			for rows.Next() {
 				var date string
-				var amount float64
+				var amount money.Amount
 				var currency string
 				rows.Scan(&date, &amount, &currency)
 				samples = append(samples, map[string]interface{}{
@@ -856,7 +1412,7 @@ func (h *UploadHandler) verifyDatabaseClear() map[string]interface{} {
 			var samples []map[string]interface{}
 			for rows.Next() {
 				var date string
-				var amount float64
+				var amount money.Amount
 				var currency string
 				rows.Scan(&date, &amount, &currency)
 				samples = append(samples, map[string]interface{}{
@@ -924,7 +1480,7 @@ func (h *UploadHandler) AuditReportGeneration(c *gin.Context) {
 			var phantomRecords []map[string]interface{}
 			for rows.Next() {
 				var date, currency, rawData string
-				var amount float64
+				var amount money.Amount
 				rows.Scan(&date, &amount, &currency, &rawData)
 				phantomRecords = append(phantomRecords, map[string]interface{}{
 					"date": date, "amount": amount, "currency": currency, "raw_data": rawData,
@@ -1036,65 +1592,3 @@ func contains(slice []string, item string) bool {
 	}
 	return false
 }
-
-// ParseDate parses date in DD/MM/YYYY format or Excel serial date
-func ParseDate(dateStr string) (time.Time, error) {
-	// Try DD/MM/YYYY format first (prioritize text dates)
-	if t, err := time.Parse("02/01/2006", dateStr); err == nil {
-		log.Printf("Parsed DD/MM/YYYY date %s -> %s", dateStr, t.Format("2006-01-02"))
-		return t, nil
-	}
-
-	// Try DD-MM-YYYY format
-	if t, err := time.Parse("02-01-2006", dateStr); err == nil {
-		log.Printf("Parsed DD-MM-YYYY date %s -> %s", dateStr, t.Format("2006-01-02"))
-		return t, nil
-	}
-
-	// Try YYYY-MM-DD format
-	if t, err := time.Parse("2006-01-02", dateStr); err == nil {
-		log.Printf("Parsed YYYY-MM-DD date %s -> %s", dateStr, t.Format("2006-01-02"))
-		return t, nil
-	}
-
-	// Try to parse as Excel serial date (number)
-	if floatVal, err := strconv.ParseFloat(dateStr, 64); err == nil && floatVal > 1 && floatVal < 100000 {
-		// Excel serial date: days since January 1, 1900
-		// Adjust for Excel's leap year bug (1900 is not a leap year)
-		days := int(floatVal) - 1 // Excel starts from 1, not 0
-		if floatVal > 59 {
-			days = days - 1 // Adjust for Excel's 1900 leap year bug
-		}
-		
-		// Convert to Go time
-		excelEpoch := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
-		date := excelEpoch.AddDate(0, 0, days)
-		
-		// Add fractional day (time portion)
-		fractionalDay := floatVal - float64(int(floatVal))
-		hours := fractionalDay * 24
-		minutes := (hours - float64(int(hours))) * 60
-		seconds := (minutes - float64(int(minutes))) * 60
-		
-		date = date.Add(time.Duration(int(hours)) * time.Hour)
-		date = date.Add(time.Duration(int(minutes)) * time.Minute)
-		date = date.Add(time.Duration(int(seconds)) * time.Second)
-		
-		log.Printf("Parsed Excel serial date %s (%.6f) -> %s", dateStr, floatVal, date.Format("2006-01-02"))
-		return date, nil
-	}
-
-	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
-}
-
-// ParseAmount parses amount string to float64
-func ParseAmount(amountStr string) (float64, error) {
-	// Remove any currency symbols and spaces
-	amountStr = strings.TrimSpace(amountStr)
-	amountStr = strings.ReplaceAll(amountStr, "₺", "")
-	amountStr = strings.ReplaceAll(amountStr, "$", "")
-	amountStr = strings.ReplaceAll(amountStr, "€", "")
-	amountStr = strings.ReplaceAll(amountStr, ",", "")
-
-	return strconv.ParseFloat(amountStr, 64)
-}
@@ -21,6 +21,36 @@ func main() {
 	}
 	defer db.Close()
 
+	// Fingerprint payments stored before duplicate detection was added
+	if err := handlers.BackfillPaymentFingerprints(db); err != nil {
+		log.Printf("Failed to backfill payment fingerprints: %v", err)
+	}
+
+	// Link payments stored before the customer table existed to their customers
+	if err := handlers.BackfillPaymentCustomers(db); err != nil {
+		log.Printf("Failed to backfill payment customers: %v", err)
+	}
+
+	// Save the built-in classification rules so they can be edited
+	if err := handlers.SeedClassificationRules(db); err != nil {
+		log.Printf("Failed to save default classification rules: %v", err)
+	}
+
+	// Save the built-in projects and their name patterns
+	if err := handlers.SeedProjects(db); err != nil {
+		log.Printf("Failed to save default projects: %v", err)
+	}
+
+	// Save the built-in locations and re-label stored payment locations
+	if err := handlers.SeedLocations(db); err != nil {
+		log.Printf("Failed to save default locations: %v", err)
+	}
+
+	// Queue payments stored before the review queue existed that hold a fallback classification
+	if err := handlers.BackfillPaymentReviews(db); err != nil {
+		log.Printf("Failed to backfill payment reviews: %v", err)
+	}
+
 	// Initialize Gin router
 	r := gin.Default()
 
@@ -41,6 +71,13 @@ func main() {
 	// Initialize handlers
 	uploadHandler := handlers.NewUploadHandler(db)
 	exportHandler := handlers.NewExportHandler(db)
+	profileHandler := handlers.NewImportProfileHandler(db)
+	settingsHandler := handlers.NewSettingsHandler(db)
+	customerHandler := handlers.NewCustomerHandler(db)
+	classificationHandler := handlers.NewClassificationHandler(db)
+	projectHandler := handlers.NewProjectHandler(db)
+	locationHandler := handlers.NewLocationHandler(db)
+	reviewHandler := handlers.NewReviewHandler(db)
 
 	// Public routes (no authentication)
 	public := r.Group("/api/public")
@@ -54,6 +91,8 @@ func main() {
 	{
 		api.GET("/auth/check", handlers.CheckAuthHandler)
 		api.POST("/upload", uploadHandler.UploadPayments)
+		api.POST("/upload/xlsx", uploadHandler.UploadXLSX)        // Server-side Excel import
+		api.POST("/upload/csv", uploadHandler.UploadCSV)          // Server-side CSV import
 		api.POST("/analyze", uploadHandler.GetRawPaymentInfo)     // Add analyze endpoint
 		api.GET("/payments", uploadHandler.GetPayments)
 		api.GET("/reports", uploadHandler.GetReports)
@@ -61,12 +100,63 @@ func main() {
 		api.DELETE("/payments", uploadHandler.ClearAllPayments) // Add clear endpoint
 		api.DELETE("/payments/:id", uploadHandler.DeletePayment) // Add individual payment delete endpoint
 		api.PUT("/payments/:id/kdv", uploadHandler.UpdatePaymentKDV) // Add KDV update endpoint
+		api.POST("/payments/reprocess", uploadHandler.ReprocessPayments) // Re-run classification from stored raw data
+		api.GET("/payments/:id/audit", uploadHandler.GetPaymentAudit) // Original row and classification decisions
+		api.POST("/payments/:id/refund", uploadHandler.CreateRefund)  // Book a refund or reversal of a collection
 		api.DELETE("/payments/date-range", uploadHandler.DeletePaymentsByDateRange) // Add date range delete endpoint
 		api.GET("/stats", uploadHandler.GetDatabaseStats)       // Add stats endpoint
 		api.GET("/audit/report", uploadHandler.AuditReportGeneration) // Add report audit endpoint
 		api.GET("/export/excel", exportHandler.ExportExcel)
 		api.GET("/export/yearly/excel/:year", exportHandler.ExportYearlyExcel) // Add yearly Excel export
 		api.GET("/export/pdf", exportHandler.ExportPDF)
+		api.GET("/imports", uploadHandler.ListImports)              // List import batches
+		api.DELETE("/imports/:id", uploadHandler.RollbackImport)    // Undo a single import batch
+		api.GET("/imports/:id/failed-rows", uploadHandler.ExportFailedRows) // Failed rows of an import as Excel
+		api.GET("/imports/jobs/:id", uploadHandler.GetImportJob)    // Status of a background import
+		api.GET("/imports/jobs/:id/events", uploadHandler.StreamImportJob) // Progress of a background import (SSE)
+
+		// Import column-mapping profiles
+		api.GET("/import-profiles", profileHandler.ListProfiles)
+		api.GET("/import-profiles/:id", profileHandler.GetProfile)
+		api.POST("/import-profiles", profileHandler.CreateProfile)
+		api.PUT("/import-profiles/:id", profileHandler.UpdateProfile)
+		api.DELETE("/import-profiles/:id", profileHandler.DeleteProfile)
+
+		// Settings
+		api.GET("/settings/date-policy", settingsHandler.GetDatePolicy)
+		api.PUT("/settings/date-policy", settingsHandler.UpdateDatePolicy)
+		api.GET("/settings/gold-units", settingsHandler.GetGoldUnits)
+		api.PUT("/settings/gold-units", settingsHandler.UpdateGoldUnits)
+		api.GET("/gold-prices", settingsHandler.GetGoldPrices)          // Gold price table used to value gold payments
+		api.PUT("/gold-prices", settingsHandler.UpdateGoldPrices)       // Add or replace prices by date
+		api.DELETE("/gold-prices/:date", settingsHandler.DeleteGoldPrice)
+
+		// Customer master table
+		api.GET("/customers", customerHandler.ListCustomers)             // ?q= finds customers with a similar name
+		api.POST("/customers/:id/merge", customerHandler.MergeCustomers) // Move payments of duplicate customers to this one
+
+		// Classification rules for payment method, location and project
+		api.GET("/classification/rules", classificationHandler.ListRules) // ?field= limits to one field
+		api.POST("/classification/rules", classificationHandler.CreateRule)
+		api.PUT("/classification/rules/:id", classificationHandler.UpdateRule)
+		api.DELETE("/classification/rules/:id", classificationHandler.DeleteRule)
+		api.POST("/classification/simulate", classificationHandler.SimulateRules) // Changes a proposed rule set would make, without saving
+
+		// Projects payments are reported under
+		api.GET("/projects", projectHandler.ListProjects)
+		api.POST("/projects", projectHandler.CreateProject)
+		api.PUT("/projects/:code", projectHandler.UpdateProject)
+		api.DELETE("/projects/:code", projectHandler.DeleteProject) // Only projects no payment or rule uses
+
+		// Locations payments are reported under
+		api.GET("/locations", locationHandler.ListLocations)
+		api.POST("/locations", locationHandler.CreateLocation)
+		api.PUT("/locations/:code", locationHandler.UpdateLocation)
+		api.DELETE("/locations/:code", locationHandler.DeleteLocation) // Only locations no payment or rule uses
+
+		// Review queue of payments classified by a fallback value
+		api.GET("/review", reviewHandler.ListReviews)        // ?status=pending|confirmed|corrected|all
+		api.POST("/review/:id", reviewHandler.ReviewPayment) // Confirm or correct the classification
 	}
 
 	// Serve static files from React build
@@ -124,7 +214,19 @@ func initDB() (*sql.DB, error) {
 		includes_kdv BOOLEAN DEFAULT FALSE,
 		kdv_amount REAL,
 		kdv_rate REAL,
-		kdv_note TEXT
+		kdv_note TEXT,
+		batch_id INTEGER REFERENCES import_batches(id),
+		fingerprint TEXT,
+		duplicate_of INTEGER,
+		kind TEXT NOT NULL DEFAULT 'collection',
+		original_payment_id INTEGER REFERENCES payments(id),
+		gold_grams REAL NOT NULL DEFAULT 0,
+		customer_id INTEGER REFERENCES customers(id),
+		matched_rules TEXT,
+		review_status TEXT,
+		review_reasons TEXT,
+		review_note TEXT,
+		reviewed_at DATETIME
 	);
 	`
 
@@ -147,11 +249,188 @@ func initDB() (*sql.DB, error) {
 		db.Exec(sql) // Ignore errors - columns might already exist
 	}
 
+	// Create import profiles table (source column header -> canonical field mappings)
+	profilesTableSQL := `
+	CREATE TABLE IF NOT EXISTS import_profiles (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		description TEXT,
+		columns TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := db.Exec(profilesTableSQL); err != nil {
+		return nil, err
+	}
+
+	// Create import batches table so each upload can be listed and rolled back
+	batchesTableSQL := `
+	CREATE TABLE IF NOT EXISTS import_batches (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		file_name TEXT,
+		source TEXT NOT NULL,
+		uploaded_by TEXT,
+		total_rows INTEGER NOT NULL DEFAULT 0,
+		imported_rows INTEGER NOT NULL DEFAULT 0,
+		error_count INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		rolled_back_at DATETIME
+	);
+	`
+	if _, err := db.Exec(batchesTableSQL); err != nil {
+		return nil, err
+	}
+
+	// Keep the failed rows of each import so they can be downloaded and fixed
+	rowErrorsTableSQL := `
+	CREATE TABLE IF NOT EXISTS import_row_errors (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		batch_id INTEGER NOT NULL REFERENCES import_batches(id),
+		row_number INTEGER NOT NULL DEFAULT 0,
+		column_name TEXT,
+		raw_value TEXT,
+		customer_name TEXT,
+		code TEXT NOT NULL,
+		message TEXT NOT NULL,
+		message_en TEXT NOT NULL,
+		detail TEXT,
+		row_data TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_row_errors_batch ON import_row_errors(batch_id);
+	`
+	if _, err := db.Exec(rowErrorsTableSQL); err != nil {
+		return nil, err
+	}
+
+	// Key/value application settings stored as JSON, e.g. the import date policy
+	settingsTableSQL := `
+	CREATE TABLE IF NOT EXISTS settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := db.Exec(settingsTableSQL); err != nil {
+		return nil, err
+	}
+
+	// Locally maintained price of a gram of pure gold, used to value gold payments
+	goldPricesTableSQL := `
+	CREATE TABLE IF NOT EXISTS gold_prices (
+		price_date TEXT PRIMARY KEY,
+		usd_per_gram REAL NOT NULL,
+		note TEXT,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := db.Exec(goldPricesTableSQL); err != nil {
+		return nil, err
+	}
+
+	// Customer master table; payments are matched to it by normalized name
+	customersTableSQL := `
+	CREATE TABLE IF NOT EXISTS customers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		normalized_name TEXT NOT NULL UNIQUE,
+		merged_into INTEGER REFERENCES customers(id),
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := db.Exec(customersTableSQL); err != nil {
+		return nil, err
+	}
+
+	// Rules that set payment method, location and project from the input row
+	classificationRulesTableSQL := `
+	CREATE TABLE IF NOT EXISTS classification_rules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		field TEXT NOT NULL,
+		source TEXT NOT NULL,
+		match_type TEXT NOT NULL,
+		pattern TEXT NOT NULL,
+		value TEXT NOT NULL,
+		priority INTEGER NOT NULL DEFAULT 0,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		note TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := db.Exec(classificationRulesTableSQL); err != nil {
+		return nil, err
+	}
+
+	// Projects with the Proje Adı patterns that classify payments into them
+	projectsTableSQL := `
+	CREATE TABLE IF NOT EXISTS projects (
+		code TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		patterns TEXT NOT NULL,
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := db.Exec(projectsTableSQL); err != nil {
+		return nil, err
+	}
+
+	// Locations with the Hesap Adı patterns and payment methods that classify payments into them
+	locationsTableSQL := `
+	CREATE TABLE IF NOT EXISTS locations (
+		code TEXT PRIMARY KEY,
+		label TEXT NOT NULL,
+		account_patterns TEXT NOT NULL,
+		method_overrides TEXT NOT NULL,
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := db.Exec(locationsTableSQL); err != nil {
+		return nil, err
+	}
+
+	// Link payments to the import batch that created them (NULL for older rows)
+	db.Exec(`ALTER TABLE payments ADD COLUMN batch_id INTEGER REFERENCES import_batches(id)`) // Ignore error - column might already exist
+
+	// Add duplicate detection columns if they don't exist (for existing databases)
+	db.Exec(`ALTER TABLE payments ADD COLUMN fingerprint TEXT`)      // Ignore error - column might already exist
+	db.Exec(`ALTER TABLE payments ADD COLUMN duplicate_of INTEGER`)  // Ignore error - column might already exist
+
+	// Refunds and reversals; existing payments are all collections
+	db.Exec(`ALTER TABLE payments ADD COLUMN kind TEXT NOT NULL DEFAULT 'collection'`)             // Ignore error - column might already exist
+	db.Exec(`ALTER TABLE payments ADD COLUMN original_payment_id INTEGER REFERENCES payments(id)`) // Ignore error - column might already exist
+
+	// Pure gold content of gold payments, 0 for currency payments
+	db.Exec(`ALTER TABLE payments ADD COLUMN gold_grams REAL NOT NULL DEFAULT 0`) // Ignore error - column might already exist
+
+	// Customer of each payment, filled in for older rows by BackfillPaymentCustomers
+	db.Exec(`ALTER TABLE payments ADD COLUMN customer_id INTEGER REFERENCES customers(id)`) // Ignore error - column might already exist
+
+	// IDs of the classification rules that set each field, as JSON
+	db.Exec(`ALTER TABLE payments ADD COLUMN matched_rules TEXT`) // Ignore error - column might already exist
+
+	// Review of classifications that used a fallback value, filled in for older rows by BackfillPaymentReviews
+	db.Exec(`ALTER TABLE payments ADD COLUMN review_status TEXT`)  // Ignore error - column might already exist
+	db.Exec(`ALTER TABLE payments ADD COLUMN review_reasons TEXT`) // Ignore error - column might already exist
+	db.Exec(`ALTER TABLE payments ADD COLUMN review_note TEXT`)    // Ignore error - column might already exist
+	db.Exec(`ALTER TABLE payments ADD COLUMN reviewed_at DATETIME`) // Ignore error - column might already exist
+
 	// Create indexes for better performance
 	indexSQL := `
 	CREATE INDEX IF NOT EXISTS idx_payment_date ON payments(payment_date);
 	CREATE INDEX IF NOT EXISTS idx_customer_name ON payments(customer_name);
 	CREATE INDEX IF NOT EXISTS idx_project ON payments(project);
+	CREATE INDEX IF NOT EXISTS idx_batch_id ON payments(batch_id);
+	CREATE INDEX IF NOT EXISTS idx_fingerprint ON payments(fingerprint);
+	CREATE INDEX IF NOT EXISTS idx_original_payment_id ON payments(original_payment_id);
+	CREATE INDEX IF NOT EXISTS idx_customer_id ON payments(customer_id);
+	CREATE INDEX IF NOT EXISTS idx_review_status ON payments(review_status);
 	`
 
 	if _, err := db.Exec(indexSQL); err != nil {
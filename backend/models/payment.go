@@ -1,40 +1,150 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"tahsilat-raporu/dateparse"
+	"tahsilat-raporu/money"
 )
 
 // RawPaymentData represents the raw data from Excel import
 type RawPaymentData struct {
-	MusteriAdiSoyadi string  `json:"musteri_adi_soyadi"` // "Müşteri Adı Soyadı"
-	Tarih            string  `json:"tarih"`              // "Tarih" - DD/MM/YYYY format
-	TahsilatSekli    string  `json:"tahsilat_sekli"`     // "Tahsilat Şekli"
-	HesapAdi         string  `json:"hesap_adi"`          // "Hesap Adı"
-	OdenenTutar      float64 `json:"odenen_tutar"`       // "Ödenen Tutar(Σ:...)"
-	OdenenDoviz      string  `json:"odenen_doviz"`       // "Ödenen Döviz"
-	ProjeAdi         string  `json:"proje_adi"`          // "Proje Adı"
+	MusteriAdiSoyadi string       `json:"musteri_adi_soyadi"`          // "Müşteri Adı Soyadı"
+	Tarih            string       `json:"tarih"`                       // "Tarih" - DD/MM/YYYY format
+	TahsilatSekli    string       `json:"tahsilat_sekli"`              // "Tahsilat Şekli"
+	HesapAdi         string       `json:"hesap_adi"`                   // "Hesap Adı"
+	OdenenTutar      money.Amount `json:"odenen_tutar"`                // "Ödenen Tutar(Σ:...)"
+	OdenenDoviz      string       `json:"odenen_doviz"`                // "Ödenen Döviz"
+	ProjeAdi         string       `json:"proje_adi"`                   // "Proje Adı"
+	RowNumber        int          `json:"row_number,omitempty"`        // Source file row, set by server-side imports
+	DateSystem       string       `json:"date_system,omitempty"`       // "1904" for serial dates from a 1904-based workbook
+	IslemTuru        string       `json:"islem_turu,omitempty"`        // "İşlem Türü": tahsilat, iade or iptal (optional)
+	OrijinalOdemeNo  string       `json:"orijinal_odeme_no,omitempty"` // "Orijinal Ödeme No": ID of the refunded payment (optional)
+	// Amount exactly as it was typed, when the file or JSON upload had it as
+	// text; OdenenTutar holds the amount parsed from it
+	OdenenTutarText string `json:"odenen_tutar_text,omitempty"`
+}
+
+// UnmarshalJSON accepts odenen_tutar as a number or as text such as "1.234,56 TL"
+func (r *RawPaymentData) UnmarshalJSON(data []byte) error {
+	type plain RawPaymentData
+	aux := struct {
+		*plain
+		OdenenTutar json.RawMessage `json:"odenen_tutar"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	switch {
+	case len(aux.OdenenTutar) == 0 || string(aux.OdenenTutar) == "null":
+		return nil
+	case aux.OdenenTutar[0] == '"':
+		return json.Unmarshal(aux.OdenenTutar, &r.OdenenTutarText)
+	default:
+		return json.Unmarshal(aux.OdenenTutar, &r.OdenenTutar)
+	}
+}
+
+// Canonical RawPaymentData field names used when mapping source columns
+const (
+	FieldMusteriAdiSoyadi = "musteri_adi_soyadi"
+	FieldTarih            = "tarih"
+	FieldTahsilatSekli    = "tahsilat_sekli"
+	FieldHesapAdi         = "hesap_adi"
+	FieldOdenenTutar      = "odenen_tutar"
+	FieldOdenenDoviz      = "odenen_doviz"
+	FieldProjeAdi         = "proje_adi"
+	FieldIslemTuru        = "islem_turu"
+	FieldOrijinalOdemeNo  = "orijinal_odeme_no"
+)
+
+// RawPaymentFields lists the canonical fields every import must have a column for
+var RawPaymentFields = []string{
+	FieldMusteriAdiSoyadi,
+	FieldTarih,
+	FieldTahsilatSekli,
+	FieldHesapAdi,
+	FieldOdenenTutar,
+	FieldOdenenDoviz,
+	FieldProjeAdi,
+}
+
+// OptionalRawPaymentFields lists canonical fields an import may have a column for
+var OptionalRawPaymentFields = []string{
+	FieldIslemTuru,
+	FieldOrijinalOdemeNo,
+}
+
+// RawPaymentFieldHeaders gives the column header users see for each field
+var RawPaymentFieldHeaders = map[string]string{
+	FieldMusteriAdiSoyadi: "Müşteri Adı Soyadı",
+	FieldTarih:            "Tarih",
+	FieldTahsilatSekli:    "Tahsilat Şekli",
+	FieldHesapAdi:         "Hesap Adı",
+	FieldOdenenTutar:      "Ödenen Tutar",
+	FieldOdenenDoviz:      "Ödenen Döviz",
+	FieldProjeAdi:         "Proje Adı",
+	FieldIslemTuru:        "İşlem Türü",
+	FieldOrijinalOdemeNo:  "Orijinal Ödeme No",
+}
+
+// ImportProfile maps source column headers to canonical RawPaymentData fields
+// so that files from different branches or ERP versions can be imported
+type ImportProfile struct {
+	ID          int               `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Columns     map[string]string `json:"columns"` // source header -> canonical field
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// ImportProfileRequest represents the request to create or update an import profile
+type ImportProfileRequest struct {
+	Name        string            `json:"name" binding:"required"`
+	Description string            `json:"description"`
+	Columns     map[string]string `json:"columns" binding:"required"`
 }
 
 // PaymentRecord represents a processed payment record
 type PaymentRecord struct {
-	ID            int       `json:"id" db:"id"`
-	CustomerName  string    `json:"customer_name" db:"customer_name"`
-	PaymentDate   time.Time `json:"payment_date" db:"payment_date"`
-	Amount        float64   `json:"amount" db:"amount"`
-	Currency      string    `json:"currency" db:"currency"`             // TL, USD, EUR
-	PaymentMethod string    `json:"payment_method" db:"payment_method"` // Nakit, Banka Havalesi, Çek
-	Location      string    `json:"location" db:"location"`             // ÇARŞI, KUYUMCUKENT, OFİS, BANKA HAVALESİ
-	Project       string    `json:"project" db:"project"`               // MKM, MSM
-	AccountName   string    `json:"account_name" db:"account_name"`
-	AmountUSD     float64   `json:"amount_usd" db:"amount_usd"`       // Calculated
-	ExchangeRate  float64   `json:"exchange_rate" db:"exchange_rate"` // Used rate
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	RawData       string    `json:"raw_data" db:"raw_data"`           // Original raw data for audit
+	ID            int          `json:"id" db:"id"`
+	CustomerName  string       `json:"customer_name" db:"customer_name"`
+	PaymentDate   time.Time    `json:"payment_date" db:"payment_date"`
+	Amount        money.Amount `json:"amount" db:"amount"`
+	Currency      string       `json:"currency" db:"currency"`             // TL, USD, EUR or a gold unit code
+	PaymentMethod string       `json:"payment_method" db:"payment_method"` // Nakit, Banka Havalesi, Çek
+	Location      string       `json:"location" db:"location"`             // Location code, e.g. CARSI
+	Project       string       `json:"project" db:"project"`               // Project code, e.g. MKM
+	AccountName   string       `json:"account_name" db:"account_name"`
+	AmountUSD     money.Amount `json:"amount_usd" db:"amount_usd"`       // Calculated
+	ExchangeRate  money.Rate   `json:"exchange_rate" db:"exchange_rate"` // Used rate
+	CreatedAt     time.Time    `json:"created_at" db:"created_at"`
+	RawData       string       `json:"raw_data" db:"raw_data"`                                 // Original raw data for audit
+	BatchID       *int64       `json:"batch_id,omitempty" db:"batch_id"`                       // Import batch that created the payment
+	Fingerprint   string       `json:"fingerprint,omitempty" db:"fingerprint"`                 // Customer/date/amount/currency/account/project hash
+	DuplicateOf   *int64       `json:"duplicate_of,omitempty" db:"duplicate_of"`               // Existing payment this one duplicates (flag mode)
+	Kind          string       `json:"kind" db:"kind"`                                         // collection, refund, reversal
+	OriginalID    *int64       `json:"original_payment_id,omitempty" db:"original_payment_id"` // Collection a refund or reversal belongs to
+	GoldGrams     float64      `json:"gold_grams,omitempty" db:"gold_grams"`                   // Pure gold content of a gold payment
+	CustomerID    *int64       `json:"customer_id,omitempty" db:"customer_id"`                 // Entry of the customer master table
+	RowNumber     int          `json:"row_number,omitempty" db:"-"`                            // Source row, only set during import
+	// Classification rule that set each classified field, by field
+	MatchedRules map[string]int64 `json:"matched_rules,omitempty" db:"matched_rules"`
+	// Review of a classification that used a fallback value
+	ReviewStatus  string         `json:"review_status,omitempty" db:"review_status"`   // pending, confirmed or corrected; empty if no review is needed
+	ReviewReasons []ReviewReason `json:"review_reasons,omitempty" db:"review_reasons"` // Fields that got their fallback value
+	ReviewNote    string         `json:"review_note,omitempty" db:"review_note"`       // Operator's note on the review
+	ReviewedAt    *time.Time     `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	// Import-only data, not stored in a column of its own
+	Original *RawPaymentData `json:"-" db:"-"` // Input row, used to build RawData
 	// KDV (Tax) related fields
-	IncludesKdv *bool    `json:"includes_kdv" db:"includes_kdv"`     // Whether payment includes KDV
-	KdvAmount   *float64 `json:"kdv_amount" db:"kdv_amount"`         // KDV amount
-	KdvRate     *float64 `json:"kdv_rate" db:"kdv_rate"`             // KDV rate percentage
-	KdvNote     *string  `json:"kdv_note" db:"kdv_note"`             // KDV related note
+	IncludesKdv *bool         `json:"includes_kdv" db:"includes_kdv"` // Whether payment includes KDV
+	KdvAmount   *money.Amount `json:"kdv_amount" db:"kdv_amount"`     // KDV amount
+	KdvRate     *float64      `json:"kdv_rate" db:"kdv_rate"`         // KDV rate percentage
+	KdvNote     *string       `json:"kdv_note" db:"kdv_note"`         // KDV related note
 }
 
 // WeeklyReport represents a weekly report structure
@@ -42,69 +152,335 @@ type WeeklyReport struct {
 	StartDate       time.Time                     `json:"start_date"`
 	EndDate         time.Time                     `json:"end_date"`
 	WeekNumber      string                        `json:"week_number"`
-	CustomerSummary map[string]float64            `json:"customer_summary"`
+	CustomerSummary map[string]money.Amount       `json:"customer_summary"`
 	PaymentMethods  map[string]PaymentMethodTotal `json:"payment_methods"`
 	ProjectSummary  ProjectTotal                  `json:"project_summary"`
-	LocationSummary map[string]LocationTotal      `json:"location_summary"`
+	LocationSummary map[string]LocationTotal      `json:"location_summary"` // location code -> totals
+	KindSummary     KindTotal                     `json:"kind_summary"`
+	GoldSummary     map[string]GoldTotal          `json:"gold_summary"` // gold unit code -> totals
+	Unconfirmed     ReviewTotal                   `json:"unconfirmed"`  // payments still awaiting review
 	Payments        []PaymentRecord               `json:"payments"`
 }
 
 // PaymentMethodTotal represents totals by payment method
 type PaymentMethodTotal struct {
-	TL       float64 `json:"tl"`
-	USD      float64 `json:"usd"`
-	TotalUSD float64 `json:"total_usd"` // Grand total in USD (TL converted + USD)
+	Currencies map[string]money.Amount `json:"currencies"` // currency -> total in that currency, before conversion
+	GoldGrams  float64                 `json:"gold_grams"` // Pure gold content of gold payments
+	TotalUSD   money.Amount            `json:"total_usd"`  // Grand total in USD (all currencies converted + gold valued)
+}
+
+// ProjectTotal holds the USD total of each project, by project code
+type ProjectTotal map[string]money.Amount
+
+// KindTotal splits a report's net USD total into gross collections and the
+// refunds and reversals netted out of them. Refunds and reversals are negative.
+type KindTotal struct {
+	Collections   money.Amount `json:"collections"`
+	Refunds       money.Amount `json:"refunds"`
+	Reversals     money.Amount `json:"reversals"`
+	Net           money.Amount `json:"net"`
+	RefundCount   int          `json:"refund_count"`
+	ReversalCount int          `json:"reversal_count"`
 }
 
-// ProjectTotal represents project-based totals
-type ProjectTotal struct {
-	MKM float64 `json:"mkm"`
-	MSM float64 `json:"msm"`
+// ReviewTotal is the part of a report's total whose classification still
+// awaits review
+type ReviewTotal struct {
+	Count     int          `json:"count"`
+	AmountUSD money.Amount `json:"amount_usd"`
+	Share     float64      `json:"share"` // Percent of the report's net USD total
+}
+
+// GoldTotal sums the gold payments made in one gold unit
+type GoldTotal struct {
+	Quantity  money.Amount `json:"quantity"`   // In the unit, e.g. grams or pieces
+	FineGrams float64      `json:"fine_grams"` // Pure gold content
+	USD       money.Amount `json:"usd"`        // Valued at the gold price of each payment date
 }
 
 // LocationTotal represents totals by location
 type LocationTotal struct {
-	MKM   float64 `json:"mkm"`
-	MSM   float64 `json:"msm"`
-	Total float64 `json:"total"`
+	Projects ProjectTotal `json:"projects"` // USD collected at the location for each project
+	Total    money.Amount `json:"total"`
 }
 
 // MonthlyReport represents monthly aggregated data
 type MonthlyReport struct {
-	Month           time.Time                `json:"month"`
-	ProjectSummary  ProjectTotal             `json:"project_summary"`
-	LocationSummary map[string]LocationTotal `json:"location_summary"`
-	DailyTotals     map[string]float64       `json:"daily_totals"` // date string -> USD amount
-	PaymentMethods  map[string]PaymentMethodTotal `json:"payment_methods"` // payment method breakdown
-	MKMPaymentMethods map[string]PaymentMethodTotal `json:"mkm_payment_methods"` // MKM project payment methods
-	MSMPaymentMethods map[string]PaymentMethodTotal `json:"msm_payment_methods"` // MSM project payment methods
+	Month                 time.Time                                `json:"month"`
+	ProjectSummary        ProjectTotal                             `json:"project_summary"`
+	LocationSummary       map[string]LocationTotal                 `json:"location_summary"` // location code -> totals
+	DailyTotals           map[string]money.Amount                  `json:"daily_totals"`     // date string -> USD amount
+	PaymentMethods        map[string]PaymentMethodTotal            `json:"payment_methods"`  // payment method breakdown
+	KindSummary           KindTotal                                `json:"kind_summary"`
+	GoldSummary           map[string]GoldTotal                     `json:"gold_summary"`            // gold unit code -> totals
+	Unconfirmed           ReviewTotal                              `json:"unconfirmed"`             // payments still awaiting review
+	ProjectPaymentMethods map[string]map[string]PaymentMethodTotal `json:"project_payment_methods"` // project code -> payment method breakdown
 }
 
 // YearlyReport represents yearly aggregated payment data
 type YearlyReport struct {
-	Year              int                          `json:"year"`
-	ProjectSummary    ProjectTotal                 `json:"project_summary"`
-	LocationSummary   map[string]LocationTotal     `json:"location_summary"`
-	PaymentMethods    map[string]PaymentMethodTotal `json:"payment_methods"` // payment method breakdown
-	MKMPaymentMethods map[string]PaymentMethodTotal `json:"mkm_payment_methods"` // MKM project payment methods
-	MSMPaymentMethods map[string]PaymentMethodTotal `json:"msm_payment_methods"` // MSM project payment methods
-	MonthlyReports    []MonthlyReport              `json:"monthly_reports"` // monthly breakdown
+	Year                  int                                      `json:"year"`
+	ProjectSummary        ProjectTotal                             `json:"project_summary"`
+	LocationSummary       map[string]LocationTotal                 `json:"location_summary"` // location code -> totals
+	PaymentMethods        map[string]PaymentMethodTotal            `json:"payment_methods"`  // payment method breakdown
+	KindSummary           KindTotal                                `json:"kind_summary"`
+	GoldSummary           map[string]GoldTotal                     `json:"gold_summary"`            // gold unit code -> totals
+	Unconfirmed           ReviewTotal                              `json:"unconfirmed"`             // payments still awaiting review
+	MonthlyReports        []MonthlyReport                          `json:"monthly_reports"`         // monthly breakdown
+	ProjectPaymentMethods map[string]map[string]PaymentMethodTotal `json:"project_payment_methods"` // project code -> payment method breakdown
+	Projects              []Project                                `json:"projects,omitempty"`      // Configured projects in report order, set by the handler
+	Locations             []Location                               `json:"locations,omitempty"`     // Configured locations in report order, set by the handler
 }
 
 // UploadRequest represents the request structure for file upload
 type UploadRequest struct {
 	RawPayments []RawPaymentData `json:"raw_payments"`
+	FileName    string           `json:"file_name,omitempty"` // Source file name recorded on the import batch
 }
 
 // UploadResponse represents the response after processing upload
 type UploadResponse struct {
-	Success       bool           `json:"success"`
-	Message       string         `json:"message"`
-	Processed     int            `json:"processed"`
-	Errors        []string       `json:"errors,omitempty"`
-	WeeklyReports []WeeklyReport `json:"weekly_reports,omitempty"`
+	Success       bool                    `json:"success"`
+	Message       string                  `json:"message"`
+	Processed     int                     `json:"processed"`
+	BatchID       int64                   `json:"batch_id,omitempty"`
+	DryRun        bool                    `json:"dry_run,omitempty"`
+	Strict        bool                    `json:"strict,omitempty"`
+	DuplicateMode string                  `json:"duplicate_mode,omitempty"`
+	Duplicates    []DuplicateRow          `json:"duplicates,omitempty"`
+	Errors        []RowError              `json:"errors,omitempty"`
+	DateFormat    *dateparse.ColumnFormat `json:"date_format,omitempty"`    // How the Tarih column was interpreted
+	WeeklyReports []WeeklyReport          `json:"weekly_reports,omitempty"` // Totals of this upload's payments alone, not of the stored ones
+	Payments      []PaymentRecord         `json:"payments,omitempty"`       // Would-be payments, only returned for dry runs
+	// New customer names that look like an existing customer and may need merging
+	CustomerSuggestions []CustomerSuggestion `json:"customer_suggestions,omitempty"`
+}
+
+// PaymentRawData is the audit record stored as JSON in payments.raw_data
+type PaymentRawData struct {
+	Original   RawPaymentData `json:"original"` // Row exactly as it was uploaded
+	SourceFile string         `json:"source_file,omitempty"`
+	Source     string         `json:"source,omitempty"` // json, xlsx, csv
+	RowNumber  int            `json:"row_number,omitempty"`
+	UploadedBy string         `json:"uploaded_by,omitempty"`
+	DateOrder  string         `json:"date_order,omitempty"`  // DMY, MDY or YMD, as inferred for the upload's date column
+	DateSystem string         `json:"date_system,omitempty"` // 1900 or 1904, as used for the upload's serial dates
+}
+
+// ClassificationDecision shows how one payment field was derived from the input
+type ClassificationDecision struct {
+	Field   string            `json:"field"`             // e.g. payment_method
+	Input   map[string]string `json:"input"`             // Raw values the decision was based on
+	Stored  string            `json:"stored"`            // Value saved with the payment
+	Current string            `json:"current,omitempty"` // Value today's rules would produce
+	Changed bool              `json:"changed"`           // Current differs from stored
+	// Rule today's rules take Current from; nil when the field fell back to its default
+	Rule *ClassificationRule `json:"rule,omitempty"`
+}
+
+// PaymentAudit shows a payment next to its original input and classification decisions
+type PaymentAudit struct {
+	Payment       PaymentRecord            `json:"payment"`
+	RawData       *PaymentRawData          `json:"raw_data,omitempty"`
+	LegacyRawData string                   `json:"legacy_raw_data,omitempty"` // raw_data of payments saved before the full row was kept
+	Decisions     []ClassificationDecision `json:"decisions"`
+}
+
+// ReprocessRequest selects stored payments to classify and convert again.
+// All filters are optional and combined with AND.
+type ReprocessRequest struct {
+	StartDate string `json:"start_date"` // YYYY-MM-DD, inclusive
+	EndDate   string `json:"end_date"`   // YYYY-MM-DD, inclusive
+	Project   string `json:"project"`
+	BatchID   int64  `json:"batch_id"`
+	Confirm   bool   `json:"confirm"` // false only returns the diff
+}
+
+// FieldChange is a single field that reprocessing changes
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ReprocessChange lists the changes reprocessing makes to one payment
+type ReprocessChange struct {
+	PaymentID    int           `json:"payment_id"`
+	CustomerName string        `json:"customer_name"`
+	PaymentDate  time.Time     `json:"payment_date"`
+	Changes      []FieldChange `json:"changes"`
+}
+
+// ReprocessResponse reports the diff of a reprocess run and whether it was applied
+type ReprocessResponse struct {
+	Success       bool              `json:"success"`
+	Message       string            `json:"message"`
+	Applied       bool              `json:"applied"`
+	Matched       int               `json:"matched"`        // Payments selected by the filters
+	Changed       int               `json:"changed"`        // Payments with at least one change
+	SkippedLegacy int               `json:"skipped_legacy"` // Payments without a stored input row
+	Changes       []ReprocessChange `json:"changes"`
+	Errors        []string          `json:"errors,omitempty"`
+}
+
+// ClassificationSimulationRequest is a proposed classification rule set. It
+// replaces all saved rules for the simulation; rules without an ID are new.
+type ClassificationSimulationRequest struct {
+	Rules []ClassificationRule `json:"rules" binding:"required"`
+}
+
+// TotalShift is a report total that a rule change would move, in USD
+type TotalShift struct {
+	Period string       `json:"period"` // Week as "2024-01-15 - 2024-01-21", or month as "2024-01"
+	Total  string       `json:"total"`  // Report field, e.g. payment_methods.Nakit or location_summary.OFIS.projects.MKM
+	Before money.Amount `json:"before"`
+	After  money.Amount `json:"after"`
+	Delta  money.Amount `json:"delta"`
+}
+
+// ClassificationSimulation shows what a proposed rule set would change in the
+// stored payments and in the weekly and monthly reports. Nothing is saved.
+type ClassificationSimulation struct {
+	Matched       int               `json:"matched"`        // Stored payments
+	Changed       int               `json:"changed"`        // Payments whose method, location or project would change
+	SkippedLegacy int               `json:"skipped_legacy"` // Payments without a stored input row, kept as they are
+	Changes       []ReprocessChange `json:"changes"`
+	WeeklyShifts  []TotalShift      `json:"weekly_shifts"`
+	MonthlyShifts []TotalShift      `json:"monthly_shifts"`
+}
+
+// DatePolicy decides which payment dates are accepted on import.
+// Dates are YYYY-MM-DD; empty dates and zero limits are not checked.
+type DatePolicy struct {
+	MinDate             string          `json:"min_date,omitempty"`    // Earliest accepted date
+	MaxDate             string          `json:"max_date,omitempty"`    // Latest accepted date
+	MaxAgeYears         int             `json:"max_age_years"`         // Reject dates older than this many years
+	FutureToleranceDays int             `json:"future_tolerance_days"` // Accept dates up to this many days after today, -1 for no limit
+	BlockedPeriods      []BlockedPeriod `json:"blocked_periods"`       // Periods no payment may fall into
+}
+
+// BlockedPeriod is a closed date range (YYYY-MM-DD, inclusive) rejected on import
+type BlockedPeriod struct {
+	Start  string `json:"start" binding:"required"`
+	End    string `json:"end" binding:"required"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// DatePolicyOverride changes parts of the date policy for a single upload
+type DatePolicyOverride struct {
+	MinDate              *string
+	MaxDate              *string
+	MaxAgeYears          *int
+	FutureToleranceDays  *int
+	IgnoreBlockedPeriods bool
+}
+
+// RowError describes a problem with an uploaded row, or with the upload as a
+// whole when Row is 0
+type RowError struct {
+	Row       int    `json:"row,omitempty"`      // Source row number
+	Column    string `json:"column,omitempty"`   // Column header, e.g. "Tarih"
+	Value     string `json:"value,omitempty"`    // Raw cell value that was rejected
+	Customer  string `json:"customer,omitempty"` // Customer name of the row, if known
+	Code      string `json:"code"`               // Stable error code, e.g. invalid_date
+	Message   string `json:"message"`            // Turkish message for staff
+	MessageEN string `json:"message_en"`         // English message
+	Detail    string `json:"detail,omitempty"`   // Technical cause
+
+	// RowData holds the original cell values by field so that failed rows can
+	// be written back to a workbook
+	RowData map[string]string `json:"-"`
 }
 
+// DuplicateRow describes an uploaded row that matched an existing payment
+type DuplicateRow struct {
+	RowNumber    int          `json:"row_number"`
+	CustomerName string       `json:"customer_name"`
+	PaymentDate  time.Time    `json:"payment_date"`
+	Amount       money.Amount `json:"amount"`
+	Currency     string       `json:"currency"`
+	ExistingID   int64        `json:"existing_id"` // Payment already in the database
+	Action       string       `json:"action"`      // skipped, flagged, allowed
+}
+
+// Customer is an entry of the customer master table. Payments are matched to
+// a customer by NormalizedName; a merged customer points to the one it was
+// merged into, so that its spelling keeps matching on later imports.
+type Customer struct {
+	ID             int64     `json:"id"`
+	Name           string    `json:"name"`            // Display name, as first imported
+	NormalizedName string    `json:"normalized_name"` // Case- and whitespace-insensitive key (Turkish casing)
+	MergedInto     *int64    `json:"merged_into,omitempty"`
+	PaymentCount   int       `json:"payment_count"`
+	Similarity     float64   `json:"similarity,omitempty"` // Only set when searching by name
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CustomerSuggestion pairs a customer created by an import with an existing
+// customer whose name is similar
+type CustomerSuggestion struct {
+	CustomerName  string  `json:"customer_name"` // Name as imported
+	CustomerID    int64   `json:"customer_id"`   // Customer the rows were saved under, 0 for dry runs
+	SuggestedID   int64   `json:"suggested_id"`
+	SuggestedName string  `json:"suggested_name"`
+	Similarity    float64 `json:"similarity"` // 0..1
+}
+
+// CustomerMergeRequest lists the duplicate customers to merge into another
+type CustomerMergeRequest struct {
+	DuplicateIDs []int64 `json:"duplicate_ids" binding:"required"`
+}
+
+// ImportBatch records a single upload so that it can be listed and rolled back
+type ImportBatch struct {
+	ID           int64      `json:"id"`
+	FileName     string     `json:"file_name"`
+	Source       string     `json:"source"` // json, xlsx, csv
+	UploadedBy   string     `json:"uploaded_by"`
+	TotalRows    int        `json:"total_rows"`
+	ImportedRows int        `json:"imported_rows"`
+	ErrorCount   int        `json:"error_count"`
+	Status       string     `json:"status"`
+	PaymentCount int        `json:"payment_count"` // Payments currently linked to the batch
+	CreatedAt    time.Time  `json:"created_at"`
+	RolledBackAt *time.Time `json:"rolled_back_at,omitempty"`
+}
+
+// Import batch statuses
+const (
+	ImportStatusCompleted  = "completed"
+	ImportStatusRolledBack = "rolled_back"
+	ImportStatusRejected   = "rejected" // strict import refused, only its row errors are kept
+)
+
+// ImportJob reports the progress of an upload running in the background
+type ImportJob struct {
+	ID         string          `json:"id"`
+	FileName   string          `json:"file_name,omitempty"`
+	Source     string          `json:"source"`
+	Status     string          `json:"status"`
+	Stage      string          `json:"stage,omitempty"` // processing, saving
+	Done       int             `json:"done"`
+	Total      int             `json:"total"`
+	CreatedAt  time.Time       `json:"created_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	Result     *UploadResponse `json:"result,omitempty"` // Set once the job has finished
+}
+
+// Import job statuses
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+// Import job stages
+const (
+	JobStageProcessing = "processing"
+	JobStageSaving     = "saving"
+)
+
 // ExportRequest represents request for report export
 type ExportRequest struct {
 	Format string `json:"format"` // "excel" or "pdf"
@@ -113,10 +489,10 @@ type ExportRequest struct {
 
 // KdvUpdateRequest represents request to update KDV information
 type KdvUpdateRequest struct {
-	IncludesKdv bool     `json:"includes_kdv"`
-	KdvAmount   *float64 `json:"kdv_amount,omitempty"`
-	KdvRate     *float64 `json:"kdv_rate,omitempty"`
-	KdvNote     *string  `json:"kdv_note,omitempty"`
+	IncludesKdv bool          `json:"includes_kdv"`
+	KdvAmount   *money.Amount `json:"kdv_amount,omitempty"`
+	KdvRate     *float64      `json:"kdv_rate,omitempty"`
+	KdvNote     *string       `json:"kdv_note,omitempty"`
 }
 
 // KdvUpdateResponse represents response after updating KDV
@@ -127,9 +503,9 @@ type KdvUpdateResponse struct {
 
 // ExchangeRate represents TCMB exchange rate data
 type ExchangeRate struct {
-	Date         time.Time `json:"date"`
-	Currency     string    `json:"currency"`
-	ForexSelling float64   `json:"forex_selling"`
+	Date         time.Time  `json:"date"`
+	Currency     string     `json:"currency"`
+	ForexSelling money.Rate `json:"forex_selling"`
 }
 
 // Valid currencies
@@ -139,45 +515,149 @@ const (
 	CurrencyEUR = "EUR"
 )
 
-// Valid payment methods
+// GoldUnit is a unit gold is paid in, e.g. gram altın or çeyrek. Payments in
+// a gold unit store its code as their currency and the quantity as amount.
+type GoldUnit struct {
+	Code    string   `json:"code"`    // e.g. XAU, CEYREK
+	Name    string   `json:"name"`    // e.g. Çeyrek Altın
+	Aliases []string `json:"aliases"` // Other "Ödenen Döviz" values meaning this unit
+	Grams   float64  `json:"grams"`   // Weight of one unit
+	Purity  float64  `json:"purity"`  // Pure gold share, e.g. 0.995 for 24 ayar, 0.916 for 22 ayar
+}
+
+// GoldPrice is the locally maintained price of one gram of pure gold on a date
+type GoldPrice struct {
+	Date       string     `json:"date"` // YYYY-MM-DD
+	USDPerGram money.Rate `json:"usd_per_gram"`
+	Note       string     `json:"note,omitempty"`
+}
+
+// Project is a configured project payments are classified into. Payments
+// store the code; reports list projects by sort order.
+type Project struct {
+	Code      string    `json:"code"`       // e.g. MKM
+	Name      string    `json:"name"`       // Display name, e.g. Model Kuyum Merkezi
+	Patterns  []string  `json:"patterns"`   // Proje Adı values containing one of these belong to the project
+	SortOrder int       `json:"sort_order"` // Lower comes first in reports and is matched first
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Location is a configured place payments are collected at. Payments store
+// the code; reports show the label and list locations by sort order.
+type Location struct {
+	Code            string    `json:"code"`             // e.g. CARSI
+	Label           string    `json:"label"`            // Shown in reports, e.g. ÇARŞI
+	AccountPatterns []string  `json:"account_patterns"` // Hesap Adı values containing one of these belong to the location
+	MethodOverrides []string  `json:"method_overrides"` // Payment methods that belong here whatever the account, e.g. Çek
+	SortOrder       int       `json:"sort_order"`       // Lower comes first in reports and is matched first
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Payment fields classification rules decide
 const (
-	PaymentMethodCash     = "Nakit"
-	PaymentMethodTransfer = "Banka Havalesi"
-	PaymentMethodCheck    = "Çek"
+	RuleFieldPaymentMethod = "payment_method"
+	RuleFieldLocation      = "location"
+	RuleFieldProject       = "project"
 )
 
-// Valid projects
+// How a classification rule compares its pattern with the source column
 const (
-	ProjectMKM = "MKM"
-	ProjectMSM = "MSM"
+	RuleMatchContains = "contains" // Case-, whitespace- and Turkish-letter-insensitive substring
+	RuleMatchEquals   = "equals"   // Same comparison as contains, on the whole value
+	RuleMatchRegex    = "regex"    // Go regular expression on the trimmed value, e.g. (?i)^nakit
 )
 
-// Valid locations
+// ClassificationRule sets a payment field when a source column of the input row
+// matches a pattern. The rules of a field are tried by ascending priority and
+// the first match decides; if none matches, the field gets its fallback value.
+type ClassificationRule struct {
+	ID        int64     `json:"id"`
+	Field     string    `json:"field"`      // payment_method, location or project
+	Source    string    `json:"source"`     // Input field, e.g. tahsilat_sekli
+	MatchType string    `json:"match_type"` // contains, equals or regex
+	Pattern   string    `json:"pattern"`
+	Value     string    `json:"value"`    // Value the field gets when the rule matches
+	Priority  int       `json:"priority"` // Lower runs first
+	Enabled   bool      `json:"enabled"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ReviewReason records a classified field of a payment that no rule matched,
+// so it got its fallback value and the payment needs review
+type ReviewReason struct {
+	Field     string `json:"field"`            // payment_method, location or project
+	Column    string `json:"column,omitempty"` // Column header the rules read, e.g. "Tahsilat Şekli"
+	Value     string `json:"value"`            // Cell value no rule matched
+	Fallback  string `json:"fallback"`         // Value the field was given
+	Code      string `json:"code"`             // Stable reason code, e.g. unknown_payment_method
+	Message   string `json:"message"`          // Turkish message for staff
+	MessageEN string `json:"message_en"`       // English message
+}
+
+// Review statuses of a payment that needs review
 const (
-	LocationCarşı       = "CARŞI"
-	LocationKuyumcukent = "KUYUMCUKENT"
-	LocationOfis        = "OFİS"
-	LocationBanka       = "BANKA HAVALESİ"
-	LocationCek         = "ÇEK"
+	ReviewStatusPending   = "pending"   // Awaiting an operator
+	ReviewStatusConfirmed = "confirmed" // Fallback classification confirmed
+	ReviewStatusCorrected = "corrected" // Classification corrected by an operator
+)
+
+// ReviewDecision confirms or corrects the classification of a payment under
+// review. Fields left empty keep their value; with none changed the payment
+// is confirmed.
+type ReviewDecision struct {
+	PaymentMethod string `json:"payment_method"`
+	Location      string `json:"location"` // Location code
+	Project       string `json:"project"`  // Project code
+	Note          string `json:"note"`
+}
+
+// Payment kinds. Refunds and reversals are stored with negative amounts so
+// that every sum over payments is already net of them.
+const (
+	PaymentKindCollection = "collection" // Tahsilat, the default
+	PaymentKindRefund     = "refund"     // İade: money paid back to the customer
+	PaymentKindReversal   = "reversal"   // İptal: a bounced transfer or cheque that cancels a collection
+)
+
+// RefundRequest records a refund or reversal of an existing collection
+type RefundRequest struct {
+	Kind   string        `json:"kind"`   // refund (default) or reversal
+	Amount *money.Amount `json:"amount"` // Positive, in the original currency; defaults to what is left to refund
+	Date   string        `json:"date"`   // YYYY-MM-DD, defaults to today
+}
+
+// Valid payment methods
+const (
+	PaymentMethodCash     = "Nakit"
+	PaymentMethodTransfer = "Banka Havalesi"
+	PaymentMethodCheck    = "Çek"
 )
 
 // Payment represents a payment record for backend yearly report handler compatibility
 type Payment struct {
-	ID            int       `json:"id" db:"id"`
-	CustomerName  string    `json:"customer_name" db:"customer_name"`
-	Amount        float64   `json:"amount" db:"amount"`
-	Currency      string    `json:"currency" db:"currency"`
-	PaymentMethod string    `json:"payment_method" db:"payment_method"`
-	PaymentDate   time.Time `json:"payment_date" db:"payment_date"`
-	AccountName   string    `json:"account_name" db:"account_name"`
-	Project       string    `json:"project" db:"project"`
-	Location      string    `json:"location" db:"location"`
-	AmountUSD     float64   `json:"amount_usd" db:"amount_usd"`
-	ExchangeRate  float64   `json:"exchange_rate" db:"exchange_rate"`
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	ID            int          `json:"id" db:"id"`
+	CustomerName  string       `json:"customer_name" db:"customer_name"`
+	Amount        money.Amount `json:"amount" db:"amount"`
+	Currency      string       `json:"currency" db:"currency"`
+	PaymentMethod string       `json:"payment_method" db:"payment_method"`
+	PaymentDate   time.Time    `json:"payment_date" db:"payment_date"`
+	AccountName   string       `json:"account_name" db:"account_name"`
+	Project       string       `json:"project" db:"project"`
+	Location      string       `json:"location" db:"location"`
+	AmountUSD     money.Amount `json:"amount_usd" db:"amount_usd"`
+	ExchangeRate  money.Rate   `json:"exchange_rate" db:"exchange_rate"`
+	CreatedAt     time.Time    `json:"created_at" db:"created_at"`
+	Kind          string       `json:"kind" db:"kind"`
+	OriginalID    *int64       `json:"original_payment_id,omitempty" db:"original_payment_id"`
+	GoldGrams     float64      `json:"gold_grams,omitempty" db:"gold_grams"`
+	ReviewStatus  string       `json:"review_status,omitempty" db:"review_status"`
 	// KDV (Tax) related fields
-	IncludesKdv *bool    `json:"includes_kdv" db:"includes_kdv"`
-	KdvAmount   *float64 `json:"kdv_amount" db:"kdv_amount"`
-	KdvRate     *float64 `json:"kdv_rate" db:"kdv_rate"`
-	KdvNote     *string  `json:"kdv_note" db:"kdv_note"`
+	IncludesKdv *bool         `json:"includes_kdv" db:"includes_kdv"`
+	KdvAmount   *money.Amount `json:"kdv_amount" db:"kdv_amount"`
+	KdvRate     *float64      `json:"kdv_rate" db:"kdv_rate"`
+	KdvNote     *string       `json:"kdv_note" db:"kdv_note"`
 }
@@ -0,0 +1,348 @@
+// Package money holds exact money amounts and exchange rates.
+//
+// Amounts are integers in minor units (kuruş, cents) and rates are fixed-point
+// with six decimals, so sums over thousands of payments match the totals an
+// accountant gets in Excel. The rounding policy is:
+//
+//   - amounts are rounded half away from zero to two decimals when they enter
+//     the system (parsed text, JSON numbers, REAL database columns), like
+//     Excel's ROUND(x; 2);
+//   - rates are rounded half away from zero to six decimals; TCMB publishes
+//     four, so only derived cross rates are ever rounded;
+//   - a conversion multiplies or divides exactly and rounds the result half
+//     away from zero to minor units once, never intermediate values.
+//
+// Both types marshal to plain JSON numbers and are stored in the REAL columns
+// the payments table has always had. A float64 holds every amount below 2^53
+// minor units closely enough for Scan to read it back to the same minor
+// units, so the columns are kept rather than changed to INTEGER, which SQLite
+// can only do by copying the whole table, and existing databases and the
+// spreadsheets that read them need no migration. What REAL does not give is
+// an exact SUM: SQL that adds amounts rounds each row first (see SumSQL).
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Amount is a money amount in minor units, e.g. 123456 for 1234.56
+type Amount int64
+
+// Rate is an exchange rate in millionths, e.g. 32512300 for 32.5123
+type Rate int64
+
+const (
+	amountScale   = 100
+	amountDigits  = 2
+	rateScale     = 1000000
+	rateDigits    = 6
+	maxFloatValue = 1 << 53 // larger values lose precision as float64
+)
+
+// FromFloat converts a float to an Amount, rounding half away from zero
+func FromFloat(f float64) Amount {
+	return Amount(math.Round(f * amountScale))
+}
+
+// ParseAmount parses a plain decimal such as "1234.567" exactly, rounding
+// half away from zero to minor units. Separators and symbols are not accepted.
+func ParseAmount(s string) (Amount, error) {
+	n, err := parseDecimal(s, amountDigits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount '%s'", s)
+	}
+	return Amount(n), nil
+}
+
+// Units returns a whole number of currency units as an Amount
+func Units(n int64) Amount {
+	return Amount(n * amountScale)
+}
+
+// Float64 returns the amount in currency units, e.g. for Excel cells
+func (a Amount) Float64() float64 {
+	return float64(a) / amountScale
+}
+
+// String formats the amount with two decimals, e.g. "-1234.50"
+func (a Amount) String() string {
+	return formatDecimal(int64(a), amountDigits)
+}
+
+// Abs returns the absolute value of a
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Convert returns a in the target currency at rate (target units per unit of a)
+func (a Amount) Convert(rate Rate) Amount {
+	// a/100 * rate/1e6 in minor units = a * rate / 1e6
+	return Amount(mulDivRound(int64(a), int64(rate), rateScale))
+}
+
+// ConvertInverse returns a in the target currency at rate (units of a per target unit)
+func (a Amount) ConvertInverse(rate Rate) (Amount, error) {
+	if rate <= 0 {
+		return 0, fmt.Errorf("exchange rate must be positive, got %s", rate)
+	}
+	// a / (rate/1e6) = a * 1e6 / rate
+	return Amount(mulDivRound(int64(a), rateScale, int64(rate))), nil
+}
+
+// ConvertCross returns a converted at rate/base in one step, e.g. EUR to USD
+// from the EUR/TL and USD/TL rates, so that no intermediate amount is rounded
+func (a Amount) ConvertCross(rate, base Rate) (Amount, error) {
+	if base <= 0 {
+		return 0, fmt.Errorf("exchange rate must be positive, got %s", base)
+	}
+	return Amount(mulDivRound(int64(a), int64(rate), int64(base))), nil
+}
+
+// Format implements fmt.Formatter so that %f, %.2f, %v and %s print units
+// rather than minor units, keeping existing log and Sprintf calls correct
+func (a Amount) Format(f fmt.State, verb rune) {
+	formatValue(f, verb, a.String(), a.Float64(), int64(a))
+}
+
+// MarshalJSON writes the amount as a JSON number with two decimals
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON reads a JSON number or numeric string exactly
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	n, err := unmarshalDecimal(data, amountDigits)
+	if err != nil {
+		return fmt.Errorf("invalid amount %s", data)
+	}
+	*a = Amount(n)
+	return nil
+}
+
+// SumSQL returns an SQL expression adding up a REAL amount column in minor
+// units, rounding each row before it is added so the sum scans exactly into
+// an int64 of minor units
+func SumSQL(column string) string {
+	return fmt.Sprintf("COALESCE(SUM(CAST(ROUND(%s * %d) AS INTEGER)), 0)", column, amountScale)
+}
+
+// Value stores the amount in a REAL column
+func (a Amount) Value() (driver.Value, error) {
+	return a.Float64(), nil
+}
+
+// Scan reads a REAL, INTEGER or text column, rounding to minor units
+func (a *Amount) Scan(src interface{}) error {
+	n, err := scanDecimal(src, amountDigits)
+	if err != nil {
+		return fmt.Errorf("cannot scan %T into money.Amount: %v", src, err)
+	}
+	*a = Amount(n)
+	return nil
+}
+
+// RateFromFloat converts a float to a Rate, rounding half away from zero
+func RateFromFloat(f float64) Rate {
+	return Rate(math.Round(f * rateScale))
+}
+
+// ParseRate parses a plain decimal rate such as "32.5123" exactly
+func ParseRate(s string) (Rate, error) {
+	n, err := parseDecimal(s, rateDigits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate '%s'", s)
+	}
+	return Rate(n), nil
+}
+
+// OneRate is the rate of a currency to itself
+const OneRate Rate = rateScale
+
+// Float64 returns the rate as a float
+func (r Rate) Float64() float64 {
+	return float64(r) / rateScale
+}
+
+// String formats the rate with six decimals, e.g. "32.512300"
+func (r Rate) String() string {
+	return formatDecimal(int64(r), rateDigits)
+}
+
+// Cross returns the rate r / other, e.g. EUR/TL divided by USD/TL gives USD per EUR
+func (r Rate) Cross(other Rate) (Rate, error) {
+	if other <= 0 {
+		return 0, fmt.Errorf("exchange rate must be positive, got %s", other)
+	}
+	return Rate(mulDivRound(int64(r), rateScale, int64(other))), nil
+}
+
+// Div returns r divided by n, e.g. a rate quoted per 100 units divided by 100
+func (r Rate) Div(n int64) (Rate, error) {
+	if n <= 0 {
+		return 0, fmt.Errorf("divisor must be positive, got %d", n)
+	}
+	return Rate(mulDivRound(int64(r), 1, n)), nil
+}
+
+// Format implements fmt.Formatter, see Amount.Format
+func (r Rate) Format(f fmt.State, verb rune) {
+	formatValue(f, verb, r.String(), r.Float64(), int64(r))
+}
+
+// MarshalJSON writes the rate as a JSON number
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON reads a JSON number or numeric string exactly
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	n, err := unmarshalDecimal(data, rateDigits)
+	if err != nil {
+		return fmt.Errorf("invalid rate %s", data)
+	}
+	*r = Rate(n)
+	return nil
+}
+
+// Value stores the rate in a REAL column
+func (r Rate) Value() (driver.Value, error) {
+	return r.Float64(), nil
+}
+
+// Scan reads a REAL, INTEGER or text column, rounding to six decimals
+func (r *Rate) Scan(src interface{}) error {
+	n, err := scanDecimal(src, rateDigits)
+	if err != nil {
+		return fmt.Errorf("cannot scan %T into money.Rate: %v", src, err)
+	}
+	*r = Rate(n)
+	return nil
+}
+
+// mulDivRound returns a*b/c rounded half away from zero, without overflowing
+// the intermediate product
+func mulDivRound(a, b, c int64) int64 {
+	num := new(big.Int).Mul(big.NewInt(a), big.NewInt(b))
+	den := big.NewInt(c)
+	negative := num.Sign()*den.Sign() < 0
+	num.Abs(num)
+	den.Abs(den)
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(den) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	if negative {
+		quo.Neg(quo)
+	}
+	return quo.Int64()
+}
+
+// parseDecimal parses "-1234.5678" into an integer scaled by 10^digits,
+// rounding half away from zero
+func parseDecimal(s string, digits int) (int64, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	integer, fraction, _ := strings.Cut(s, ".")
+	if integer == "" && fraction == "" || strings.Trim(integer+fraction, "0123456789") != "" {
+		return 0, fmt.Errorf("not a decimal number")
+	}
+
+	roundUp := false
+	if len(fraction) > digits {
+		roundUp = fraction[digits] >= '5'
+		fraction = fraction[:digits]
+	}
+	fraction += strings.Repeat("0", digits-len(fraction))
+
+	n, err := strconv.ParseInt(integer+fraction, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if roundUp {
+		n++
+	}
+	if negative {
+		n = -n
+	}
+	return n, nil
+}
+
+// formatDecimal formats an integer scaled by 10^digits
+func formatDecimal(n int64, digits int) string {
+	sign := ""
+	u := uint64(n)
+	if n < 0 {
+		sign, u = "-", uint64(-n)
+	}
+	s := strconv.FormatUint(u, 10)
+	if len(s) <= digits {
+		s = strings.Repeat("0", digits-len(s)+1) + s
+	}
+	return sign + s[:len(s)-digits] + "." + s[len(s)-digits:]
+}
+
+// unmarshalDecimal reads a JSON number, a quoted number or null
+func unmarshalDecimal(data []byte, digits int) (int64, error) {
+	s := string(data)
+	if s == "null" {
+		return 0, nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	// Exponent notation is valid JSON but not a plain decimal
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, err
+		}
+		return int64(math.Round(f * math.Pow10(digits))), nil
+	}
+	return parseDecimal(s, digits)
+}
+
+// scanDecimal converts a database value to an integer scaled by 10^digits
+func scanDecimal(src interface{}, digits int) (int64, error) {
+	scale := math.Pow10(digits)
+	switch v := src.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		if math.Abs(v*scale) > maxFloatValue {
+			return 0, fmt.Errorf("value %v is out of range", v)
+		}
+		return int64(math.Round(v * scale)), nil
+	case int64:
+		return v * int64(scale), nil
+	case []byte:
+		return parseDecimal(string(v), digits)
+	case string:
+		return parseDecimal(v, digits)
+	}
+	return 0, fmt.Errorf("unsupported type")
+}
+
+// formatValue prints a money value for fmt: %s and %v use the exact decimal
+// text, float verbs the float value and %d the scaled integer
+func formatValue(f fmt.State, verb rune, text string, value float64, scaled int64) {
+	switch verb {
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		fmt.Fprintf(f, fmt.FormatString(f, verb), value)
+	case 'd':
+		fmt.Fprintf(f, fmt.FormatString(f, verb), scaled)
+	default:
+		fmt.Fprintf(f, fmt.FormatString(f, 's'), text)
+	}
+}
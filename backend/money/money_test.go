@@ -0,0 +1,284 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		rate   Rate
+		want   Amount
+	}{
+		{100000, 30758, 3076},     // 1000.00 TL at 0.030758 = 30.758 USD
+		{100, 5000, 1},            // 1.00 at 0.005 = 0.005, half rounds away from zero
+		{-100, 5000, -1},          // -0.005 rounds to -0.01
+		{100, 4999, 0},            // 0.004999 rounds down
+		{-100, 4999, 0},           // -0.004999 rounds towards zero
+		{123456, OneRate, 123456}, // same currency
+		{0, 30758, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.Convert(tt.rate); got != tt.want {
+			t.Errorf("%s.Convert(%s) = %s, want %s", tt.amount, tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestConvertInverse(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		rate   Rate
+		want   Amount
+	}{
+		{100000, 32512300, 3076}, // 1000.00 TL at 32.5123 TL/USD = 30.7576 USD
+		{1, 2000000, 1},          // 0.01 / 2 = 0.005, half rounds away from zero
+		{-1, 2000000, -1},        // -0.005 rounds to -0.01
+		{3, 2000000, 2},          // 0.015 rounds to 0.02
+		{-3, 2000000, -2},        // -0.015 rounds to -0.02
+		{1, 3000000, 0},          // 0.0033 rounds down
+	}
+	for _, tt := range tests {
+		got, err := tt.amount.ConvertInverse(tt.rate)
+		if err != nil {
+			t.Errorf("%s.ConvertInverse(%s) returned error: %v", tt.amount, tt.rate, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s.ConvertInverse(%s) = %s, want %s", tt.amount, tt.rate, got, tt.want)
+		}
+	}
+
+	for _, rate := range []Rate{0, -1000000} {
+		if _, err := Amount(100).ConvertInverse(rate); err == nil {
+			t.Errorf("ConvertInverse(%s) accepted a non-positive rate", rate)
+		}
+	}
+}
+
+func TestConvertCross(t *testing.T) {
+	tests := []struct {
+		amount     Amount
+		rate, base Rate
+		want       Amount
+	}{
+		{10000, 35000000, 32000000, 10938},   // 100.00 EUR = 109.375 USD, half rounds away from zero
+		{-10000, 35000000, 32000000, -10938}, // -109.375 rounds to -109.38
+		{10000, 32000000, 32000000, 10000},   // same rate
+		{1, 10000000, 30000000, 0},           // 0.0033 rounds down
+		{2, 10000000, 30000000, 1},           // 0.0067 rounds up
+		{-2, 10000000, 30000000, -1},         // -0.0067 rounds to -0.01
+	}
+	for _, tt := range tests {
+		got, err := tt.amount.ConvertCross(tt.rate, tt.base)
+		if err != nil {
+			t.Errorf("%s.ConvertCross(%s, %s) returned error: %v", tt.amount, tt.rate, tt.base, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s.ConvertCross(%s, %s) = %s, want %s", tt.amount, tt.rate, tt.base, got, tt.want)
+		}
+	}
+
+	if _, err := Amount(100).ConvertCross(35000000, 0); err == nil {
+		t.Error("ConvertCross accepted a zero base rate")
+	}
+}
+
+func TestConvertCrossRoundsOnce(t *testing.T) {
+	// 0.01 EUR at 1.5 TL/EUR and 4 TL/USD is 0.00375 USD. Rounding the
+	// 0.015 TL in between would give 0.02 TL and then 0.01 USD.
+	amount, rate, base := Amount(1), Rate(1500000), Rate(4000000)
+	got, err := amount.ConvertCross(rate, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 0 {
+		t.Errorf("ConvertCross = %s, want 0.00", got)
+	}
+
+	viaTL, err := amount.Convert(rate).ConvertInverse(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if viaTL != 1 {
+		t.Errorf("converting through a rounded TL amount = %s, want 0.01", viaTL)
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Amount
+	}{
+		{"1234.56", 123456},
+		{"1234.5", 123450},
+		{"1234", 123400},
+		{".5", 50},
+		{"+12", 1200},
+		{"-12.5", -1250},
+		{" 7.25 ", 725},
+		{"1234.565", 123457}, // exact, a float64 would give 1234.56
+		{"1234.564", 123456},
+		{"0.005", 1},
+		{"-0.005", -1},
+		{"0.0049", 0},
+		{"2.675", 268},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.value)
+		if err != nil {
+			t.Errorf("ParseAmount(%q) returned error: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAmount(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", ".", "-", "1,234.56", "1.234.56", "12a", "1e3", "$5"} {
+		if got, err := ParseAmount(value); err == nil {
+			t.Errorf("ParseAmount(%q) = %s, want an error", value, got)
+		}
+	}
+}
+
+func TestFromFloat(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  Amount
+	}{
+		{1234.56, 123456},
+		{0.125, 13},
+		{-0.125, -13},
+		{0.004, 0},
+		{-0.004, 0},
+	}
+	for _, tt := range tests {
+		if got := FromFloat(tt.value); got != tt.want {
+			t.Errorf("FromFloat(%v) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRate(t *testing.T) {
+	tests := []struct {
+		name string
+		got  func() (Rate, error)
+		want Rate
+	}{
+		{"ParseRate", func() (Rate, error) { return ParseRate("32.5123") }, 32512300},
+		{"ParseRate rounds", func() (Rate, error) { return ParseRate("0.03075805") }, 30758},
+		{"Cross", func() (Rate, error) { return Rate(35000000).Cross(32000000) }, 1093750},
+		{"Cross rounds", func() (Rate, error) { return Rate(2000000).Cross(3000000) }, 666667},
+		{"Div", func() (Rate, error) { return Rate(1234567800).Div(100) }, 12345678},
+		{"Div rounds", func() (Rate, error) { return Rate(5).Div(10) }, 1},
+	}
+	for _, tt := range tests {
+		got, err := tt.got()
+		if err != nil {
+			t.Errorf("%s returned error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := Rate(1000000).Cross(0); err == nil {
+		t.Error("Cross accepted a zero rate")
+	}
+	if _, err := Rate(1000000).Div(0); err == nil {
+		t.Error("Div accepted a zero divisor")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{Amount(123450).String(), "1234.50"},
+		{Amount(-5).String(), "-0.05"},
+		{Amount(0).String(), "0.00"},
+		{fmt.Sprintf("%.2f", Amount(123456)), "1234.56"},
+		{fmt.Sprintf("%v", Amount(-123456)), "-1234.56"},
+		{fmt.Sprintf("%d", Amount(123456)), "123456"},
+		{fmt.Sprintf("%10s", Amount(100)), "      1.00"},
+		{Rate(32512300).String(), "32.512300"},
+		{fmt.Sprintf("%.4f", Rate(32512300)), "32.5123"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Amount Amount `json:"amount"`
+		Rate   Rate   `json:"rate"`
+	}{123456, 32512300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"amount":1234.56,"rate":32.512300}`; string(data) != want {
+		t.Errorf("json.Marshal = %s, want %s", data, want)
+	}
+
+	tests := []struct {
+		data string
+		want Amount
+	}{
+		{`1234.56`, 123456},
+		{`1234.565`, 123457},
+		{`"12.5"`, 1250},
+		{`-0.005`, -1},
+		{`1e3`, 100000},
+		{`null`, 0},
+	}
+	for _, tt := range tests {
+		var got Amount
+		if err := json.Unmarshal([]byte(tt.data), &got); err != nil {
+			t.Errorf("json.Unmarshal(%s) returned error: %v", tt.data, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("json.Unmarshal(%s) = %s, want %s", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want Amount
+	}{
+		{1234.56, 123456},
+		{0.1 + 0.2, 30},
+		{int64(5), 500},
+		{[]byte("1234.565"), 123457},
+		{"-7.5", -750},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		var got Amount
+		if err := got.Scan(tt.src); err != nil {
+			t.Errorf("Scan(%v) returned error: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Scan(%v) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+
+	var a Amount
+	if err := a.Scan(true); err == nil {
+		t.Error("Scan accepted a bool")
+	}
+	if err := a.Scan(1e17); err == nil {
+		t.Error("Scan accepted a value beyond float64 precision")
+	}
+}
@@ -2,9 +2,11 @@ package services
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 	"tahsilat-raporu/models"
+	"tahsilat-raporu/money"
 	"time"
 )
 
@@ -123,63 +125,42 @@ func aggregateWeek(payments []models.PaymentRecord) models.WeeklyReport {
 	report := models.WeeklyReport{
 		StartDate:       weekStart,
 		EndDate:         weekEnd,
-		CustomerSummary: make(map[string]float64),
+		CustomerSummary: make(map[string]money.Amount),
+		ProjectSummary:  make(models.ProjectTotal),
 		PaymentMethods:  make(map[string]models.PaymentMethodTotal),
 		LocationSummary: make(map[string]models.LocationTotal),
+		GoldSummary:     make(map[string]models.GoldTotal),
 		Payments:        payments,
 	}
 
 	// Initialize payment methods
-	report.PaymentMethods[models.PaymentMethodCash] = models.PaymentMethodTotal{}
-	report.PaymentMethods[models.PaymentMethodTransfer] = models.PaymentMethodTotal{}
-	report.PaymentMethods[models.PaymentMethodCheck] = models.PaymentMethodTotal{}
-
-	// Initialize locations
-	report.LocationSummary[models.LocationCarşı] = models.LocationTotal{}
-	report.LocationSummary[models.LocationKuyumcukent] = models.LocationTotal{}
-	report.LocationSummary[models.LocationOfis] = models.LocationTotal{}
-	report.LocationSummary[models.LocationBanka] = models.LocationTotal{}
-	report.LocationSummary[models.LocationCek] = models.LocationTotal{}
+	report.PaymentMethods[models.PaymentMethodCash] = models.PaymentMethodTotal{Currencies: map[string]money.Amount{}}
+	report.PaymentMethods[models.PaymentMethodTransfer] = models.PaymentMethodTotal{Currencies: map[string]money.Amount{}}
+	report.PaymentMethods[models.PaymentMethodCheck] = models.PaymentMethodTotal{Currencies: map[string]money.Amount{}}
 
 	// Aggregate payments
 	for _, payment := range payments {
+		// Refunds and reversals are negative, so every total below is net of them
+		addKindTotal(&report.KindSummary, payment.Kind, payment.AmountUSD)
+		addGoldTotal(report.GoldSummary, payment.Currency, payment.Amount, payment.GoldGrams, payment.AmountUSD)
+
 		// Customer summary
 		report.CustomerSummary[payment.CustomerName] += payment.AmountUSD
 
 		// Payment method summary
 		if method, exists := report.PaymentMethods[payment.PaymentMethod]; exists {
-			// TL column: Only add if original currency is TL
-			if payment.Currency == "TL" {
-				method.TL += payment.Amount
-			}
-			// USD column: Only add raw amount if original currency is USD
-			if payment.Currency == "USD" {
-				method.USD += payment.Amount
-			}
-			// Total USD: All payments converted to USD (this gives us the grand total)
-			method.TotalUSD += payment.AmountUSD
-			report.PaymentMethods[payment.PaymentMethod] = method
+			report.PaymentMethods[payment.PaymentMethod] = addMethodTotal(method, payment.Currency, payment.Amount, payment.GoldGrams, payment.AmountUSD)
 		}
 
 		// Project summary
-		if payment.Project == models.ProjectMKM {
-			report.ProjectSummary.MKM += payment.AmountUSD
-		} else if payment.Project == models.ProjectMSM {
-			report.ProjectSummary.MSM += payment.AmountUSD
-		}
+		report.ProjectSummary[payment.Project] += payment.AmountUSD
 
-		// Location summary based on payment method and account name
-		location := getLocationFromPayment(payment)
-		if loc, exists := report.LocationSummary[location]; exists {
-			if payment.Project == models.ProjectMKM {
-				loc.MKM += payment.AmountUSD
-			} else if payment.Project == models.ProjectMSM {
-				loc.MSM += payment.AmountUSD
-			}
-			loc.Total += payment.AmountUSD
-			report.LocationSummary[location] = loc
-		}
+		// Location summary by the stored location
+		report.LocationSummary[payment.Location] = addLocationTotal(report.LocationSummary[payment.Location], payment.Project, payment.AmountUSD)
+
+		addReviewTotal(&report.Unconfirmed, payment.ReviewStatus, payment.AmountUSD)
 	}
+	setReviewShare(&report.Unconfirmed, report.KindSummary.Net)
 
 	return report
 }
@@ -196,156 +177,136 @@ func aggregateMonth(payments []models.PaymentRecord) models.MonthlyReport {
 	report := models.MonthlyReport{
 		Month:             month,
 		LocationSummary:   make(map[string]models.LocationTotal),
-		DailyTotals:       make(map[string]float64),
+		DailyTotals:       make(map[string]money.Amount),
 		PaymentMethods:    make(map[string]models.PaymentMethodTotal),
-		MKMPaymentMethods: make(map[string]models.PaymentMethodTotal),
-		MSMPaymentMethods: make(map[string]models.PaymentMethodTotal),
-	}
+		GoldSummary:       make(map[string]models.GoldTotal),
+		ProjectSummary:    make(models.ProjectTotal),
 
-	// Initialize locations
-	report.LocationSummary[models.LocationCarşı] = models.LocationTotal{}
-	report.LocationSummary[models.LocationKuyumcukent] = models.LocationTotal{}
-	report.LocationSummary[models.LocationOfis] = models.LocationTotal{}
-	report.LocationSummary[models.LocationBanka] = models.LocationTotal{}
-	report.LocationSummary[models.LocationCek] = models.LocationTotal{}
+		ProjectPaymentMethods: make(map[string]map[string]models.PaymentMethodTotal),
+	}
 
 	// Aggregate payments
 	for _, payment := range payments {
 		// Daily totals - format date as YYYY-MM-DD
 		dateKey := payment.PaymentDate.Format("2006-01-02")
 		report.DailyTotals[dateKey] += payment.AmountUSD
+		addKindTotal(&report.KindSummary, payment.Kind, payment.AmountUSD)
+		addGoldTotal(report.GoldSummary, payment.Currency, payment.Amount, payment.GoldGrams, payment.AmountUSD)
 
 		// Project summary
-		if payment.Project == models.ProjectMKM {
-			report.ProjectSummary.MKM += payment.AmountUSD
-		} else if payment.Project == models.ProjectMSM {
-			report.ProjectSummary.MSM += payment.AmountUSD
-		}
+		report.ProjectSummary[payment.Project] += payment.AmountUSD
 
 		// Payment method summary
 		paymentMethod := payment.PaymentMethod
-		// Initialize if not exists
-		if _, exists := report.PaymentMethods[paymentMethod]; !exists {
-			report.PaymentMethods[paymentMethod] = models.PaymentMethodTotal{}
-		}
-		method := report.PaymentMethods[paymentMethod]
-		if payment.Currency == "TL" {
-			method.TL += payment.Amount
-		} else {
-			method.USD += payment.Amount
-		}
-		method.TotalUSD += payment.AmountUSD
-		report.PaymentMethods[paymentMethod] = method
+		report.PaymentMethods[paymentMethod] = addMethodTotal(report.PaymentMethods[paymentMethod], payment.Currency, payment.Amount, payment.GoldGrams, payment.AmountUSD)
 
 		// Project-specific payment method summary
-		if payment.Project == models.ProjectMKM {
-			if _, exists := report.MKMPaymentMethods[paymentMethod]; !exists {
-				report.MKMPaymentMethods[paymentMethod] = models.PaymentMethodTotal{}
-			}
-			mkmMethod := report.MKMPaymentMethods[paymentMethod]
-			if payment.Currency == "TL" {
-				mkmMethod.TL += payment.Amount
-			} else {
-				mkmMethod.USD += payment.Amount
-			}
-			mkmMethod.TotalUSD += payment.AmountUSD
-			report.MKMPaymentMethods[paymentMethod] = mkmMethod
-		} else if payment.Project == models.ProjectMSM {
-			if _, exists := report.MSMPaymentMethods[paymentMethod]; !exists {
-				report.MSMPaymentMethods[paymentMethod] = models.PaymentMethodTotal{}
-			}
-			msmMethod := report.MSMPaymentMethods[paymentMethod]
-			if payment.Currency == "TL" {
-				msmMethod.TL += payment.Amount
-			} else {
-				msmMethod.USD += payment.Amount
-			}
-			msmMethod.TotalUSD += payment.AmountUSD
-			report.MSMPaymentMethods[paymentMethod] = msmMethod
-		}
+		addProjectMethodTotal(report.ProjectPaymentMethods, payment.Project, paymentMethod, payment.Currency, payment.Amount, payment.GoldGrams, payment.AmountUSD)
 
-		// Location summary based on payment method and account name
-		location := getLocationFromPayment(payment)
-		if loc, exists := report.LocationSummary[location]; exists {
-			if payment.Project == models.ProjectMKM {
-				loc.MKM += payment.AmountUSD
-			} else if payment.Project == models.ProjectMSM {
-				loc.MSM += payment.AmountUSD
-			}
-			loc.Total += payment.AmountUSD
-			report.LocationSummary[location] = loc
-		}
+		// Location summary by the stored location
+		report.LocationSummary[payment.Location] = addLocationTotal(report.LocationSummary[payment.Location], payment.Project, payment.AmountUSD)
+
+		addReviewTotal(&report.Unconfirmed, payment.ReviewStatus, payment.AmountUSD)
 	}
+	setReviewShare(&report.Unconfirmed, report.KindSummary.Net)
 
 	return report
 }
 
-// getWeekStart returns the start of the week (Monday) for a given date
-func getWeekStart(date time.Time) time.Time {
-	// Week starts on Monday
-	weekday := date.Weekday()
-	if weekday == time.Sunday {
-		weekday = 7 // Treat Sunday as 7
+// addKindTotal adds a payment's USD amount to the line of its kind and to the net
+func addKindTotal(total *models.KindTotal, kind string, amountUSD money.Amount) {
+	switch kind {
+	case models.PaymentKindRefund:
+		total.Refunds += amountUSD
+		total.RefundCount++
+	case models.PaymentKindReversal:
+		total.Reversals += amountUSD
+		total.ReversalCount++
+	default:
+		total.Collections += amountUSD
 	}
-	daysToSubtract := int(weekday) - 1 // Monday is 1, so subtract (weekday - 1) days
-	weekStart := date.AddDate(0, 0, -daysToSubtract)
-	return time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day(), 0, 0, 0, 0, weekStart.Location())
+	total.Net += amountUSD
 }
 
-// getLocationFromPayment determines location based on payment method and account name
-func getLocationFromPayment(payment models.PaymentRecord) string {
-	// If payment method is Çek, location should be ÇEK regardless of account name
-	if payment.PaymentMethod == models.PaymentMethodCheck {
-		return models.LocationCek
+// addMethodTotal adds a payment to a payment method line: its original amount
+// to the total of its currency and its USD value to TotalUSD. Gold payments are
+// counted in grams of pure gold instead of under a currency.
+func addMethodTotal(total models.PaymentMethodTotal, currency string, amount money.Amount, goldGrams float64, amountUSD money.Amount) models.PaymentMethodTotal {
+	if total.Currencies == nil {
+		total.Currencies = make(map[string]money.Amount)
 	}
-
-	// Otherwise use account-based classification
-	return getLocationFromAccount(payment.AccountName)
+	if goldGrams != 0 {
+		total.GoldGrams = roundGrams(total.GoldGrams + goldGrams)
+	} else {
+		total.Currencies[currency] += amount
+	}
+	total.TotalUSD += amountUSD
+	return total
 }
 
-// getLocationFromAccount determines location based on account name
-func getLocationFromAccount(accountName string) string {
-	// Use the same logic as LocationClassifier for consistency
-	accountLower := strings.ToLower(accountName)
-
-	// ÇARŞI - Shopping area accounts
-	if strings.Contains(accountLower, "çarşi") ||
-		strings.Contains(accountLower, "carsi") {
-		return models.LocationCarşı
+// addProjectMethodTotal adds a payment to the payment method breakdown of its project
+func addProjectMethodTotal(tables map[string]map[string]models.PaymentMethodTotal, project, method, currency string, amount money.Amount, goldGrams float64, amountUSD money.Amount) {
+	if tables[project] == nil {
+		tables[project] = make(map[string]models.PaymentMethodTotal)
 	}
+	tables[project][method] = addMethodTotal(tables[project][method], currency, amount, goldGrams, amountUSD)
+}
 
-	// KUYUMCUKENT - Jewelry district
-	if strings.Contains(accountLower, "kuyumcukent") {
-		return models.LocationKuyumcukent
+// addLocationTotal adds a payment's USD amount to its project's column of a location
+func addLocationTotal(total models.LocationTotal, project string, amountUSD money.Amount) models.LocationTotal {
+	if total.Projects == nil {
+		total.Projects = make(models.ProjectTotal)
 	}
+	total.Projects[project] += amountUSD
+	total.Total += amountUSD
+	return total
+}
 
-	// BANKA - Bank transfers
-	if strings.Contains(accountLower, "yapi kredi") ||
-		strings.Contains(accountLower, "banka") ||
-		strings.Contains(accountLower, "havale") {
-		return models.LocationBanka
+// addReviewTotal counts a payment whose classification still awaits review
+func addReviewTotal(total *models.ReviewTotal, reviewStatus string, amountUSD money.Amount) {
+	if reviewStatus != models.ReviewStatusPending {
+		return
 	}
+	total.Count++
+	total.AmountUSD += amountUSD
+}
 
-	// ÇEK - Check payments
-	if strings.Contains(accountLower, "çek") ||
-		strings.Contains(accountLower, "cek") {
-		return models.LocationCek
+// setReviewShare sets the percentage of the net total that awaits review
+func setReviewShare(total *models.ReviewTotal, net money.Amount) {
+	if net == 0 {
+		return
 	}
+	total.Share = math.Round(total.AmountUSD.Float64()/net.Float64()*10000) / 100
+}
 
-	// OFİS - Office (including cash/kasa)
-	if strings.Contains(accountLower, "ofis") ||
-		strings.Contains(accountLower, "office") ||
-		strings.Contains(accountLower, "kasa") {
-		return models.LocationOfis
+// addGoldTotal adds a gold payment to the totals of its unit; other payments
+// have no gold content and are left out
+func addGoldTotal(totals map[string]models.GoldTotal, unit string, quantity money.Amount, fineGrams float64, amountUSD money.Amount) {
+	if fineGrams == 0 {
+		return
 	}
+	total := totals[unit]
+	total.Quantity += quantity
+	total.FineGrams = roundGrams(total.FineGrams + fineGrams)
+	total.USD += amountUSD
+	totals[unit] = total
+}
 
-	// Default to office if not specified
-	return models.LocationOfis
+// getWeekStart returns the start of the week (Monday) for a given date
+func getWeekStart(date time.Time) time.Time {
+	// Week starts on Monday
+	weekday := date.Weekday()
+	if weekday == time.Sunday {
+		weekday = 7 // Treat Sunday as 7
+	}
+	daysToSubtract := int(weekday) - 1 // Monday is 1, so subtract (weekday - 1) days
+	weekStart := date.AddDate(0, 0, -daysToSubtract)
+	return time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day(), 0, 0, 0, 0, weekStart.Location())
 }
 
 // GetTotalAmount calculates total amount for a slice of payments
-func GetTotalAmount(payments []models.PaymentRecord) float64 {
-	total := 0.0
+func GetTotalAmount(payments []models.PaymentRecord) money.Amount {
+	var total money.Amount
 	for _, payment := range payments {
 		total += payment.AmountUSD
 	}
@@ -353,8 +314,8 @@ func GetTotalAmount(payments []models.PaymentRecord) float64 {
 }
 
 // GetCustomerTotals returns a map of customer names to their total amounts
-func GetCustomerTotals(payments []models.PaymentRecord) map[string]float64 {
-	totals := make(map[string]float64)
+func GetCustomerTotals(payments []models.PaymentRecord) map[string]money.Amount {
+	totals := make(map[string]money.Amount)
 	for _, payment := range payments {
 		totals[payment.CustomerName] += payment.AmountUSD
 	}
@@ -364,15 +325,13 @@ func GetCustomerTotals(payments []models.PaymentRecord) map[string]float64 {
 // GenerateYearlyReport generates a yearly summary report
 func GenerateYearlyReport(payments []models.Payment, year int) models.YearlyReport {
 	report := models.YearlyReport{
-		Year: year,
-		ProjectSummary: models.ProjectTotal{
-			MKM: 0,
-			MSM: 0,
-		},
+		Year:              year,
+		ProjectSummary:    make(models.ProjectTotal),
 		LocationSummary:   make(map[string]models.LocationTotal),
 		PaymentMethods:    make(map[string]models.PaymentMethodTotal),
-		MKMPaymentMethods: make(map[string]models.PaymentMethodTotal),
-		MSMPaymentMethods: make(map[string]models.PaymentMethodTotal),
+		GoldSummary:       make(map[string]models.GoldTotal),
+
+		ProjectPaymentMethods: make(map[string]map[string]models.PaymentMethodTotal),
 	}
 
 	// Convert Payment slice to PaymentRecord slice for monthly report generation
@@ -391,6 +350,10 @@ func GenerateYearlyReport(payments []models.Payment, year int) models.YearlyRepo
 			AmountUSD:     payment.AmountUSD,
 			ExchangeRate:  payment.ExchangeRate,
 			CreatedAt:     payment.CreatedAt,
+			Kind:          payment.Kind,
+			OriginalID:    payment.OriginalID,
+			GoldGrams:     payment.GoldGrams,
+			ReviewStatus:  payment.ReviewStatus,
 		}
 		paymentRecords = append(paymentRecords, paymentRecord)
 	}
@@ -399,101 +362,35 @@ func GenerateYearlyReport(payments []models.Payment, year int) models.YearlyRepo
 	monthlyReports := GenerateMonthlyReports(paymentRecords)
 	report.MonthlyReports = monthlyReports
 
-	// Initialize location summaries
-	locations := []string{"BANKA HAVALESİ", "CARŞI", "KUYUMCUKENT", "OFİS", "ÇEK"}
-	for _, location := range locations {
-		report.LocationSummary[location] = models.LocationTotal{
-			MKM:   0,
-			MSM:   0,
-			Total: 0,
-		}
-	}
-
 	// Initialize payment methods
 	paymentMethods := []string{"Banka Havalesi", "Nakit", "Çek"}
 	for _, method := range paymentMethods {
-		report.PaymentMethods[method] = models.PaymentMethodTotal{}
-		report.MKMPaymentMethods[method] = models.PaymentMethodTotal{}
-		report.MSMPaymentMethods[method] = models.PaymentMethodTotal{}
+		report.PaymentMethods[method] = models.PaymentMethodTotal{Currencies: map[string]money.Amount{}}
 	}
 
 	// Process each payment
 	for _, payment := range payments {
+		addKindTotal(&report.KindSummary, payment.Kind, payment.AmountUSD)
+		addGoldTotal(report.GoldSummary, payment.Currency, payment.Amount, payment.GoldGrams, payment.AmountUSD)
+
 		// Update project summary
-		if payment.Project == models.ProjectMKM {
-			report.ProjectSummary.MKM += payment.AmountUSD
-		} else if payment.Project == models.ProjectMSM {
-			report.ProjectSummary.MSM += payment.AmountUSD
-		}
+		report.ProjectSummary[payment.Project] += payment.AmountUSD
 
 		// Get payment method
 		paymentMethod := getPaymentMethodFromString(payment.PaymentMethod)
 
 		// Update general payment methods
-		if _, exists := report.PaymentMethods[paymentMethod]; !exists {
-			report.PaymentMethods[paymentMethod] = models.PaymentMethodTotal{}
-		}
-		method := report.PaymentMethods[paymentMethod]
-		if payment.Currency == "TL" {
-			method.TL += payment.Amount
-		} else {
-			method.USD += payment.Amount
-		}
-		method.TotalUSD += payment.AmountUSD
-		report.PaymentMethods[paymentMethod] = method
+		report.PaymentMethods[paymentMethod] = addMethodTotal(report.PaymentMethods[paymentMethod], payment.Currency, payment.Amount, payment.GoldGrams, payment.AmountUSD)
 
 		// Project-specific payment method summary
-		if payment.Project == models.ProjectMKM {
-			if _, exists := report.MKMPaymentMethods[paymentMethod]; !exists {
-				report.MKMPaymentMethods[paymentMethod] = models.PaymentMethodTotal{}
-			}
-			mkmMethod := report.MKMPaymentMethods[paymentMethod]
-			if payment.Currency == "TL" {
-				mkmMethod.TL += payment.Amount
-			} else {
-				mkmMethod.USD += payment.Amount
-			}
-			mkmMethod.TotalUSD += payment.AmountUSD
-			report.MKMPaymentMethods[paymentMethod] = mkmMethod
-		} else if payment.Project == models.ProjectMSM {
-			if _, exists := report.MSMPaymentMethods[paymentMethod]; !exists {
-				report.MSMPaymentMethods[paymentMethod] = models.PaymentMethodTotal{}
-			}
-			msmMethod := report.MSMPaymentMethods[paymentMethod]
-			if payment.Currency == "TL" {
-				msmMethod.TL += payment.Amount
-			} else {
-				msmMethod.USD += payment.Amount
-			}
-			msmMethod.TotalUSD += payment.AmountUSD
-			report.MSMPaymentMethods[paymentMethod] = msmMethod
-		}
+		addProjectMethodTotal(report.ProjectPaymentMethods, payment.Project, paymentMethod, payment.Currency, payment.Amount, payment.GoldGrams, payment.AmountUSD)
 
-		// Location summary based on payment method and account name
-		location := getLocationFromPayment(models.PaymentRecord{
-			ID:            payment.ID,
-			CustomerName:  payment.CustomerName,
-			Amount:        payment.Amount,
-			Currency:      payment.Currency,
-			PaymentMethod: payment.PaymentMethod,
-			PaymentDate:   payment.PaymentDate,
-			AccountName:   payment.AccountName,
-			Project:       payment.Project,
-			Location:      payment.Location,
-			AmountUSD:     payment.AmountUSD,
-			ExchangeRate:  payment.ExchangeRate,
-			CreatedAt:     payment.CreatedAt,
-		})
-		if loc, exists := report.LocationSummary[location]; exists {
-			if payment.Project == models.ProjectMKM {
-				loc.MKM += payment.AmountUSD
-			} else if payment.Project == models.ProjectMSM {
-				loc.MSM += payment.AmountUSD
-			}
-			loc.Total += payment.AmountUSD
-			report.LocationSummary[location] = loc
-		}
+		// Location summary by the stored location
+		report.LocationSummary[payment.Location] = addLocationTotal(report.LocationSummary[payment.Location], payment.Project, payment.AmountUSD)
+
+		addReviewTotal(&report.Unconfirmed, payment.ReviewStatus, payment.AmountUSD)
 	}
+	setReviewShare(&report.Unconfirmed, report.KindSummary.Net)
 
 	return report
 }
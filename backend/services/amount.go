@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"tahsilat-raporu/money"
+)
+
+// Amount locales, used only to settle values like "1.234" that are valid in both
+const (
+	AmountLocaleAuto = ""   // reject ambiguous values
+	AmountLocaleTR   = "tr" // "." thousands, "," decimal: 1.234,56
+	AmountLocaleEN   = "en" // "," thousands, "." decimal: 1,234.56
+)
+
+// ErrAmbiguousAmount is returned for a value such as "1.234" or "1,234" that
+// is a valid amount with either separator convention
+var ErrAmbiguousAmount = errors.New("ambiguous amount")
+
+// amountCurrencyMarks are stripped from either end of an amount, longest first
+var amountCurrencyMarks = []string{"TRY", "USD", "EUR", "GBP", "CHF", "TL", "₺", "$", "€", "£"}
+
+// ValidateAmountLocale checks an amount locale query parameter
+func ValidateAmountLocale(locale string) error {
+	switch locale {
+	case AmountLocaleAuto, AmountLocaleTR, AmountLocaleEN:
+		return nil
+	}
+	return fmt.Errorf("invalid amount locale '%s' (valid: tr, en)", locale)
+}
+
+// ParseAmount parses an amount written in Turkish ("1.234,56 TL") or English
+// ("$1,234.56") notation. Currency marks before or after the number, a leading
+// minus and accounting-style parentheses "(250,00)" are accepted. The decimal
+// separator is detected from the value; locale only decides values that are
+// valid both ways, which are rejected with ErrAmbiguousAmount if it is empty.
+func ParseAmount(value string, locale string) (money.Amount, error) {
+	original := value
+	value = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\u00a0' || r == '\u202f' {
+			return -1
+		}
+		return r
+	}, value)
+	if value == "" {
+		return 0, fmt.Errorf("amount is empty")
+	}
+
+	negative, err := stripAmountDecorations(&value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount '%s': %v", original, err)
+	}
+
+	number, err := normalizeAmount(value, locale)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount '%s': %w", original, err)
+	}
+
+	amount, err := money.ParseAmount(number)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount '%s'", original)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// stripAmountDecorations removes currency marks, signs and parentheses from
+// both ends of value and reports whether the amount is negative
+func stripAmountDecorations(value *string) (bool, error) {
+	s := *value
+	negative := false
+	signs := 0
+	for changed := true; changed && s != ""; {
+		changed = false
+		upper := strings.ToUpper(s)
+		for _, mark := range amountCurrencyMarks {
+			if strings.HasPrefix(upper, mark) {
+				s, changed = s[len(mark):], true
+				break
+			}
+			if strings.HasSuffix(upper, mark) {
+				s, changed = s[:len(s)-len(mark)], true
+				break
+			}
+		}
+		if changed {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")"):
+			s, negative, changed = s[1:len(s)-1], true, true
+			signs++
+		case strings.HasPrefix(s, "-"):
+			s, negative, changed = s[1:], true, true
+			signs++
+		case strings.HasPrefix(s, "+"):
+			s, changed = s[1:], true
+			signs++
+		}
+	}
+
+	if signs > 1 {
+		return false, fmt.Errorf("more than one sign")
+	}
+	*value = s
+	return negative, nil
+}
+
+// normalizeAmount converts the digits and separators of an amount to the
+// "1234.56" form understood by money.ParseAmount
+func normalizeAmount(value, locale string) (string, error) {
+	if value == "" || strings.Trim(value, "0123456789.,") != "" || strings.Trim(value, ".,") == "" {
+		return "", fmt.Errorf("not a number")
+	}
+
+	dots := strings.Count(value, ".")
+	commas := strings.Count(value, ",")
+
+	// With both separators the last one is the decimal separator
+	if dots > 0 && commas > 0 {
+		decimal, thousands := ",", "."
+		if strings.LastIndex(value, ".") > strings.LastIndex(value, ",") {
+			decimal, thousands = ".", ","
+		}
+		if strings.Count(value, decimal) > 1 {
+			return "", fmt.Errorf("decimal separator '%s' appears more than once", decimal)
+		}
+		i := strings.LastIndex(value, decimal)
+		integer, fraction := value[:i], value[i+1:]
+		if !validThousands(integer, thousands) {
+			return "", fmt.Errorf("thousands separator '%s' is not in groups of three", thousands)
+		}
+		return strings.ReplaceAll(integer, thousands, "") + "." + fraction, nil
+	}
+
+	if dots == 0 && commas == 0 {
+		return value, nil
+	}
+
+	separator := "."
+	if commas > 0 {
+		separator = ","
+	}
+
+	// A repeated separator can only be the thousands separator
+	if dots > 1 || commas > 1 {
+		if !validThousands(value, separator) {
+			return "", fmt.Errorf("separator '%s' is not in groups of three", separator)
+		}
+		return strings.ReplaceAll(value, separator, ""), nil
+	}
+
+	i := strings.Index(value, separator)
+	integer, fraction := value[:i], value[i+1:]
+	if len(fraction) != 3 || integer == "" || integer == "0" || len(integer) > 3 {
+		return integer + "." + fraction, nil
+	}
+
+	// One separator followed by exactly three digits: 1.234 or 1,234
+	thousands := (locale == AmountLocaleTR && separator == ".") || (locale == AmountLocaleEN && separator == ",")
+	decimal := (locale == AmountLocaleTR && separator == ",") || (locale == AmountLocaleEN && separator == ".")
+	switch {
+	case thousands:
+		return integer + fraction, nil
+	case decimal:
+		return integer + "." + fraction, nil
+	}
+	return "", fmt.Errorf("%w: '%s' could be a thousands or a decimal separator (%s%s or %s.%s), set the amount locale",
+		ErrAmbiguousAmount, separator, integer, fraction, integer, fraction)
+}
+
+// validThousands reports whether integer is correctly grouped by separator, e.g. 1.234.567
+func validThousands(integer, separator string) bool {
+	groups := strings.Split(integer, separator)
+	if len(groups[0]) < 1 || len(groups[0]) > 3 {
+		return false
+	}
+	for _, group := range groups[1:] {
+		if len(group) != 3 {
+			return false
+		}
+	}
+	return true
+}
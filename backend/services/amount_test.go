@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"tahsilat-raporu/money"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value  string
+		locale string
+		want   money.Amount
+	}{
+		{"1234", AmountLocaleAuto, 123400},
+		{"1234,56", AmountLocaleAuto, 123456},
+		{"1234.56", AmountLocaleAuto, 123456},
+		{"1.234,56", AmountLocaleAuto, 123456},
+		{"1,234.56", AmountLocaleAuto, 123456},
+		{"1.234.567", AmountLocaleAuto, 123456700},
+		{"1,234,567", AmountLocaleAuto, 123456700},
+		{"1.234.567,8", AmountLocaleAuto, 123456780},
+		{"12,5", AmountLocaleAuto, 1250},
+		{"0,125", AmountLocaleAuto, 13},
+		{"1234,567", AmountLocaleAuto, 123457},
+		{",5", AmountLocaleAuto, 50},
+		{"1.234,56 TL", AmountLocaleAuto, 123456},
+		{"₺1.234,56", AmountLocaleAuto, 123456},
+		{"$1,234.56", AmountLocaleAuto, 123456},
+		{"1 234,56 €", AmountLocaleAuto, 123456},
+		{"1 234,56", AmountLocaleAuto, 123456},
+		{"usd 250", AmountLocaleAuto, 25000},
+		{"-250,00", AmountLocaleAuto, -25000},
+		{"(250,00)", AmountLocaleAuto, -25000},
+		{"-$1,234.56", AmountLocaleAuto, -123456},
+		{"+250", AmountLocaleAuto, 25000},
+		{"1.234", AmountLocaleTR, 123400},
+		{"1,234", AmountLocaleEN, 123400},
+		{"1.234,5", AmountLocaleEN, 123450},
+		{"1,234.5", AmountLocaleTR, 123450},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.value, tt.locale)
+		if err != nil {
+			t.Errorf("ParseAmount(%q, %q) returned error: %v", tt.value, tt.locale, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAmount(%q, %q) = %s, want %s", tt.value, tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestParseAmountSeparatorLocale(t *testing.T) {
+	// One separator followed by exactly three digits is the only case the
+	// locale decides
+	tests := []struct {
+		value string
+		tr    money.Amount
+		en    money.Amount
+	}{
+		{"1.234", 123400, 123},
+		{"1,234", 123, 123400},
+		{"999.999", 99999900, 100000},
+		{"-5,250", -525, -525000},
+	}
+	for _, tt := range tests {
+		if _, err := ParseAmount(tt.value, AmountLocaleAuto); !errors.Is(err, ErrAmbiguousAmount) {
+			t.Errorf("ParseAmount(%q) without locale error = %v, want ErrAmbiguousAmount", tt.value, err)
+		}
+		if got, err := ParseAmount(tt.value, AmountLocaleTR); err != nil || got != tt.tr {
+			t.Errorf("ParseAmount(%q, tr) = %s, %v, want %s", tt.value, got, err, tt.tr)
+		}
+		if got, err := ParseAmount(tt.value, AmountLocaleEN); err != nil || got != tt.en {
+			t.Errorf("ParseAmount(%q, en) = %s, %v, want %s", tt.value, got, err, tt.en)
+		}
+	}
+}
+
+func TestParseAmountInvalid(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"  ",
+		"TL",
+		"abc",
+		"12a",
+		".",
+		"1.234.56",
+		"1,23,456",
+		"1.234,567,8",
+		"12.34.567,8",
+		"--5",
+		"-(5)",
+		"5-",
+	} {
+		if got, err := ParseAmount(value, AmountLocaleAuto); err == nil {
+			t.Errorf("ParseAmount(%q) = %s, want an error", value, got)
+		}
+	}
+}
+
+func TestValidateAmountLocale(t *testing.T) {
+	for _, locale := range []string{"", "tr", "en"} {
+		if err := ValidateAmountLocale(locale); err != nil {
+			t.Errorf("ValidateAmountLocale(%q) returned error: %v", locale, err)
+		}
+	}
+	for _, locale := range []string{"TR", "de", "tr-TR"} {
+		if err := ValidateAmountLocale(locale); err == nil {
+			t.Errorf("ValidateAmountLocale(%q) accepted an invalid locale", locale)
+		}
+	}
+}
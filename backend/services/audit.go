@@ -0,0 +1,109 @@
+package services
+
+import (
+	"fmt"
+	"tahsilat-raporu/dateparse"
+	"tahsilat-raporu/models"
+)
+
+// ExplainClassification lists, for each derived payment field, the raw input it
+// came from, the stored value and the value the current rules would produce.
+// The date is parsed with the order and date system used at upload time,
+// and the classified fields with the processor's rules.
+func ExplainClassification(processor *PaymentProcessor, payment models.PaymentRecord, rawData models.PaymentRawData) []models.ClassificationDecision {
+	raw := rawData.Original
+	currentMethod, methodRule := processor.Classify(models.RuleFieldPaymentMethod, raw)
+	currentLocation, locationRule := processor.Classify(models.RuleFieldLocation, raw)
+	currentProject, projectRule := processor.Classify(models.RuleFieldProject, raw)
+
+	currentDate := ""
+	dateSystem := rawData.DateSystem
+	if dateSystem == "" {
+		dateSystem = raw.DateSystem
+	}
+	dateFormat := dateparse.InferColumn([]string{raw.Tarih}, dateparse.Options{Order: rawData.DateOrder, DateSystem: dateSystem})
+	if date, err := dateFormat.Parse(raw.Tarih); err == nil {
+		currentDate = date.Format("2006-01-02")
+	} else {
+		currentDate = "error: " + err.Error()
+	}
+
+	currentKind, err := ClassifyPaymentKind(raw)
+	if err != nil {
+		currentKind = "error: " + err.Error()
+	}
+
+	decisions := []models.ClassificationDecision{
+		{
+			Field:   "payment_date",
+			Input:   map[string]string{models.FieldTarih: raw.Tarih},
+			Stored:  payment.PaymentDate.Format("2006-01-02"),
+			Current: currentDate,
+		},
+		{
+			Field:   "payment_method",
+			Input:   map[string]string{models.FieldTahsilatSekli: raw.TahsilatSekli, models.FieldHesapAdi: raw.HesapAdi},
+			Stored:  payment.PaymentMethod,
+			Current: currentMethod,
+			Rule:    methodRule,
+		},
+		{
+			Field:   "location",
+			Input:   map[string]string{models.FieldHesapAdi: raw.HesapAdi, models.RuleFieldPaymentMethod: currentMethod},
+			Stored:  payment.Location,
+			Current: currentLocation,
+			Rule:    locationRule,
+		},
+		{
+			Field:   "project",
+			Input:   map[string]string{models.FieldProjeAdi: raw.ProjeAdi},
+			Stored:  payment.Project,
+			Current: currentProject,
+			Rule:    projectRule,
+		},
+		{
+			Field:   "kind",
+			Input:   map[string]string{models.FieldIslemTuru: raw.IslemTuru, models.FieldOdenenTutar: amountInput(raw)},
+			Stored:  payment.Kind,
+			Current: currentKind,
+		},
+		{
+			Field:   "currency",
+			Input:   map[string]string{models.FieldOdenenDoviz: raw.OdenenDoviz},
+			Stored:  payment.Currency,
+			Current: processor.currencyCode(raw.OdenenDoviz),
+		},
+		{
+			// Rates are not looked up again, so there is no current value
+			Field: "amount_usd",
+			Input: map[string]string{
+				models.FieldOdenenTutar: amountInput(raw),
+				"parsed_amount":         fmt.Sprintf("%.2f", raw.OdenenTutar),
+				models.FieldOdenenDoviz: raw.OdenenDoviz,
+				"exchange_rate":         fmt.Sprintf("%.4f", payment.ExchangeRate),
+			},
+			Stored: fmt.Sprintf("%.2f", payment.AmountUSD),
+		},
+	}
+
+	for i := range decisions {
+		// Show the column a rule read if it is not already listed
+		if rule := decisions[i].Rule; rule != nil {
+			source, ok := ruleSources[rule.Source]
+			if _, listed := decisions[i].Input[rule.Source]; ok && !listed {
+				decisions[i].Input[rule.Source] = source(raw)
+			}
+		}
+		decisions[i].Changed = decisions[i].Current != "" && decisions[i].Current != decisions[i].Stored
+	}
+	return decisions
+}
+
+// amountInput is the Ödenen Tutar of a row as the user typed it, or the parsed
+// amount for rows uploaded as numbers
+func amountInput(raw models.RawPaymentData) string {
+	if raw.OdenenTutarText != "" {
+		return raw.OdenenTutarText
+	}
+	return raw.OdenenTutar.String()
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+
+	"tahsilat-raporu/models"
+)
+
+func TestExplainClassificationCurrency(t *testing.T) {
+	tests := []struct {
+		input  string
+		stored string
+	}{
+		{"TL", "TL"},
+		{" try ", "TL"},
+		{"₺", "TL"},
+		{"$", "USD"},
+		{"eur", "EUR"},
+		{"Gram Altın", "XAU"},
+		{"çeyrek", "CEYREK"},
+		{"ZİYNET", "TAM"},
+	}
+	processor := NewPaymentProcessor()
+	for _, tt := range tests {
+		raw := models.RawPaymentData{Tarih: "15/01/2025", OdenenDoviz: tt.input}
+		payment := models.PaymentRecord{Currency: tt.stored}
+		for _, decision := range ExplainClassification(processor, payment, models.PaymentRawData{Original: raw}) {
+			if decision.Field != "currency" {
+				continue
+			}
+			if decision.Current != tt.stored || decision.Changed {
+				t.Errorf("currency of %q: current %q, changed %v; want %q, unchanged", tt.input, decision.Current, decision.Changed, tt.stored)
+			}
+		}
+	}
+}
@@ -1,115 +1,173 @@
 package services
 
 import (
+	"fmt"
+	"regexp"
+	"sort"
 	"strings"
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/money"
 )
 
-// PaymentMethodClassifier classifies payment methods based on raw data
-type PaymentMethodClassifier struct{}
-
-// Classify determines payment method ONLY from tahsilat sekli column
-func (p *PaymentMethodClassifier) Classify(tahsilatSekli string, hesapAdi string) string {
-	// Payment method should ONLY be determined by Tahsilat Şekli column
-	// Hesap Adı should NOT affect payment method classification
-	
-	tahsilatLower := strings.ToLower(strings.TrimSpace(tahsilatSekli))
-
-	// Check for Çek (Check)
-	if strings.Contains(tahsilatLower, "çek") {
-		return "Çek"
-	}
+// ClassificationFallbacks are the values a field gets when none of its rules match
+var ClassificationFallbacks = map[string]string{
+	models.RuleFieldPaymentMethod: "Nakit",
+	models.RuleFieldLocation:      "DIGER",
+	models.RuleFieldProject:       "UNKNOWN",
+}
 
-	// Check for Bank Transfer/Wire Transfer
-	if strings.Contains(tahsilatLower, "banka havalesi") ||
-		strings.Contains(tahsilatLower, "havale") {
-		return "Banka Havalesi"
-	}
+// ruleSources are the input fields a classification rule can read
+var ruleSources = map[string]func(models.RawPaymentData) string{
+	models.FieldTahsilatSekli:    func(raw models.RawPaymentData) string { return raw.TahsilatSekli },
+	models.FieldHesapAdi:         func(raw models.RawPaymentData) string { return raw.HesapAdi },
+	models.FieldProjeAdi:         func(raw models.RawPaymentData) string { return raw.ProjeAdi },
+	models.FieldMusteriAdiSoyadi: func(raw models.RawPaymentData) string { return raw.MusteriAdiSoyadi },
+	models.FieldOdenenDoviz:      func(raw models.RawPaymentData) string { return raw.OdenenDoviz },
+	models.FieldIslemTuru:        func(raw models.RawPaymentData) string { return raw.IslemTuru },
+}
 
-	// Check for Cash payments
-	if strings.Contains(tahsilatLower, "nakit") ||
-		strings.Contains(tahsilatLower, "kasa") {
-		return "Nakit"
+// DefaultClassificationRules returns the rules used until others are saved:
+// payment method from Tahsilat Şekli. Locations and projects are matched by the
+// patterns of the location and project tables.
+func DefaultClassificationRules() []models.ClassificationRule {
+	rule := func(field, source, pattern, value string, priority int) models.ClassificationRule {
+		return models.ClassificationRule{Field: field, Source: source, MatchType: models.RuleMatchContains,
+			Pattern: pattern, Value: value, Priority: priority, Enabled: true}
 	}
-
-	// Handle common payment terms
-	if strings.Contains(tahsilatLower, "vadeli") {
-		// Vadeli payments are typically bank transfers in this context
-		return "Banka Havalesi"
+	method, sekli := models.RuleFieldPaymentMethod, models.FieldTahsilatSekli
+	return []models.ClassificationRule{
+		rule(method, sekli, "çek", "Çek", 10),
+		rule(method, sekli, "havale", "Banka Havalesi", 20),
+		rule(method, sekli, "nakit", "Nakit", 30),
+		rule(method, sekli, "kasa", "Nakit", 40),
+		rule(method, sekli, "vadeli", "Banka Havalesi", 50), // Vadeli payments are bank transfers here
 	}
-
-	// Default fallback for unknown payment types
-	return "Nakit"
 }
 
-// LocationClassifier classifies locations based on account name
-type LocationClassifier struct{}
-
-// Classify determines location from hesap adi
-func (l *LocationClassifier) Classify(hesapAdi string) string {
-	hesapLower := strings.ToLower(hesapAdi)
-
-	// ÇARŞI - Shopping area accounts
-	if strings.Contains(hesapLower, "çarşi") ||
-		strings.Contains(hesapLower, "carsi") {
-		return "ÇARŞI"
+// ValidateClassificationRule checks that a rule names a known field, source and
+// match type, has a value, and that a regex pattern compiles
+func ValidateClassificationRule(rule models.ClassificationRule) error {
+	if _, ok := ClassificationFallbacks[rule.Field]; !ok {
+		return fmt.Errorf("invalid field '%s' (valid: payment_method, location, project)", rule.Field)
 	}
-
-	// KUYUMCUKENT - Jewelry district
-	if strings.Contains(hesapLower, "kuyumcukent") {
-		return "KUYUMCUKENT"
+	if _, ok := ruleSources[rule.Source]; !ok {
+		sources := make([]string, 0, len(ruleSources))
+		for source := range ruleSources {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+		return fmt.Errorf("invalid source '%s' (valid: %s)", rule.Source, strings.Join(sources, ", "))
 	}
-
-	// OFİS - Office
-	if strings.Contains(hesapLower, "ofis") ||
-		strings.Contains(hesapLower, "office") {
-		return "OFİS"
+	switch rule.MatchType {
+	case models.RuleMatchContains, models.RuleMatchEquals:
+		if foldText(rule.Pattern) == "" {
+			return fmt.Errorf("pattern must not be empty")
+		}
+	case models.RuleMatchRegex:
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid regex '%s': %v", rule.Pattern, err)
+		}
+	default:
+		return fmt.Errorf("invalid match_type '%s' (valid: contains, equals, regex)", rule.MatchType)
 	}
-
-	// BANKA - Bank transfers (if not categorized above)
-	if strings.Contains(hesapLower, "yapi kredi") ||
-		strings.Contains(hesapLower, "banka") {
-		return "BANKA HAVALESİ"
+	if strings.TrimSpace(rule.Value) == "" {
+		return fmt.Errorf("value must not be empty")
 	}
+	return nil
+}
 
-	return "DİĞER"
+// RuleSet evaluates classification rules
+type RuleSet struct {
+	rules           map[string][]compiledRule            // By field, in evaluation order
+	methodLocations map[string]models.ClassificationRule // Payment method -> location override
 }
 
-// ProjectClassifier classifies projects based on project name
-type ProjectClassifier struct{}
+// compiledRule is an enabled rule with its pattern prepared for matching
+type compiledRule struct {
+	rule    models.ClassificationRule
+	pattern string
+	regex   *regexp.Regexp
+}
 
-// Classify determines project from proje adi
-func (p *ProjectClassifier) Classify(projeAdi string) string {
-	projeAdi = strings.TrimSpace(strings.ToUpper(projeAdi))
+// NewRuleSet prepares the enabled rules for evaluation, ordered by priority
+// and then by ID. Project and location rules are tried before the patterns of
+// the projects and locations; the payment method overrides of the locations
+// come before any location rule.
+func NewRuleSet(rules []models.ClassificationRule, projects []models.Project, locations []models.Location) (*RuleSet, error) {
+	sorted := append([]models.ClassificationRule{}, rules...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority < sorted[j].Priority
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	set := &RuleSet{rules: make(map[string][]compiledRule), methodLocations: methodLocations(locations)}
+	sorted = append(sorted, projectRules(projects)...)
+	for _, rule := range append(sorted, locationRules(locations)...) {
+		if !rule.Enabled {
+			continue
+		}
+		if err := ValidateClassificationRule(rule); err != nil {
+			return nil, fmt.Errorf("rule %d: %v", rule.ID, err)
+		}
+		compiled := compiledRule{rule: rule, pattern: foldText(rule.Pattern)}
+		if rule.MatchType == models.RuleMatchRegex {
+			compiled.regex = regexp.MustCompile(rule.Pattern)
+		}
+		set.rules[rule.Field] = append(set.rules[rule.Field], compiled)
+	}
+	return set, nil
+}
 
-	// Model Kuyum Merkezi (MKM)
-	if strings.Contains(projeAdi, "MODEL KUYUM") ||
-		strings.Contains(projeAdi, "KUYUM MERKEZİ") ||
-		strings.Contains(projeAdi, "MKM") {
-		return "MKM"
+// Classify returns the value of field for an input row and the rule that
+// decided it, or the field's fallback value and nil if no rule matched
+func (s *RuleSet) Classify(field string, raw models.RawPaymentData) (string, *models.ClassificationRule) {
+	if field == models.RuleFieldLocation {
+		method, _ := s.Classify(models.RuleFieldPaymentMethod, raw)
+		if rule, ok := s.methodLocations[method]; ok {
+			return rule.Value, &rule
+		}
+	}
+	for _, compiled := range s.rules[field] {
+		if compiled.matches(ruleSources[compiled.rule.Source](raw)) {
+			rule := compiled.rule
+			return rule.Value, &rule
+		}
 	}
+	return ClassificationFallbacks[field], nil
+}
 
-	// Model Sanayi Merkezi (MSM)
-	if strings.Contains(projeAdi, "MODEL SANAYI") ||
-		strings.Contains(projeAdi, "SANAYI MERKEZİ") ||
-		strings.Contains(projeAdi, "MSM") ||
-		strings.Contains(projeAdi, "3. ETAP") {
-		return "MSM"
+// matches reports whether a source value matches the rule
+func (c compiledRule) matches(value string) bool {
+	switch c.rule.MatchType {
+	case models.RuleMatchRegex:
+		return c.regex.MatchString(strings.TrimSpace(value))
+	case models.RuleMatchEquals:
+		return foldText(value) == c.pattern
+	default:
+		return strings.Contains(foldText(value), c.pattern)
 	}
+}
 
-	return "UNKNOWN"
+// foldText is the comparison key of contains and equals rules: lower case with
+// Turkish rules, whitespace collapsed and Turkish letters folded to ASCII, so
+// "ÇARŞI", "Çarşı" and "carsi" compare equal
+func foldText(value string) string {
+	return turkishFold.Replace(normalizeText(value))
 }
 
 // ProcessedPayment represents a fully processed payment record
 type ProcessedPayment struct {
 	CustomerName  string
 	PaymentDate   string
-	Amount        float64
+	Amount        money.Amount
 	Currency      string
 	PaymentMethod string // Nakit, Banka Havalesi, Çek
-	Location      string // ÇARŞI, KUYUMCUKENT, OFİS, BANKA HAVALESİ
+	Location      string // Location code, e.g. CARSI
 	Project       string // MKM, MSM
 	AccountName   string
-	AmountUSD     float64
-	ExchangeRate  float64
+	AmountUSD     money.Amount
+	ExchangeRate  money.Rate
 	OriginalData  interface{} // Keep reference to original data for debugging
 }
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+
+	"tahsilat-raporu/models"
+)
+
+func TestRuleSetClassify(t *testing.T) {
+	rule := func(id int64, field, source, matchType, pattern, value string, priority int) models.ClassificationRule {
+		return models.ClassificationRule{ID: id, Field: field, Source: source, MatchType: matchType,
+			Pattern: pattern, Value: value, Priority: priority, Enabled: true}
+	}
+	method, location, project := models.RuleFieldPaymentMethod, models.RuleFieldLocation, models.RuleFieldProject
+	disabled := rule(9, method, models.FieldTahsilatSekli, models.RuleMatchContains, "eft", models.PaymentMethodCheck, 1)
+	disabled.Enabled = false
+	rules := append(DefaultClassificationRules(),
+		disabled,
+		rule(7, method, models.FieldTahsilatSekli, models.RuleMatchContains, "eft", models.PaymentMethodTransfer, 15),
+		rule(3, method, models.FieldHesapAdi, models.RuleMatchEquals, "pos", models.PaymentMethodTransfer, 5),
+		rule(2, method, models.FieldHesapAdi, models.RuleMatchEquals, "pos", models.PaymentMethodCash, 5),
+		rule(4, location, models.FieldHesapAdi, models.RuleMatchRegex, `^Şube \d+$`, "CARSI", 10),
+		rule(5, project, models.FieldMusteriAdiSoyadi, models.RuleMatchContains, "sanayi", "MSM", 10),
+	)
+	set, err := NewRuleSet(rules, DefaultProjects(), DefaultLocations())
+	if err != nil {
+		t.Fatalf("NewRuleSet returned error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		field  string
+		raw    models.RawPaymentData
+		want   string
+		ruleID int64 // -1 if the fallback decides
+	}{
+		{"default rule", method, models.RawPaymentData{TahsilatSekli: "Banka Havalesi"}, models.PaymentMethodTransfer, 0},
+		{"turkish letters folded", method, models.RawPaymentData{TahsilatSekli: "ÇEK"}, models.PaymentMethodCheck, 0},
+		{"priority before defaults", method, models.RawPaymentData{TahsilatSekli: "EFT"}, models.PaymentMethodTransfer, 7},
+		{"equal priority by ID", method, models.RawPaymentData{TahsilatSekli: "Nakit", HesapAdi: " POS "}, models.PaymentMethodCash, 2},
+		{"method fallback", method, models.RawPaymentData{TahsilatSekli: "Virman"}, models.PaymentMethodCash, -1},
+		{"regex rule", location, models.RawPaymentData{TahsilatSekli: "Nakit", HesapAdi: "Şube 12"}, "CARSI", 4},
+		{"regex must match whole value", location, models.RawPaymentData{TahsilatSekli: "Nakit", HesapAdi: "Şube 12 Kasa"}, "OFIS", 0},
+		{"location pattern", location, models.RawPaymentData{TahsilatSekli: "Nakit", HesapAdi: "KUYUMCUKENT KASA"}, "KUYUMCUKENT", 0},
+		{"method override before location rules", location, models.RawPaymentData{TahsilatSekli: "Çek", HesapAdi: "Şube 12"}, "CEK", 0},
+		{"location fallback", location, models.RawPaymentData{TahsilatSekli: "Nakit", HesapAdi: "Merkez"}, "DIGER", -1},
+		{"project rule before project patterns", project, models.RawPaymentData{ProjeAdi: "MKM", MusteriAdiSoyadi: "Sanayi AŞ"}, "MSM", 5},
+		{"project pattern", project, models.RawPaymentData{ProjeAdi: "Model Kuyum Merkezi"}, "MKM", 0},
+		{"project fallback", project, models.RawPaymentData{ProjeAdi: "Başka"}, "UNKNOWN", -1},
+	}
+	for _, tt := range tests {
+		value, matched := set.Classify(tt.field, tt.raw)
+		if value != tt.want {
+			t.Errorf("%s: Classify(%s) = %q, want %q", tt.name, tt.field, value, tt.want)
+		}
+		switch {
+		case tt.ruleID == -1 && matched != nil:
+			t.Errorf("%s: rule %+v matched, want the fallback", tt.name, *matched)
+		case tt.ruleID != -1 && matched == nil:
+			t.Errorf("%s: no rule matched", tt.name)
+		case tt.ruleID != -1 && matched.ID != tt.ruleID:
+			t.Errorf("%s: rule %d matched, want rule %d", tt.name, matched.ID, tt.ruleID)
+		}
+	}
+}
+
+func TestNewRuleSetInvalidRule(t *testing.T) {
+	tests := []models.ClassificationRule{
+		{ID: 1, Field: "customer", Source: models.FieldHesapAdi, MatchType: models.RuleMatchContains, Pattern: "a", Value: "b", Enabled: true},
+		{ID: 2, Field: models.RuleFieldLocation, Source: "tutar", MatchType: models.RuleMatchContains, Pattern: "a", Value: "OFIS", Enabled: true},
+		{ID: 3, Field: models.RuleFieldLocation, Source: models.FieldHesapAdi, MatchType: models.RuleMatchRegex, Pattern: "(", Value: "OFIS", Enabled: true},
+		{ID: 4, Field: models.RuleFieldLocation, Source: models.FieldHesapAdi, MatchType: models.RuleMatchContains, Pattern: " ", Value: "OFIS", Enabled: true},
+		{ID: 5, Field: models.RuleFieldLocation, Source: models.FieldHesapAdi, MatchType: models.RuleMatchContains, Pattern: "a", Value: " ", Enabled: true},
+	}
+	for _, rule := range tests {
+		if _, err := NewRuleSet([]models.ClassificationRule{rule}, nil, nil); err == nil {
+			t.Errorf("NewRuleSet accepted invalid rule %+v", rule)
+		}
+	}
+
+	// A disabled rule is not evaluated, so it is not validated either
+	rule := tests[0]
+	rule.Enabled = false
+	if _, err := NewRuleSet([]models.ClassificationRule{rule}, nil, nil); err != nil {
+		t.Errorf("NewRuleSet rejected disabled rule: %v", err)
+	}
+}
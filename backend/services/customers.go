@@ -0,0 +1,106 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"tahsilat-raporu/models"
+	"unicode"
+)
+
+// CustomerMatchThreshold is the similarity from which an existing customer is
+// suggested for a new customer name
+const CustomerMatchThreshold = 0.8
+
+// turkishFold maps Turkish letters to their ASCII look-alikes for fuzzy matching
+var turkishFold = strings.NewReplacer("ç", "c", "ğ", "g", "ı", "i", "ö", "o", "ş", "s", "ü", "u", "â", "a", "î", "i", "û", "u")
+
+// NormalizeCustomerName returns the key customers are matched on: lower case
+// with Turkish rules (I -> ı, İ -> i), punctuation removed and whitespace
+// collapsed, so "AHMET YILMAZ" and "Ahmet Yılmaz " are the same customer
+func NormalizeCustomerName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '&' {
+			return r
+		}
+		return ' '
+	}, name)
+	return normalizeText(name)
+}
+
+// CustomerDisplayName tidies an imported customer name for display
+func CustomerDisplayName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// CustomerSimilarity compares two customer names from 0 (unrelated) to 1.
+// Turkish letters are folded to ASCII and word order is ignored, so typos
+// such as "Ahmet Yilmaz" or "Yılmaz Ahmet" still score high.
+func CustomerSimilarity(a, b string) float64 {
+	a, b = foldCustomerName(a), foldCustomerName(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	best := editSimilarity(a, b)
+	if sorted := editSimilarity(sortWords(a), sortWords(b)); sorted > best {
+		best = sorted
+	}
+	return best
+}
+
+// SimilarCustomers returns the customers whose name is at least
+// CustomerMatchThreshold similar to name, most similar first
+func SimilarCustomers(name string, customers []models.Customer) []models.Customer {
+	var matches []models.Customer
+	for _, customer := range customers {
+		similarity := CustomerSimilarity(name, customer.Name)
+		if similarity >= CustomerMatchThreshold {
+			customer.Similarity = math.Round(similarity*100) / 100
+			matches = append(matches, customer)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Similarity > matches[j].Similarity })
+	return matches
+}
+
+// foldCustomerName normalizes a name and folds Turkish letters to ASCII
+func foldCustomerName(name string) string {
+	return turkishFold.Replace(NormalizeCustomerName(name))
+}
+
+// sortWords returns the words of s in alphabetical order
+func sortWords(s string) string {
+	words := strings.Fields(s)
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// editSimilarity is 1 minus the Levenshtein distance relative to the longer string
+func editSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(rb)])/float64(longest)
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+
+	"tahsilat-raporu/models"
+)
+
+func TestNormalizeCustomerName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Ahmet Yılmaz", "ahmet yılmaz"},
+		{"AHMET YILMAZ", "ahmet yılmaz"},
+		{"  Ahmet   Yılmaz ", "ahmet yılmaz"},
+		{"İSMAİL ÖZTÜRK", "ismail öztürk"},
+		{"Yılmaz, Ahmet", "yılmaz ahmet"},
+		{"ABC Ltd. Şti.", "abc ltd şti"},
+		{"Demir & Oğulları", "demir & oğulları"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeCustomerName(tt.name); got != tt.want {
+			t.Errorf("NormalizeCustomerName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCustomerSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		match bool // at least CustomerMatchThreshold
+	}{
+		{"Ahmet Yılmaz", "AHMET YILMAZ", true},
+		{"Ahmet Yılmaz", "Ahmet Yilmaz", true},
+		{"Ahmet Yılmaz", "Yılmaz Ahmet", true},
+		{"Ahmet Yılmaz", "Ahmet Yılmazz", true},
+		{"Ahmet Yılmaz", "Ahmed Yılmaz", true},
+		{"Ahmet Yılmaz", "Mehmet Demir", false},
+		{"Ahmet Yılmaz", "Ahmet Kaya", false},
+		{"Ahmet Yılmaz", "", false},
+	}
+	for _, tt := range tests {
+		got := CustomerSimilarity(tt.a, tt.b)
+		if got < 0 || got > 1 {
+			t.Errorf("CustomerSimilarity(%q, %q) = %.2f, want a value from 0 to 1", tt.a, tt.b, got)
+		}
+		if (got >= CustomerMatchThreshold) != tt.match {
+			t.Errorf("CustomerSimilarity(%q, %q) = %.2f, want match %v", tt.a, tt.b, got, tt.match)
+		}
+		if back := CustomerSimilarity(tt.b, tt.a); back != got {
+			t.Errorf("CustomerSimilarity(%q, %q) = %.2f, but %.2f the other way round", tt.a, tt.b, got, back)
+		}
+	}
+	if got := CustomerSimilarity("Ahmet Yılmaz", "ahmet yilmaz"); got != 1 {
+		t.Errorf("CustomerSimilarity of case and letter variants = %.2f, want 1", got)
+	}
+}
+
+func TestSimilarCustomers(t *testing.T) {
+	customers := []models.Customer{
+		{ID: 1, Name: "Mehmet Demir"},
+		{ID: 2, Name: "Ahmet Yılmazer"},
+		{ID: 3, Name: "Ahmet Yilmaz"},
+	}
+	matches := SimilarCustomers("Ahmet Yılmaz", customers)
+	if len(matches) != 2 {
+		t.Fatalf("SimilarCustomers returned %d customers, want 2: %+v", len(matches), matches)
+	}
+	if matches[0].ID != 3 || matches[1].ID != 2 {
+		t.Errorf("SimilarCustomers order = %d, %d, want 3, 2", matches[0].ID, matches[1].ID)
+	}
+	if matches[0].Similarity != 1 {
+		t.Errorf("similarity of the closest match = %.2f, want 1", matches[0].Similarity)
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"tahsilat-raporu/models"
+	"time"
+)
+
+// DefaultDatePolicy returns the policy used until one is saved in the settings:
+// no dates older than ten years and none more than six months ahead
+func DefaultDatePolicy() models.DatePolicy {
+	return models.DatePolicy{
+		MaxAgeYears:         10,
+		FutureToleranceDays: 183,
+		BlockedPeriods:      []models.BlockedPeriod{},
+	}
+}
+
+// ValidateDatePolicy checks the dates and limits of a policy
+func ValidateDatePolicy(policy models.DatePolicy) error {
+	minDate, err := parsePolicyDate("min_date", policy.MinDate)
+	if err != nil {
+		return err
+	}
+	maxDate, err := parsePolicyDate("max_date", policy.MaxDate)
+	if err != nil {
+		return err
+	}
+	if !minDate.IsZero() && !maxDate.IsZero() && maxDate.Before(minDate) {
+		return fmt.Errorf("max_date %s is before min_date %s", policy.MaxDate, policy.MinDate)
+	}
+	if policy.MaxAgeYears < 0 {
+		return fmt.Errorf("max_age_years cannot be negative")
+	}
+	if policy.FutureToleranceDays < -1 {
+		return fmt.Errorf("future_tolerance_days must be -1 (no limit) or more")
+	}
+
+	for i, period := range policy.BlockedPeriods {
+		start, err := parsePolicyDate(fmt.Sprintf("blocked_periods[%d].start", i), period.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parsePolicyDate(fmt.Sprintf("blocked_periods[%d].end", i), period.End)
+		if err != nil {
+			return err
+		}
+		if start.IsZero() || end.IsZero() {
+			return fmt.Errorf("blocked_periods[%d] needs both start and end", i)
+		}
+		if end.Before(start) {
+			return fmt.Errorf("blocked_periods[%d] ends before it starts", i)
+		}
+	}
+	return nil
+}
+
+// ApplyDatePolicyOverride returns the policy with the per-upload overrides applied
+func ApplyDatePolicyOverride(policy models.DatePolicy, override models.DatePolicyOverride) models.DatePolicy {
+	if override.MinDate != nil {
+		policy.MinDate = *override.MinDate
+	}
+	if override.MaxDate != nil {
+		policy.MaxDate = *override.MaxDate
+	}
+	if override.MaxAgeYears != nil {
+		policy.MaxAgeYears = *override.MaxAgeYears
+	}
+	if override.FutureToleranceDays != nil {
+		policy.FutureToleranceDays = *override.FutureToleranceDays
+	}
+	if override.IgnoreBlockedPeriods {
+		policy.BlockedPeriods = nil
+	}
+	return policy
+}
+
+// CheckPaymentDate returns a row error code and reason if the date is not
+// accepted by the policy, or "" and nil if it is
+func CheckPaymentDate(policy models.DatePolicy, date time.Time, now time.Time) (string, error) {
+	day := date.Format("2006-01-02")
+
+	if policy.MinDate != "" && day < policy.MinDate {
+		return ErrCodeDateOutOfRange, fmt.Errorf("date %s is before the earliest allowed date %s", day, policy.MinDate)
+	}
+	if policy.MaxDate != "" && day > policy.MaxDate {
+		return ErrCodeDateOutOfRange, fmt.Errorf("date %s is after the latest allowed date %s", day, policy.MaxDate)
+	}
+	if policy.FutureToleranceDays >= 0 {
+		cutoff := now.AddDate(0, 0, policy.FutureToleranceDays)
+		if date.After(cutoff) {
+			return ErrCodeFutureDate, fmt.Errorf("date %s is more than %d days in the future (beyond %s)", day, policy.FutureToleranceDays, cutoff.Format("2006-01-02"))
+		}
+	}
+	if policy.MaxAgeYears > 0 {
+		cutoff := now.AddDate(-policy.MaxAgeYears, 0, 0)
+		if date.Before(cutoff) {
+			return ErrCodeOldDate, fmt.Errorf("date %s is more than %d years old (before %s)", day, policy.MaxAgeYears, cutoff.Format("2006-01-02"))
+		}
+	}
+	for _, period := range policy.BlockedPeriods {
+		if day >= period.Start && day <= period.End {
+			reason := fmt.Sprintf("date %s is in the blocked period %s to %s", day, period.Start, period.End)
+			if period.Reason != "" {
+				reason += " (" + period.Reason + ")"
+			}
+			return ErrCodeBlockedPeriod, errors.New(reason)
+		}
+	}
+	return "", nil
+}
+
+// parsePolicyDate parses an optional YYYY-MM-DD policy date
+func parsePolicyDate(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s '%s', use YYYY-MM-DD", name, value)
+	}
+	return date, nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"tahsilat-raporu/models"
+)
+
+// Duplicate handling modes for imports
+const (
+	DuplicateModeSkip  = "skip"  // do not save payments that already exist
+	DuplicateModeFlag  = "flag"  // save them but mark which payment they duplicate
+	DuplicateModeAllow = "allow" // save them unchanged (previous behaviour)
+)
+
+// ParseDuplicateMode validates an import mode, defaulting to skip when empty
+func ParseDuplicateMode(mode string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", DuplicateModeSkip:
+		return DuplicateModeSkip, nil
+	case DuplicateModeFlag:
+		return DuplicateModeFlag, nil
+	case DuplicateModeAllow:
+		return DuplicateModeAllow, nil
+	}
+	return "", fmt.Errorf("invalid duplicate mode '%s' (valid: skip, flag, allow)", mode)
+}
+
+// PaymentFingerprint identifies a payment by customer, date, amount, currency,
+// account and project. Free-text fields are compared case- and
+// whitespace-insensitively so that re-exports of the same file still match.
+func PaymentFingerprint(payment models.PaymentRecord) string {
+	key := strings.Join([]string{
+		normalizeText(payment.CustomerName),
+		payment.PaymentDate.Format("2006-01-02"),
+		fmt.Sprintf("%.2f", payment.Amount),
+		strings.ToUpper(strings.TrimSpace(payment.Currency)),
+		normalizeText(payment.AccountName),
+		strings.ToUpper(strings.TrimSpace(payment.Project)),
+	}, "|")
+
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/money"
+	"time"
+)
+
+// goldPriceMaxAgeDays is how old the latest gold price may be and still value
+// a payment, so that weekends and holidays need no entry of their own
+const goldPriceMaxAgeDays = 7
+
+// ErrNoGoldPrice is returned when the gold price table has no usable price for a date
+var ErrNoGoldPrice = errors.New("no gold price")
+
+// DefaultGoldUnits returns the gold units used until others are saved in the settings
+func DefaultGoldUnits() []models.GoldUnit {
+	return []models.GoldUnit{
+		{Code: "XAU", Name: "Gram Altın (24 Ayar)", Aliases: []string{"GRAM", "GRAM ALTIN", "HAS ALTIN", "24 AYAR"}, Grams: 1, Purity: 0.995},
+		{Code: "GR22", Name: "22 Ayar Gram", Aliases: []string{"22 AYAR", "BİLEZİK"}, Grams: 1, Purity: 0.916},
+		{Code: "CEYREK", Name: "Çeyrek Altın", Aliases: []string{"ÇEYREK", "ÇEYREK ALTIN"}, Grams: 1.75, Purity: 0.916},
+		{Code: "YARIM", Name: "Yarım Altın", Aliases: []string{"YARIM ALTIN"}, Grams: 3.5, Purity: 0.916},
+		{Code: "TAM", Name: "Tam Altın", Aliases: []string{"TAM ALTIN", "ZİYNET"}, Grams: 7, Purity: 0.916},
+		{Code: "ATA", Name: "Ata Lira", Aliases: []string{"ATA ALTIN", "CUMHURİYET"}, Grams: 7.216, Purity: 0.916},
+	}
+}
+
+// ValidateGoldUnits checks that codes and aliases name only one unit, ignoring
+// case and Turkish letters, and do not name TL or a currency TCMB publishes,
+// and that every unit has a weight and a purity between 0 and 1
+func ValidateGoldUnits(units []models.GoldUnit) error {
+	seen := make(map[string]string)
+	for i, unit := range units {
+		if strings.TrimSpace(unit.Code) == "" {
+			return fmt.Errorf("gold unit %d has no code", i+1)
+		}
+		if unit.Grams <= 0 {
+			return fmt.Errorf("gold unit %s: grams must be greater than 0", unit.Code)
+		}
+		if unit.Purity <= 0 || unit.Purity > 1 {
+			return fmt.Errorf("gold unit %s: purity must be between 0 and 1, e.g. 0.916 for 22 ayar", unit.Code)
+		}
+		for _, name := range append([]string{unit.Code}, unit.Aliases...) {
+			// Rows in that currency would otherwise be read as gold
+			if IsPublishedCurrency(name) || IsPublishedCurrency(strings.ToUpper(foldText(name))) {
+				return fmt.Errorf("gold unit %s: '%s' is a currency", unit.Code, name)
+			}
+			key := foldText(name)
+			if other, exists := seen[key]; exists && other != unit.Code {
+				return fmt.Errorf("gold unit %s: '%s' is already used by %s", unit.Code, name, other)
+			}
+			seen[key] = unit.Code
+		}
+	}
+	return nil
+}
+
+// FindGoldUnit returns the unit whose code or one of whose aliases is currency.
+// Names are compared as contains and equals rules compare them, so "ZIYNET",
+// "Ziynet" and "ZİYNET" are the same alias.
+func FindGoldUnit(units []models.GoldUnit, currency string) (models.GoldUnit, bool) {
+	name := foldText(currency)
+	if name == "" {
+		return models.GoldUnit{}, false
+	}
+	for _, unit := range units {
+		if foldText(unit.Code) == name {
+			return unit, true
+		}
+		for _, alias := range unit.Aliases {
+			if foldText(alias) == name {
+				return unit, true
+			}
+		}
+	}
+	return models.GoldUnit{}, false
+}
+
+// ValidateGoldPrice checks the date and price of a gold price table entry
+func ValidateGoldPrice(price models.GoldPrice) error {
+	if _, err := time.Parse("2006-01-02", price.Date); err != nil {
+		return fmt.Errorf("invalid date '%s', use YYYY-MM-DD", price.Date)
+	}
+	if price.USDPerGram <= 0 {
+		return fmt.Errorf("usd_per_gram for %s must be greater than 0", price.Date)
+	}
+	return nil
+}
+
+// FindGoldPrice returns the latest price on or before date. Prices must be
+// sorted by date; a price older than goldPriceMaxAgeDays is not used.
+func FindGoldPrice(prices []models.GoldPrice, date time.Time) (models.GoldPrice, error) {
+	day := date.Format("2006-01-02")
+	i := sort.Search(len(prices), func(i int) bool { return prices[i].Date > day })
+	if i == 0 {
+		return models.GoldPrice{}, fmt.Errorf("%w on or before %s, add it to the gold price table", ErrNoGoldPrice, day)
+	}
+
+	price := prices[i-1]
+	priceDate, err := time.Parse("2006-01-02", price.Date)
+	if err != nil {
+		return models.GoldPrice{}, err
+	}
+	paymentDay, _ := time.Parse("2006-01-02", day)
+	if paymentDay.Sub(priceDate) > goldPriceMaxAgeDays*24*time.Hour {
+		return models.GoldPrice{}, fmt.Errorf("%w for %s, the latest is from %s (more than %d days earlier)",
+			ErrNoGoldPrice, day, price.Date, goldPriceMaxAgeDays)
+	}
+	return price, nil
+}
+
+// GoldUnitRate returns the USD value of one unit at price
+func GoldUnitRate(unit models.GoldUnit, price models.GoldPrice) money.Rate {
+	return money.RateFromFloat(unit.Grams * unit.Purity * price.USDPerGram.Float64())
+}
+
+// GoldFineGrams returns the pure gold content of a quantity of unit
+func GoldFineGrams(unit models.GoldUnit, quantity money.Amount) float64 {
+	return roundGrams(quantity.Float64() * unit.Grams * unit.Purity)
+}
+
+// roundGrams rounds a gold weight to milligrams
+func roundGrams(grams float64) float64 {
+	return math.Round(grams*1000) / 1000
+}
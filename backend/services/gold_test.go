@@ -0,0 +1,81 @@
+package services
+
+import (
+	"testing"
+
+	"tahsilat-raporu/models"
+)
+
+func TestFindGoldUnit(t *testing.T) {
+	units := DefaultGoldUnits()
+	tests := []struct {
+		currency string
+		want     string
+	}{
+		{"XAU", "XAU"},
+		{"xau", "XAU"},
+		{"GRAM ALTIN", "XAU"},
+		{"Gram Altın", "XAU"},
+		{"Gram Altin", "XAU"},
+		{"BİLEZİK", "GR22"},
+		{"BILEZIK", "GR22"},
+		{"Bilezik", "GR22"},
+		{"ÇEYREK", "CEYREK"},
+		{"CEYREK ALTIN", "CEYREK"},
+		{"ZİYNET", "TAM"},
+		{"ZIYNET", "TAM"},
+		{"CUMHURIYET", "ATA"},
+		{"Cumhuriyet", "ATA"},
+	}
+	for _, tt := range tests {
+		unit, ok := FindGoldUnit(units, NormalizeCurrency(tt.currency))
+		if !ok {
+			t.Errorf("FindGoldUnit(%q) found no unit, want %s", tt.currency, tt.want)
+			continue
+		}
+		if unit.Code != tt.want {
+			t.Errorf("FindGoldUnit(%q) = %s, want %s", tt.currency, unit.Code, tt.want)
+		}
+	}
+
+	for _, currency := range []string{"", "USD", "GÜMÜŞ"} {
+		if unit, ok := FindGoldUnit(units, currency); ok {
+			t.Errorf("FindGoldUnit(%q) = %s, want no unit", currency, unit.Code)
+		}
+	}
+}
+
+func TestValidateGoldUnitsFoldsAliases(t *testing.T) {
+	if err := ValidateGoldUnits(DefaultGoldUnits()); err != nil {
+		t.Fatalf("default gold units are invalid: %v", err)
+	}
+
+	units := append(DefaultGoldUnits(), models.GoldUnit{Code: "ZYN", Aliases: []string{"ZIYNET"}, Grams: 7, Purity: 0.916})
+	if err := ValidateGoldUnits(units); err == nil {
+		t.Error("ValidateGoldUnits accepted ZIYNET next to ZİYNET")
+	}
+}
+
+func TestValidateGoldUnitsRejectsCurrencies(t *testing.T) {
+	tests := []models.GoldUnit{
+		{Code: "USD", Grams: 1, Purity: 0.995},
+		{Code: "TL", Grams: 1, Purity: 0.995},
+		{Code: "GBP", Grams: 1, Purity: 0.995},
+		{Code: "sterlin", Aliases: []string{"gbp"}, Grams: 1, Purity: 0.995},
+		{Code: "RIYAL", Aliases: []string{"ŞAR"}, Grams: 1, Purity: 0.995},
+		{Code: "FRANK", Aliases: []string{"CHF"}, Grams: 1, Purity: 0.995},
+		{Code: "LIRA", Aliases: []string{"TRY"}, Grams: 1, Purity: 0.995},
+		{Code: "DOLAR", Aliases: []string{"$"}, Grams: 1, Purity: 0.995},
+	}
+	for _, unit := range tests {
+		if err := ValidateGoldUnits(append(DefaultGoldUnits(), unit)); err == nil {
+			t.Errorf("ValidateGoldUnits accepted unit %s with aliases %v", unit.Code, unit.Aliases)
+		}
+	}
+
+	// Three-letter names are fine as long as TCMB has no such currency
+	units := append(DefaultGoldUnits(), models.GoldUnit{Code: "RST", Aliases: []string{"REŞAT"}, Grams: 7.2, Purity: 0.916})
+	if err := ValidateGoldUnits(units); err != nil {
+		t.Errorf("ValidateGoldUnits rejected unit RST: %v", err)
+	}
+}
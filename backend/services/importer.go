@@ -0,0 +1,393 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"tahsilat-raporu/dateparse"
+	"tahsilat-raporu/models"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// maxHeaderScanRows limits how far down a sheet we look for the header row
+const maxHeaderScanRows = 20
+
+// defaultColumnHeaders maps normalised source headers to RawPaymentData fields
+var defaultColumnHeaders = map[string]string{
+	"müşteri adı soyadı": models.FieldMusteriAdiSoyadi,
+	"tarih":              models.FieldTarih,
+	"tahsilat şekli":     models.FieldTahsilatSekli,
+	"hesap adı":          models.FieldHesapAdi,
+	"ödenen döviz":       models.FieldOdenenDoviz,
+	"proje adı":          models.FieldProjeAdi,
+	"işlem türü":         models.FieldIslemTuru,
+	"orijinal ödeme no":  models.FieldOrijinalOdemeNo,
+}
+
+// amountHeaderPrefixes matches amount columns such as "Ödenen Tutar(Σ:1000000.00)"
+var amountHeaderPrefixes = []string{"ödenen tutar", "alacak tutar"}
+
+// columnIndex maps canonical field names to zero-based column positions
+type columnIndex map[string]int
+
+// importOptions controls how source cells are interpreted
+type importOptions struct {
+	amountLocale string            // settles amounts like "1.234", see ParseAmount
+	headers      map[string]string // profile mapping, normalised header -> canonical field
+	// numericCell reports whether the cell at zero-based row and column holds a
+	// number rather than text; such amounts are read as "1234.567" (may be nil)
+	numericCell func(row, col int) bool
+}
+
+// newImportOptions builds options from an import profile's column mapping (may be nil)
+func newImportOptions(columns map[string]string) importOptions {
+	opts := importOptions{headers: make(map[string]string)}
+	for header, field := range columns {
+		opts.headers[normalizeText(header)] = field
+	}
+	return opts
+}
+
+// ValidateColumnMapping checks that every profile column maps to a known canonical field
+func ValidateColumnMapping(columns map[string]string) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("at least one column mapping is required")
+	}
+	for header, field := range columns {
+		if normalizeText(header) == "" {
+			return fmt.Errorf("column header cannot be empty")
+		}
+		if !isRawPaymentField(field) {
+			valid := append(append([]string{}, models.RawPaymentFields...), models.OptionalRawPaymentFields...)
+			return fmt.Errorf("unknown field '%s' for column '%s' (valid: %s)", field, header, strings.Join(valid, ", "))
+		}
+	}
+	return nil
+}
+
+// isRawPaymentField reports whether field is a required or optional canonical RawPaymentData field
+func isRawPaymentField(field string) bool {
+	for _, fields := range [][]string{models.RawPaymentFields, models.OptionalRawPaymentFields} {
+		for _, f := range fields {
+			if f == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadXLSXPayments reads raw payments from the given sheet of an Excel workbook.
+// If sheet is empty the first sheet is used. columns is an optional import
+// profile mapping that takes precedence over the default headers. Numeric
+// amount cells are read as is; amounts typed as text are parsed with
+// amountLocale, so an ambiguous "1.234" is rejected unless it is set. Row-level
+// problems are returned as messages so that the remaining rows can still be imported.
+func ReadXLSXPayments(r io.Reader, sheet string, columns map[string]string, amountLocale string) ([]models.RawPaymentData, []models.RowError, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open Excel file: %v", err)
+	}
+	defer f.Close()
+
+	if sheet == "" {
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, nil, fmt.Errorf("no sheets found in Excel file")
+		}
+		sheet = sheets[0]
+	}
+
+	// Raw values keep dates as Excel serials and amounts unformatted
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read sheet '%s': %v", sheet, err)
+	}
+
+	log.Printf("Read %d rows from sheet '%s'", len(rows), sheet)
+	// Numeric cells come back as "1234.567"; only text cells use Turkish notation
+	opts := newImportOptions(columns)
+	opts.amountLocale = amountLocale
+	opts.numericCell = func(row, col int) bool {
+		cell, err := excelize.CoordinatesToCellName(col+1, row+1)
+		if err != nil {
+			return false
+		}
+		cellType, err := f.GetCellType(sheet, cell)
+		return err == nil && (cellType == excelize.CellTypeUnset || cellType == excelize.CellTypeNumber)
+	}
+	payments, rowErrors, err := rowsToRawPayments(rows, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Serial dates of workbooks saved with the 1904 date system count from 1904-01-01
+	if props, err := f.GetWorkbookProps(); err == nil && props.Date1904 != nil && *props.Date1904 {
+		log.Printf("Workbook uses the 1904 date system")
+		for i := range payments {
+			payments[i].DateSystem = dateparse.System1904
+		}
+	}
+	return payments, rowErrors, nil
+}
+
+// ReadCSVPayments reads raw payments from a CSV export. The text encoding
+// (UTF-8 with or without BOM, Windows-1254/ISO-8859-9) and the delimiter
+// (";" or ",") are detected from the content. Amount separators are detected
+// per value; semicolon-delimited files are treated as Turkish locale, so an
+// amount like "1.234" reads as 1234 there and is rejected as ambiguous otherwise,
+// unless amountLocale is set. columns is an optional import profile mapping, as
+// for ReadXLSXPayments.
+func ReadCSVPayments(r io.Reader, columns map[string]string, amountLocale string) ([]models.RawPaymentData, []models.RowError, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CSV file: %v", err)
+	}
+
+	text, encoding, err := decodeCSVText(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	delimiter := detectDelimiter(text)
+	log.Printf("Detected CSV encoding %s, delimiter '%c'", encoding, delimiter)
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CSV file: %v", err)
+	}
+
+	log.Printf("Read %d rows from CSV", len(rows))
+	opts := newImportOptions(columns)
+	opts.amountLocale = amountLocale
+	if amountLocale == "" && delimiter == ';' {
+		opts.amountLocale = AmountLocaleTR
+	}
+	return rowsToRawPayments(rows, opts)
+}
+
+// decodeCSVText converts raw CSV bytes to a UTF-8 string and names the detected encoding
+func decodeCSVText(data []byte) (string, string, error) {
+	if bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}) {
+		return string(data[3:]), "UTF-8 (BOM)", nil
+	}
+	if utf8.Valid(data) {
+		return string(data), "UTF-8", nil
+	}
+
+	// Windows-1254 is a superset of ISO-8859-9 for the printable Turkish range
+	decoded, err := charmap.Windows1254.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode CSV file as Windows-1254: %v", err)
+	}
+	return string(decoded), "Windows-1254", nil
+}
+
+// detectDelimiter picks ";" or "," by counting unquoted occurrences in the first lines
+func detectDelimiter(text string) rune {
+	semicolons, commas := 0, 0
+	lines := strings.SplitN(text, "\n", 6)
+	if len(lines) > 5 {
+		lines = lines[:5]
+	}
+
+	for _, line := range lines {
+		inQuotes := false
+		for _, ch := range line {
+			switch {
+			case ch == '"':
+				inQuotes = !inQuotes
+			case inQuotes:
+			case ch == ';':
+				semicolons++
+			case ch == ',':
+				commas++
+			}
+		}
+	}
+
+	if semicolons > commas {
+		return ';'
+	}
+	return ','
+}
+
+// rowsToRawPayments locates the header row and converts the rows below it
+func rowsToRawPayments(rows [][]string, opts importOptions) ([]models.RawPaymentData, []models.RowError, error) {
+	headerRow, cols, err := findHeaderRow(rows, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var payments []models.RawPaymentData
+	var rowErrors []models.RowError
+	for i := headerRow + 1; i < len(rows); i++ {
+		row := rows[i]
+		if isBlankRow(row, cols) {
+			continue
+		}
+
+		// Row numbers are 1-based to match what users see in Excel
+		rowNumber := i + 1
+		rowOpts := opts
+		if opts.numericCell != nil && opts.numericCell(i, cols[models.FieldOdenenTutar]) {
+			rowOpts.amountLocale = AmountLocaleEN
+		}
+		raw, err := buildRawPayment(row, cols, rowOpts)
+		if err != nil {
+			amountStr := cellValue(row, cols, models.FieldOdenenTutar)
+			code := ErrCodeInvalidAmount
+			if errors.Is(err, ErrAmbiguousAmount) {
+				code = ErrCodeAmbiguousAmount
+			}
+			rowError := NewRowError(rowNumber, models.FieldOdenenTutar, amountStr, code, err)
+			rowError.Customer = cellValue(row, cols, models.FieldMusteriAdiSoyadi)
+			rowError.RowData = rowValues(row, cols)
+			rowErrors = append(rowErrors, rowError)
+			continue
+		}
+		raw.RowNumber = rowNumber
+		payments = append(payments, raw)
+	}
+
+	log.Printf("Header found on row %d, %d data rows read, %d row errors", headerRow+1, len(payments), len(rowErrors))
+	return payments, rowErrors, nil
+}
+
+// findHeaderRow returns the first row that contains every required column
+func findHeaderRow(rows [][]string, opts importOptions) (int, columnIndex, error) {
+	bestMatches := -1
+	var bestMissing []string
+
+	for i := 0; i < len(rows) && i < maxHeaderScanRows; i++ {
+		cols := matchHeaders(rows[i], opts)
+
+		var missing []string
+		for _, field := range models.RawPaymentFields {
+			if _, ok := cols[field]; !ok {
+				missing = append(missing, field)
+			}
+		}
+		if len(missing) == 0 {
+			return i, cols, nil
+		}
+		if len(cols) > bestMatches {
+			bestMatches = len(cols)
+			bestMissing = missing
+		}
+	}
+
+	if bestMatches <= 0 {
+		return 0, nil, fmt.Errorf("header row not found in the first %d rows (expected columns like 'Müşteri Adı Soyadı', 'Tarih')", maxHeaderScanRows)
+	}
+	return 0, nil, fmt.Errorf("missing required columns: %s", strings.Join(bestMissing, ", "))
+}
+
+// matchHeaders maps the cells of a candidate header row to canonical fields.
+// Profile headers are matched first; the default headers only fill the gaps.
+func matchHeaders(row []string, opts importOptions) columnIndex {
+	cols := make(columnIndex)
+	assign := func(match func(string) string) {
+		for i, cell := range row {
+			field := match(normalizeText(cell))
+			if field == "" {
+				continue
+			}
+			// Keep the first occurrence if a header is repeated
+			if _, exists := cols[field]; !exists {
+				cols[field] = i
+			}
+		}
+	}
+
+	assign(func(header string) string { return opts.headers[header] })
+	assign(matchDefaultHeader)
+	return cols
+}
+
+// matchDefaultHeader returns the canonical field for a normalised header cell
+func matchDefaultHeader(header string) string {
+	if header == "" {
+		return ""
+	}
+	if field, ok := defaultColumnHeaders[header]; ok {
+		return field
+	}
+	for _, prefix := range amountHeaderPrefixes {
+		if strings.HasPrefix(header, prefix) {
+			return models.FieldOdenenTutar
+		}
+	}
+	return ""
+}
+
+// normalizeText lowercases with Turkish casing rules and collapses whitespace.
+// It is used for header matching and for comparing free-text values.
+func normalizeText(value string) string {
+	value = strings.TrimPrefix(value, "\ufeff")
+	value = strings.ToLowerSpecial(unicode.TurkishCase, value)
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// cellValue returns the trimmed value of a field, or "" if the row is short
+func cellValue(row []string, cols columnIndex, field string) string {
+	idx, ok := cols[field]
+	if !ok || idx >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[idx])
+}
+
+// rowValues returns the mapped cells of a row by field
+func rowValues(row []string, cols columnIndex) map[string]string {
+	values := make(map[string]string, len(cols))
+	for field := range cols {
+		values[field] = cellValue(row, cols, field)
+	}
+	return values
+}
+
+// isBlankRow reports whether all mapped cells of a row are empty
+func isBlankRow(row []string, cols columnIndex) bool {
+	for field := range cols {
+		if cellValue(row, cols, field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// buildRawPayment converts a single data row into RawPaymentData
+func buildRawPayment(row []string, cols columnIndex, opts importOptions) (models.RawPaymentData, error) {
+	amountStr := cellValue(row, cols, models.FieldOdenenTutar)
+	amount, err := ParseAmount(amountStr, opts.amountLocale)
+	if err != nil {
+		return models.RawPaymentData{}, err
+	}
+
+	return models.RawPaymentData{
+		MusteriAdiSoyadi: cellValue(row, cols, models.FieldMusteriAdiSoyadi),
+		Tarih:            cellValue(row, cols, models.FieldTarih),
+		TahsilatSekli:    cellValue(row, cols, models.FieldTahsilatSekli),
+		HesapAdi:         cellValue(row, cols, models.FieldHesapAdi),
+		OdenenTutar:      amount,
+		OdenenTutarText:  amountStr,
+		OdenenDoviz:      cellValue(row, cols, models.FieldOdenenDoviz),
+		ProjeAdi:         cellValue(row, cols, models.FieldProjeAdi),
+		IslemTuru:        cellValue(row, cols, models.FieldIslemTuru),
+		OrijinalOdemeNo:  cellValue(row, cols, models.FieldOrijinalOdemeNo),
+	}, nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/money"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// testWorkbook builds a one-sheet workbook with the default headers and one
+// data row per amount; string amounts are stored as text cells
+func testWorkbook(t *testing.T, amounts ...interface{}) *bytes.Buffer {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+
+	header := []interface{}{"Müşteri Adı Soyadı", "Tarih", "Tahsilat Şekli", "Hesap Adı", "Ödenen Tutar", "Ödenen Döviz", "Proje Adı"}
+	if err := f.SetSheetRow("Sheet1", "A1", &header); err != nil {
+		t.Fatal(err)
+	}
+	for i, amount := range amounts {
+		row := []interface{}{"Ali Veli", "15/01/2025", "Nakit", "Kasa", amount, "TL", "MKM"}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestReadXLSXPaymentsAmountLocale(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount interface{}
+		locale string
+		want   money.Amount
+		code   string // expected row error code, "" if the row is read
+	}{
+		{"numeric cell ignores locale", 1234.5, AmountLocaleTR, 123450, ""},
+		{"numeric cell without locale", 1.234, AmountLocaleAuto, 123, ""},
+		{"turkish text cell", "1.234", AmountLocaleTR, 123400, ""},
+		{"english text cell", "1.234", AmountLocaleEN, 123, ""},
+		{"ambiguous text cell", "1.234", AmountLocaleAuto, 0, ErrCodeAmbiguousAmount},
+		{"unambiguous text cell", "1.234,56", AmountLocaleAuto, 123456, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payments, rowErrors, err := ReadXLSXPayments(testWorkbook(t, tt.amount), "", nil, tt.locale)
+			if err != nil {
+				t.Fatalf("ReadXLSXPayments returned error: %v", err)
+			}
+			if tt.code != "" {
+				if len(rowErrors) != 1 || rowErrors[0].Code != tt.code {
+					t.Fatalf("row errors = %+v, want one %s", rowErrors, tt.code)
+				}
+				if rowErrors[0].RowData[models.FieldOdenenTutar] == "" {
+					t.Errorf("row error has no amount in RowData")
+				}
+				return
+			}
+			if len(rowErrors) != 0 || len(payments) != 1 {
+				t.Fatalf("got %d payments and row errors %+v, want one payment", len(payments), rowErrors)
+			}
+			if payments[0].OdenenTutar != tt.want {
+				t.Errorf("amount = %s, want %s", payments[0].OdenenTutar, tt.want)
+			}
+		})
+	}
+}
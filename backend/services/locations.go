@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"tahsilat-raporu/models"
+)
+
+// fallbackLocationLabel is shown for payments no location matched
+const fallbackLocationLabel = "DİĞER"
+
+// DefaultLocations returns the locations used until others are saved. Cheques
+// are reported under ÇEK whichever account they were booked to.
+func DefaultLocations() []models.Location {
+	return []models.Location{
+		{Code: "CARSI", Label: "ÇARŞI", AccountPatterns: []string{"çarşı"}, MethodOverrides: []string{}, SortOrder: 10},
+		{Code: "KUYUMCUKENT", Label: "KUYUMCUKENT", AccountPatterns: []string{"kuyumcukent"}, MethodOverrides: []string{}, SortOrder: 20},
+		{Code: "BANKA", Label: "BANKA HAVALESİ", AccountPatterns: []string{"yapı kredi", "banka", "havale"}, MethodOverrides: []string{}, SortOrder: 30},
+		{Code: "CEK", Label: "ÇEK", AccountPatterns: []string{"çek"}, MethodOverrides: []string{models.PaymentMethodCheck}, SortOrder: 40},
+		{Code: "OFIS", Label: "OFİS", AccountPatterns: []string{"ofis", "office", "kasa"}, MethodOverrides: []string{}, SortOrder: 50},
+	}
+}
+
+// NormalizeLocationCode returns the form location codes are stored in
+func NormalizeLocationCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ValidateLocation checks that a location has a code other than the fallback
+// location, a label and only non-empty patterns and payment methods
+func ValidateLocation(location models.Location) error {
+	code := NormalizeLocationCode(location.Code)
+	if code == "" {
+		return fmt.Errorf("code must not be empty")
+	}
+	if code == ClassificationFallbacks[models.RuleFieldLocation] {
+		return fmt.Errorf("code %s is reserved for payments without a location", code)
+	}
+	if strings.TrimSpace(location.Label) == "" {
+		return fmt.Errorf("label must not be empty")
+	}
+	for _, pattern := range location.AccountPatterns {
+		if foldText(pattern) == "" {
+			return fmt.Errorf("account patterns must not be empty")
+		}
+	}
+	for _, method := range location.MethodOverrides {
+		if strings.TrimSpace(method) == "" {
+			return fmt.Errorf("method overrides must not be empty")
+		}
+	}
+	return nil
+}
+
+// SortLocations orders locations by sort order and then by code
+func SortLocations(locations []models.Location) {
+	sort.SliceStable(locations, func(i, j int) bool {
+		if locations[i].SortOrder != locations[j].SortOrder {
+			return locations[i].SortOrder < locations[j].SortOrder
+		}
+		return locations[i].Code < locations[j].Code
+	})
+}
+
+// ReportLocations returns the locations a report shows: the configured ones in
+// order, then any other of the reported location codes, e.g. the fallback location
+func ReportLocations(locations []models.Location, reported map[string]bool) []models.Location {
+	listed := append([]models.Location{}, locations...)
+	SortLocations(listed)
+
+	known := make(map[string]bool)
+	for _, location := range listed {
+		known[location.Code] = true
+	}
+	var others []string
+	for code := range reported {
+		if !known[code] {
+			others = append(others, code)
+		}
+	}
+	sort.Strings(others)
+	for _, code := range others {
+		label := code
+		if code == ClassificationFallbacks[models.RuleFieldLocation] {
+			label = fallbackLocationLabel
+		}
+		listed = append(listed, models.Location{Code: code, Label: label})
+	}
+	return listed
+}
+
+// LocationCodes returns the set of location codes a location summary has totals for
+func LocationCodes(summary map[string]models.LocationTotal) map[string]bool {
+	codes := make(map[string]bool, len(summary))
+	for code := range summary {
+		codes[code] = true
+	}
+	return codes
+}
+
+// FindLocationCode returns the code of the location a stored location value
+// names, comparing it with the codes and labels as contains rules do, so
+// "ÇARŞI", "CARŞI" and "CARSI" are all the same location
+func FindLocationCode(locations []models.Location, value string) (string, bool) {
+	key := foldText(value)
+	if key == foldText(fallbackLocationLabel) || key == foldText(ClassificationFallbacks[models.RuleFieldLocation]) {
+		return ClassificationFallbacks[models.RuleFieldLocation], true
+	}
+	for _, location := range locations {
+		if key == foldText(location.Code) || key == foldText(location.Label) {
+			return location.Code, true
+		}
+	}
+	return "", false
+}
+
+// locationRules turns the account patterns of the locations into contains
+// rules on Hesap Adı, in location order. They have no ID, so payments do not
+// record them.
+func locationRules(locations []models.Location) []models.ClassificationRule {
+	sorted := append([]models.Location{}, locations...)
+	SortLocations(sorted)
+
+	var rules []models.ClassificationRule
+	for _, location := range sorted {
+		for _, pattern := range location.AccountPatterns {
+			rules = append(rules, models.ClassificationRule{
+				Field:     models.RuleFieldLocation,
+				Source:    models.FieldHesapAdi,
+				MatchType: models.RuleMatchContains,
+				Pattern:   pattern,
+				Value:     location.Code,
+				Enabled:   true,
+				Note:      "Account pattern of location " + location.Code,
+			})
+		}
+	}
+	return rules
+}
+
+// methodLocations maps each overridden payment method to a rule placing it in
+// its location. The first location by sort order wins a method listed twice.
+func methodLocations(locations []models.Location) map[string]models.ClassificationRule {
+	sorted := append([]models.Location{}, locations...)
+	SortLocations(sorted)
+
+	overrides := make(map[string]models.ClassificationRule)
+	for _, location := range sorted {
+		for _, method := range location.MethodOverrides {
+			method = strings.TrimSpace(method)
+			if _, ok := overrides[method]; ok {
+				continue
+			}
+			overrides[method] = models.ClassificationRule{
+				Field:     models.RuleFieldLocation,
+				Source:    models.RuleFieldPaymentMethod,
+				MatchType: models.RuleMatchEquals,
+				Pattern:   method,
+				Value:     location.Code,
+				Enabled:   true,
+				Note:      "Payment method override of location " + location.Code,
+			}
+		}
+	}
+	return overrides
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"tahsilat-raporu/models"
+)
+
+// paymentKindNames maps folded "İşlem Türü" values (see foldText) to payment kinds
+var paymentKindNames = map[string]string{
+	"tahsilat":    models.PaymentKindCollection,
+	"collection":  models.PaymentKindCollection,
+	"iade":        models.PaymentKindRefund,
+	"refund":      models.PaymentKindRefund,
+	"iptal":       models.PaymentKindReversal,
+	"ters kayit":  models.PaymentKindReversal,
+	"karsiliksiz": models.PaymentKindReversal,
+	"reversal":    models.PaymentKindReversal,
+}
+
+// ParsePaymentKind reads an "İşlem Türü" value in Turkish or English, ignoring
+// case and Turkish letters, so "IADE", "İade" and "iade" are all refunds. An
+// empty value returns "" so that the caller can fall back to the amount's sign.
+func ParsePaymentKind(value string) (string, error) {
+	name := foldText(value)
+	if name == "" {
+		return "", nil
+	}
+	if kind, ok := paymentKindNames[name]; ok {
+		return kind, nil
+	}
+	return "", fmt.Errorf("unknown payment kind '%s' (valid: tahsilat, iade, iptal)", value)
+}
+
+// ClassifyPaymentKind decides the kind of a raw payment. Without an explicit
+// kind a negative amount is a refund and anything else a collection.
+func ClassifyPaymentKind(raw models.RawPaymentData) (string, error) {
+	kind, err := ParsePaymentKind(raw.IslemTuru)
+	if err != nil || kind != "" {
+		return kind, err
+	}
+	if raw.OdenenTutar < 0 {
+		return models.PaymentKindRefund, nil
+	}
+	return models.PaymentKindCollection, nil
+}
+
+// ParseOriginalPaymentID reads the "Orijinal Ödeme No" of a refund or reversal
+func ParseOriginalPaymentID(value string) (int64, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid original payment ID '%s'", value)
+	}
+	return id, nil
+}
+
+// IsNegativeKind reports whether payments of kind are stored as negative amounts
+func IsNegativeKind(kind string) bool {
+	return kind == models.PaymentKindRefund || kind == models.PaymentKindReversal
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"testing"
+
+	"tahsilat-raporu/models"
+)
+
+func TestParsePaymentKind(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"  ", ""},
+		{"tahsilat", models.PaymentKindCollection},
+		{"TAHSILAT", models.PaymentKindCollection},
+		{"TAHSİLAT", models.PaymentKindCollection},
+		{"Tahsilat", models.PaymentKindCollection},
+		{"Collection", models.PaymentKindCollection},
+		{"iade", models.PaymentKindRefund},
+		{"IADE", models.PaymentKindRefund},
+		{"İADE", models.PaymentKindRefund},
+		{"İade", models.PaymentKindRefund},
+		{"Refund", models.PaymentKindRefund},
+		{"iptal", models.PaymentKindReversal},
+		{"IPTAL", models.PaymentKindReversal},
+		{"İptal", models.PaymentKindReversal},
+		{"Ters Kayıt", models.PaymentKindReversal},
+		{"TERS KAYIT", models.PaymentKindReversal},
+		{"ters  kayit", models.PaymentKindReversal},
+		{"KARŞILIKSIZ", models.PaymentKindReversal},
+		{"Karsiliksiz", models.PaymentKindReversal},
+		{"REVERSAL", models.PaymentKindReversal},
+	}
+	for _, tt := range tests {
+		got, err := ParsePaymentKind(tt.value)
+		if err != nil {
+			t.Errorf("ParsePaymentKind(%q) returned error: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePaymentKind(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParsePaymentKindUnknown(t *testing.T) {
+	for _, value := range []string{"havale", "IADEX", "virman"} {
+		if kind, err := ParsePaymentKind(value); err == nil {
+			t.Errorf("ParsePaymentKind(%q) = %q, want an error", value, kind)
+		}
+	}
+}
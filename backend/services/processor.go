@@ -1,62 +1,216 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"strconv"
 	"strings"
+	"tahsilat-raporu/dateparse"
 	"tahsilat-raporu/models"
+	"tahsilat-raporu/money"
 	"time"
 )
 
 // PaymentProcessor handles the complete payment processing pipeline
 type PaymentProcessor struct {
-	methodClassifier   *PaymentMethodClassifier
-	locationClassifier *LocationClassifier
-	projectClassifier  *ProjectClassifier
+	rules              *RuleSet
+	ruleList           []models.ClassificationRule
+	projects           []models.Project
+	locations          []models.Location
+	datePolicy         models.DatePolicy
+	dateOptions        dateparse.Options
+	dateFormat         dateparse.ColumnFormat // Format of the Tarih column in the last batch
+	goldUnits          []models.GoldUnit
+	goldPrices         []models.GoldPrice // Sorted by date
 }
 
-// NewPaymentProcessor creates a new payment processor
+// NewPaymentProcessor creates a new payment processor using the default
+// classification rules, projects, locations, date policy and gold units, with
+// no gold prices. It panics if the defaults do not validate.
 func NewPaymentProcessor() *PaymentProcessor {
+	ruleList, projects, locations := DefaultClassificationRules(), DefaultProjects(), DefaultLocations()
+	rules, err := NewRuleSet(ruleList, projects, locations)
+	if err != nil {
+		panic(fmt.Sprintf("default classification rules are invalid: %v", err))
+	}
 	return &PaymentProcessor{
-		methodClassifier:   &PaymentMethodClassifier{},
-		locationClassifier: &LocationClassifier{},
-		projectClassifier:  &ProjectClassifier{},
+		rules:              rules,
+		ruleList:           ruleList,
+		projects:           projects,
+		locations:          locations,
+		datePolicy:         DefaultDatePolicy(),
+		goldUnits:          DefaultGoldUnits(),
+	}
+}
+
+// SetClassificationRules replaces the rules that decide payment method,
+// location and project
+func (p *PaymentProcessor) SetClassificationRules(rules []models.ClassificationRule) error {
+	set, err := NewRuleSet(rules, p.projects, p.locations)
+	if err != nil {
+		return err
+	}
+	p.rules, p.ruleList = set, rules
+	return nil
+}
+
+// SetProjects replaces the projects whose patterns classify payments that no
+// project rule matched
+func (p *PaymentProcessor) SetProjects(projects []models.Project) error {
+	set, err := NewRuleSet(p.ruleList, projects, p.locations)
+	if err != nil {
+		return err
+	}
+	p.rules, p.projects = set, projects
+	return nil
+}
+
+// SetLocations replaces the locations whose account patterns and payment
+// method overrides classify payments
+func (p *PaymentProcessor) SetLocations(locations []models.Location) error {
+	set, err := NewRuleSet(p.ruleList, p.projects, locations)
+	if err != nil {
+		return err
 	}
+	p.rules, p.locations = set, locations
+	return nil
+}
+
+// Classify returns the value the classification rules give field for an
+// input row, and the rule that decided it (nil for the fallback value)
+func (p *PaymentProcessor) Classify(field string, raw models.RawPaymentData) (string, *models.ClassificationRule) {
+	return p.rules.Classify(field, raw)
+}
+
+// SetGoldValuation replaces the gold units and the gold price table used to
+// value gold payments. Prices must be sorted by date.
+func (p *PaymentProcessor) SetGoldValuation(units []models.GoldUnit, prices []models.GoldPrice) {
+	p.goldUnits = units
+	p.goldPrices = prices
 }
 
-// Process converts raw payment data to processed payment record
+// SetDatePolicy replaces the policy used to accept or reject payment dates
+func (p *PaymentProcessor) SetDatePolicy(policy models.DatePolicy) {
+	p.datePolicy = policy
+}
+
+// SetDateOptions forces a date order or date system instead of inferring them
+func (p *PaymentProcessor) SetDateOptions(opts dateparse.Options) {
+	p.dateOptions = opts
+}
+
+// DateFormat returns how the Tarih column of the last batch was interpreted
+func (p *PaymentProcessor) DateFormat() dateparse.ColumnFormat {
+	return p.dateFormat
+}
+
+// inferDateFormat decides the date format from the Tarih values of all rows.
+// Rows read from a 1904-based workbook carry that date system themselves.
+func (p *PaymentProcessor) inferDateFormat(rawPayments []models.RawPaymentData) dateparse.ColumnFormat {
+	opts := p.dateOptions
+	values := make([]string, len(rawPayments))
+	for i, raw := range rawPayments {
+		values[i] = raw.Tarih
+		if opts.DateSystem == "" {
+			opts.DateSystem = raw.DateSystem
+		}
+	}
+	return dateparse.InferColumn(values, opts)
+}
+
+// Process converts raw payment data to processed payment record. The date is
+// parsed on its own, with the options from SetDateOptions.
+// Errors are *fieldError values naming the field that failed.
 func (p *PaymentProcessor) Process(raw models.RawPaymentData) (*models.PaymentRecord, error) {
-	// Parse date - now supports Excel serial dates
-	paymentDate, err := parseImprovedDate(raw.Tarih)
+	return p.processRow(raw, p.inferDateFormat([]models.RawPaymentData{raw}))
+}
+
+// processRow converts one row, parsing its date with the column format
+func (p *PaymentProcessor) processRow(raw models.RawPaymentData, dateFormat dateparse.ColumnFormat) (*models.PaymentRecord, error) {
+	paymentDate, err := dateFormat.Parse(raw.Tarih)
+	if errors.Is(err, dateparse.ErrAmbiguousDate) {
+		return nil, &fieldError{models.FieldTarih, raw.Tarih, ErrCodeAmbiguousDate, err}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("invalid date format '%s': %v", raw.Tarih, err)
+		return nil, &fieldError{models.FieldTarih, raw.Tarih, ErrCodeInvalidDate, fmt.Errorf("invalid date format '%s': %v", raw.Tarih, err)}
+	}
+
+	// Refunds and reversals are stored negative, whichever sign the file used
+	kind, err := ClassifyPaymentKind(raw)
+	if err != nil {
+		return nil, &fieldError{models.FieldIslemTuru, raw.IslemTuru, ErrCodeInvalidKind, err}
+	}
+	amount := raw.OdenenTutar
+	if IsNegativeKind(kind) {
+		amount = -amount.Abs()
+	}
+
+	var originalID *int64
+	if raw.OrijinalOdemeNo != "" {
+		if !IsNegativeKind(kind) {
+			return nil, &fieldError{models.FieldOrijinalOdemeNo, raw.OrijinalOdemeNo, ErrCodeInvalidOriginal,
+				fmt.Errorf("only refunds and reversals can reference an original payment")}
+		}
+		id, err := ParseOriginalPaymentID(raw.OrijinalOdemeNo)
+		if err != nil {
+			return nil, &fieldError{models.FieldOrijinalOdemeNo, raw.OrijinalOdemeNo, ErrCodeInvalidOriginal, err}
+		}
+		originalID = &id
 	}
 
 	// Classify payment components with debugging
 	fmt.Printf("CLASSIFICATION DEBUG - TahsilatSekli: '%s', HesapAdi: '%s'\n", raw.TahsilatSekli, raw.HesapAdi)
-	paymentMethod := p.methodClassifier.Classify(raw.TahsilatSekli, raw.HesapAdi)
+	matchedRules := make(map[string]int64)
+	var reviewReasons []models.ReviewReason
+	classify := func(field string) string {
+		value, rule := p.rules.Classify(field, raw)
+		if rule != nil && rule.ID > 0 {
+			matchedRules[field] = rule.ID
+		}
+		// A fallback value is only a guess, an operator has to review it
+		if rule == nil {
+			reviewReasons = append(reviewReasons, FallbackReviewReason(field, raw))
+		}
+		return value
+	}
+	paymentMethod := classify(models.RuleFieldPaymentMethod)
 	fmt.Printf("CLASSIFICATION RESULT: '%s'\n", paymentMethod)
-	location := p.locationClassifier.Classify(raw.HesapAdi)
-	project := p.projectClassifier.Classify(raw.ProjeAdi)
+	location := classify(models.RuleFieldLocation)
+	project := classify(models.RuleFieldProject)
 
 	// Create processed payment
 	payment := &models.PaymentRecord{
 		CustomerName:  strings.TrimSpace(raw.MusteriAdiSoyadi),
 		PaymentDate:   paymentDate,
-		Amount:        raw.OdenenTutar,
-		Currency:      strings.ToUpper(strings.TrimSpace(raw.OdenenDoviz)),
+		Amount:        amount,
+		Currency:      p.currencyCode(raw.OdenenDoviz),
 		PaymentMethod: paymentMethod,
 		Location:      location,
 		Project:       project,
 		AccountName:   strings.TrimSpace(raw.HesapAdi),
 		CreatedAt:     time.Now(),
+		Kind:          kind,
+		OriginalID:    originalID,
+		MatchedRules:  matchedRules,
+		ReviewStatus:  ReviewStatusFor(reviewReasons),
+		ReviewReasons: reviewReasons,
+	}
+
+	// Gold is paid in units such as grams or çeyrek; the amount is the quantity
+	if unit, ok := FindGoldUnit(p.goldUnits, payment.Currency); ok {
+		payment.GoldGrams = GoldFineGrams(unit, payment.Amount)
 	}
 
 	// Convert to USD
 	amountUSD, rate, err := p.convertToUSD(payment)
+	if errors.Is(err, ErrNoGoldPrice) {
+		return nil, &fieldError{models.FieldTarih, raw.Tarih, ErrCodeNoGoldPrice, err}
+	}
+	if errors.Is(err, ErrCurrencyNotPublished) {
+		return nil, &fieldError{models.FieldOdenenDoviz, raw.OdenenDoviz, ErrCodeInvalidCurrency, err}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("currency conversion failed: %v", err)
+		return nil, &fieldError{models.FieldOdenenDoviz, raw.OdenenDoviz, ErrCodeConversionFailed, fmt.Errorf("currency conversion failed: %v", err)}
 	}
 
 	payment.AmountUSD = amountUSD
@@ -65,13 +219,24 @@ func (p *PaymentProcessor) Process(raw models.RawPaymentData) (*models.PaymentRe
 	return payment, nil
 }
 
-// convertToUSD converts any currency amount to USD
-func (p *PaymentProcessor) convertToUSD(payment *models.PaymentRecord) (float64, float64, error) {
+// currencyCode returns the currency an "Ödenen Döviz" value is stored with:
+// the code of the gold unit it names, or the normalised currency code
+func (p *PaymentProcessor) currencyCode(value string) string {
+	code := NormalizeCurrency(value)
+	if unit, ok := FindGoldUnit(p.goldUnits, code); ok {
+		return unit.Code
+	}
+	return code
+}
+
+// convertToUSD converts any currency amount to USD. Conversions round once,
+// see the money package for the rounding policy.
+func (p *PaymentProcessor) convertToUSD(payment *models.PaymentRecord) (money.Amount, money.Rate, error) {
 	log.Printf("Converting to USD: %.2f %s on %s", payment.Amount, payment.Currency, payment.PaymentDate.Format("2006-01-02"))
 	
 	if payment.Currency == "USD" {
 		log.Printf("Already in USD: %.2f", payment.Amount)
-		return payment.Amount, 1.0, nil
+		return payment.Amount, money.OneRate, nil
 	}
 
 	if payment.Currency == "TL" {
@@ -80,103 +245,153 @@ func (p *PaymentProcessor) convertToUSD(payment *models.PaymentRecord) (float64,
 			log.Printf("Error getting USD rate: %v", err)
 			return 0, 0, err
 		}
-		usdAmount := payment.Amount / rate
+		usdAmount, err := payment.Amount.ConvertInverse(rate)
+		if err != nil {
+			return 0, 0, err
+		}
 		log.Printf("TL to USD: %.2f TL * (1/%.4f) = %.2f USD", payment.Amount, rate, usdAmount)
 		return usdAmount, rate, nil
 	}
 
-	if payment.Currency == "EUR" {
-		usdRate, err := GetExchangeRate(payment.PaymentDate, "USD")
+	// Gold is valued from the local price table, TCMB does not publish it
+	if unit, ok := FindGoldUnit(p.goldUnits, payment.Currency); ok {
+		price, err := FindGoldPrice(p.goldPrices, payment.PaymentDate)
 		if err != nil {
-			log.Printf("Error getting USD rate: %v", err)
+			log.Printf("Error getting gold price: %v", err)
 			return 0, 0, err
 		}
-		eurRate, err := GetExchangeRate(payment.PaymentDate, "EUR")
-		if err != nil {
-			log.Printf("Error getting EUR rate: %v", err)
-			return 0, 0, err
-		}
-		// EUR amount -> TL -> USD
-		tlAmount := payment.Amount * eurRate
-		usdAmount := tlAmount / usdRate
-		log.Printf("EUR to USD: %.2f EUR * %.4f / %.4f = %.2f USD", payment.Amount, eurRate, usdRate, usdAmount)
-		return usdAmount, eurRate, nil
+		rate := GoldUnitRate(unit, price)
+		usdAmount := payment.Amount.Convert(rate)
+		log.Printf("%s to USD: %.2f %s * %.4f USD (%.4f USD/g on %s) = %.2f USD",
+			unit.Code, payment.Amount, unit.Code, rate, price.USDPerGram, price.Date, usdAmount)
+		return usdAmount, rate, nil
+	}
+
+	if !IsCurrencyCode(payment.Currency) {
+		return 0, 0, fmt.Errorf("%w: '%s' is neither a currency code nor a gold unit", ErrCurrencyNotPublished, payment.Currency)
 	}
 
-	return 0, 0, fmt.Errorf("unsupported currency: %s", payment.Currency)
+	// Any other currency TCMB publishes goes through TL: currency -> TL -> USD
+	usdRate, err := GetExchangeRate(payment.PaymentDate, "USD")
+	if err != nil {
+		log.Printf("Error getting USD rate: %v", err)
+		return 0, 0, err
+	}
+	currencyRate, err := GetExchangeRate(payment.PaymentDate, payment.Currency)
+	if err != nil {
+		log.Printf("Error getting %s rate: %v", payment.Currency, err)
+		return 0, 0, err
+	}
+	usdAmount, err := payment.Amount.ConvertCross(currencyRate, usdRate)
+	if err != nil {
+		return 0, 0, err
+	}
+	log.Printf("%s to USD: %.2f %s * %.4f / %.4f = %.2f USD",
+		payment.Currency, payment.Amount, payment.Currency, currencyRate, usdRate, usdAmount)
+	return usdAmount, currencyRate, nil
 }
 
-// ValidatePayment validates a processed payment record
-func ValidatePayment(payment *models.PaymentRecord) []string {
-	var errors []string
+// ValidatePayment validates a processed payment record, checking the date
+// against the given policy. The returned errors have no row number; ProcessBatch fills it in.
+func ValidatePayment(payment *models.PaymentRecord, raw models.RawPaymentData, policy models.DatePolicy) []models.RowError {
+	var errors []models.RowError
 
 	// Customer name required
 	if payment.CustomerName == "" {
-		errors = append(errors, "Müşteri adı boş olamaz")
+		errors = append(errors, NewRowError(0, models.FieldMusteriAdiSoyadi, raw.MusteriAdiSoyadi, ErrCodeMissingCustomer, nil))
 	}
 
-	// Amount must be positive
-	if payment.Amount <= 0 {
-		errors = append(errors, "Ödenen tutar sıfırdan büyük olmalı")
+	// Collections must be positive; refunds and reversals were made negative by Process
+	if payment.Amount == 0 || payment.Kind == models.PaymentKindCollection && payment.Amount < 0 {
+		errors = append(errors, NewRowError(0, models.FieldOdenenTutar, raw.OdenenTutar.String(), ErrCodeNonPositiveAmount, nil))
 	}
 
-	// Currency must be valid
-	validCurrencies := map[string]bool{"TL": true, "USD": true, "EUR": true}
-	if !validCurrencies[payment.Currency] {
-		errors = append(errors, "Geçersiz para birimi: "+payment.Currency)
+	// Currency must be valid; gold payments were matched to a gold unit by Process
+	if !IsCurrencyCode(payment.Currency) && payment.GoldGrams == 0 {
+		errors = append(errors, NewRowError(0, models.FieldOdenenDoviz, raw.OdenenDoviz, ErrCodeInvalidCurrency, nil))
 	}
 
 	// Project must be recognized
 	if payment.Project == "UNKNOWN" {
-		errors = append(errors, "Proje tanımlanamadı")
-	}
-
-	// Date must be reasonable - allow future dates but warn
-	if payment.PaymentDate.After(time.Now().AddDate(0, 6, 0)) { // More than 6 months in future
-		errors = append(errors, "Çok ileri tarihli ödeme (6 aydan fazla)")
+		errors = append(errors, NewRowError(0, models.FieldProjeAdi, raw.ProjeAdi, ErrCodeUnknownProject, nil))
 	}
 
-	// Date must not be too old (more than 10 years)
-	if payment.PaymentDate.Before(time.Now().AddDate(-10, 0, 0)) {
-		errors = append(errors, "Çok eski tarihli ödeme")
+	// Date must be accepted by the date policy
+	if code, reason := CheckPaymentDate(policy, payment.PaymentDate, time.Now()); reason != nil {
+		errors = append(errors, NewRowError(0, models.FieldTarih, raw.Tarih, code, reason))
 	}
 
 	return errors
 }
 
 // ProcessBatch processes multiple raw payments
-func (p *PaymentProcessor) ProcessBatch(rawPayments []models.RawPaymentData) ([]models.PaymentRecord, []string) {
+func (p *PaymentProcessor) ProcessBatch(rawPayments []models.RawPaymentData) ([]models.PaymentRecord, []models.RowError) {
+	return p.ProcessBatchWithProgress(rawPayments, nil)
+}
+
+// ProcessBatchWithProgress works like ProcessBatch and calls progress (if not nil)
+// with the number of finished rows, since exchange rate lookups can make large batches slow
+func (p *PaymentProcessor) ProcessBatchWithProgress(rawPayments []models.RawPaymentData, progress func(done, total int)) ([]models.PaymentRecord, []models.RowError) {
 	var processedPayments []models.PaymentRecord
-	var allErrors []string
+	var allErrors []models.RowError
 
 	log.Printf("=== STARTING BATCH PROCESSING ===")
 	log.Printf("Total raw payments to process: %d", len(rawPayments))
 
+	// Decide DD/MM versus MM/DD and serial versus text once for the whole column
+	p.dateFormat = p.inferDateFormat(rawPayments)
+	log.Printf("Date column format: order=%s system=%s serial=%t ambiguous=%t conflicting=%t",
+		p.dateFormat.Order, p.dateFormat.DateSystem, p.dateFormat.Serial, p.dateFormat.Ambiguous, p.dateFormat.Conflicting)
+
 	for i, raw := range rawPayments {
-		log.Printf("--- Processing row %d ---", i+1)
+		// Prefer the source file row so errors point at the right line
+		rowNumber := i + 1
+		if raw.RowNumber > 0 {
+			rowNumber = raw.RowNumber
+		}
+
+		log.Printf("--- Processing row %d ---", rowNumber)
 		log.Printf("Customer: %s, Date: %s, Amount: %.2f %s", raw.MusteriAdiSoyadi, raw.Tarih, raw.OdenenTutar, raw.OdenenDoviz)
 		
-		payment, err := p.Process(raw)
+		if progress != nil {
+			progress(i, len(rawPayments))
+		}
+
+		payment, err := p.processRow(raw, p.dateFormat)
 		if err != nil {
-			errorMsg := fmt.Sprintf("Satır %d (%s): %v", i+1, raw.MusteriAdiSoyadi, err)
-			allErrors = append(allErrors, errorMsg)
-			log.Printf("ERROR processing row %d: %v", i+1, err)
+			rowError := NewRowError(rowNumber, "", "", ErrCodeProcessingFailed, err)
+			if fe, ok := err.(*fieldError); ok {
+				rowError = NewRowError(rowNumber, fe.field, fe.value, fe.code, fe.cause)
+			}
+			rowError.Customer = raw.MusteriAdiSoyadi
+			rowError.RowData = RawPaymentValues(raw)
+			allErrors = append(allErrors, rowError)
+			log.Printf("ERROR processing row %d: %v", rowNumber, err)
 			continue
 		}
 
 		// Validate the processed payment
-		validationErrors := ValidatePayment(payment)
+		validationErrors := ValidatePayment(payment, raw, p.datePolicy)
 		if len(validationErrors) > 0 {
 			for _, validationError := range validationErrors {
-				errorMsg := fmt.Sprintf("Satır %d (%s): %s", i+1, raw.MusteriAdiSoyadi, validationError)
-				allErrors = append(allErrors, errorMsg)
-				log.Printf("VALIDATION ERROR row %d: %s", i+1, validationError)
+				validationError.Row = rowNumber
+				validationError.Customer = raw.MusteriAdiSoyadi
+				validationError.RowData = RawPaymentValues(raw)
+				allErrors = append(allErrors, validationError)
+				log.Printf("VALIDATION ERROR row %d: %s", rowNumber, validationError.Message)
 			}
 			continue
 		}
 
+		payment.RowNumber = rowNumber
+		original := raw
+		payment.Original = &original
 		processedPayments = append(processedPayments, *payment)
-		log.Printf("SUCCESS row %d: %s - %.2f USD", i+1, payment.CustomerName, payment.AmountUSD)
+		log.Printf("SUCCESS row %d: %s - %.2f USD", rowNumber, payment.CustomerName, payment.AmountUSD)
+	}
+
+	if progress != nil {
+		progress(len(rawPayments), len(rawPayments))
 	}
 
 	log.Printf("=== BATCH PROCESSING COMPLETE ===")
@@ -184,216 +399,8 @@ func (p *PaymentProcessor) ProcessBatch(rawPayments []models.RawPaymentData) ([]
 	log.Printf("Errors encountered: %d", len(allErrors))
 	
 	for _, err := range allErrors {
-		log.Printf("ERROR: %s", err)
+		log.Printf("ERROR: %s", FormatRowError(err))
 	}
 
 	return processedPayments, allErrors
 }
-
-// parseImprovedDate parses date in multiple formats including Excel serial dates and Turkish months
-func parseImprovedDate(dateStr string) (time.Time, error) {
-	// Clean up the date string
-	dateStr = strings.TrimSpace(dateStr)
-	log.Printf("Parsing date string: '%s'", dateStr)
-	
-	// First, try to parse as Excel serial date (number)
-	if floatVal, err := strconv.ParseFloat(dateStr, 64); err == nil {
-		// Excel serial date: days since January 1, 1900
-		// Adjust for Excel's leap year bug (1900 is not a leap year)
-		if floatVal > 59 {
-			floatVal = floatVal - 1
-		}
-		
-		// Convert to Go time
-		excelEpoch := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
-		days := int(floatVal) - 1 // Excel starts from 1, not 0
-		fractionalDay := floatVal - float64(int(floatVal))
-		
-		date := excelEpoch.AddDate(0, 0, days)
-		
-		// Add fractional day (time portion)
-		hours := fractionalDay * 24
-		minutes := (hours - float64(int(hours))) * 60
-		seconds := (minutes - float64(int(minutes))) * 60
-		
-		date = date.Add(time.Duration(int(hours)) * time.Hour)
-		date = date.Add(time.Duration(int(minutes)) * time.Minute)
-		date = date.Add(time.Duration(int(seconds)) * time.Second)
-		
-		log.Printf("Parsed Excel serial date %s -> %s", dateStr, date.Format("2006-01-02"))
-		return date, nil
-	}
-	
-	// === NEW: HANDLE TURKISH MONTH FORMATS (DD/MonthName/YYYY) ===
-	if strings.Contains(dateStr, "/") && !isNumericDate(dateStr) {
-		// Handle Turkish month names like "02/ocak/2025", "25/aralık/2025"
-		if t, err := parseTurkishDate(dateStr); err == nil {
-			// Apply validation
-			futureCutoff := time.Now().AddDate(0, 6, 0) // 6 months from now
-			if t.After(futureCutoff) {
-				log.Printf("🚨 WARNING: Turkish date '%s' is too far in future, rejecting", dateStr)
-				return time.Time{}, fmt.Errorf("date '%s' is too far in future (beyond %s)", dateStr, futureCutoff.Format("2006-01-02"))
-			}
-			
-			if t.Year() == 2025 && t.Month() == time.December {
-				log.Printf("🔥 DECEMBER 2025 ALERT: Rejecting December 2025 Turkish date '%s'", dateStr)
-				return time.Time{}, fmt.Errorf("december 2025 dates are not allowed: %s", dateStr)
-			}
-			
-			log.Printf("✅ Parsed Turkish date '%s' -> %s", dateStr, t.Format("2006-01-02"))
-			return t, nil
-		}
-	}
-	
-	// === HANDLE "DD Month YYYY" FORMAT (like "31 January 2025") ===
-	if strings.Contains(dateStr, " ") && !strings.Contains(dateStr, "/") && !strings.Contains(dateStr, "-") {
-		// Try "DD Month YYYY" format (like your imported data)
-		monthFormats := []string{
-			"02 January 2006",   // DD Month YYYY (English month names)
-			"2 January 2006",    // D Month YYYY (single digit day)
-		}
-		
-		for _, format := range monthFormats {
-			if t, err := time.Parse(format, dateStr); err == nil {
-				// --- CRITICAL VALIDATION FOR "DD Month YYYY" FORMAT ---
-				futureCutoff := time.Now().AddDate(0, 6, 0) // 6 months from now
-				if t.After(futureCutoff) {
-					log.Printf("🚨 HIGH WARNING: Parsed date '%s' (%s) is too far in future (cutoff: %s). REJECTING!", 
-						t.Format("2006-01-02"), dateStr, futureCutoff.Format("2006-01-02"))
-					return time.Time{}, fmt.Errorf("date '%s' is too far in future (beyond %s)", dateStr, futureCutoff.Format("2006-01-02"))
-				}
-
-				// Check if date is suspiciously in December 2025
-				if t.Year() == 2025 && t.Month() == time.December {
-					log.Printf("🔥 DECEMBER 2025 ALERT: Date '%s' parsed as December 2025 - REJECTING!", dateStr)
-					return time.Time{}, fmt.Errorf("december 2025 dates are not allowed: %s", dateStr)
-				}
-
-				log.Printf("✅ Parsed date '%s' using DD Month YYYY format -> %s", dateStr, t.Format("2006-01-02"))
-				return t, nil
-			}
-		}
-	}
-	
-	// === STRICT DD/MM/YYYY WITH VALIDATION ===
-	if strings.Contains(dateStr, "/") {
-		const expectedLayout = "02/01/2006" // DD/MM/YYYY
-		
-		t, err := time.Parse(expectedLayout, dateStr)
-		if err != nil {
-			log.Printf("ERROR: Failed to parse date string '%s' with DD/MM/YYYY format: %v", dateStr, err)
-			return time.Time{}, fmt.Errorf("invalid date format for '%s': %w", dateStr, err)
-		}
-
-		// --- CRITICAL VALIDATION STEP ---
-		// Check for sanity: if parsed date is far in future, likely format error
-		futureCutoff := time.Now().AddDate(0, 6, 0) // 6 months from now
-		if t.After(futureCutoff) {
-			log.Printf("🚨 HIGH WARNING: Parsed date '%s' (%s) is too far in future (cutoff: %s). REJECTING!", 
-				t.Format("2006-01-02"), dateStr, futureCutoff.Format("2006-01-02"))
-			return time.Time{}, fmt.Errorf("date '%s' is too far in future (beyond %s)", dateStr, futureCutoff.Format("2006-01-02"))
-		}
-
-		// Check if date is suspiciously in December 2025
-		if t.Year() == 2025 && t.Month() == time.December {
-			log.Printf("🔥 DECEMBER 2025 ALERT: Date '%s' parsed as December 2025 - REJECTING!", dateStr)
-			return time.Time{}, fmt.Errorf("december 2025 dates are not allowed: %s", dateStr)
-		}
-
-		log.Printf("✅ Parsed date '%s' using DD/MM/YYYY -> %s", dateStr, t.Format("2006-01-02"))
-		return t, nil
-	}
-	// === END STRICT DD/MM/YYYY ===
-	
-	// List of other date formats to try (with validation)
-	dateFormats := []string{
-		"2-1-2006",       // D-M-YYYY
-		"02-01-2006",     // DD-MM-YYYY
-		"2006-01-02",     // YYYY-MM-DD (ISO format)
-		"2.1.2006",       // D.M.YYYY
-		"02.01.2006",     // DD.MM.YYYY (German/Turkish format)
-	}
-	
-	for _, format := range dateFormats {
-		if t, err := time.Parse(format, dateStr); err == nil {
-			// Apply same validation to all date formats
-			futureCutoff := time.Now().AddDate(0, 6, 0) // 6 months from now
-			if t.After(futureCutoff) {
-				log.Printf("🚨 WARNING: Date '%s' is too far in future, rejecting", dateStr)
-				continue // Try next format
-			}
-			
-			if t.Year() == 2025 && t.Month() == time.December {
-				log.Printf("🔥 DECEMBER 2025 ALERT: Rejecting December 2025 date '%s'", dateStr)
-				continue // Try next format
-			}
-			
-			log.Printf("✅ Parsed date '%s' using format '%s' -> %s", dateStr, format, t.Format("2006-01-02"))
-			return t, nil
-		}
-	}
-
-	return time.Time{}, fmt.Errorf("invalid date format: %s (supported formats: DD/MM/YYYY, DD/MonthName/YYYY, or DD Month YYYY)", dateStr)
-}
-
-// parseTurkishDate parses dates with Turkish month names like "02/ocak/2025"
-func parseTurkishDate(dateStr string) (time.Time, error) {
-	// Turkish month names mapping
-	turkishMonths := map[string]int{
-		"ocak":     1,  // January
-		"şubat":    2,  // February  
-		"mart":     3,  // March
-		"nisan":    4,  // April
-		"mayıs":    5,  // May
-		"haziran":  6,  // June
-		"temmuz":   7,  // July
-		"ağustos":  8,  // August
-		"eylül":    9,  // September
-		"ekim":     10, // October
-		"kasım":    11, // November
-		"aralık":   12, // December
-	}
-	
-	// Split by "/" - expect format: DD/MonthName/YYYY
-	parts := strings.Split(dateStr, "/")
-	if len(parts) != 3 {
-		return time.Time{}, fmt.Errorf("turkish date must be in DD/MonthName/YYYY format")
-	}
-	
-	// Parse day
-	day, err := strconv.Atoi(strings.TrimSpace(parts[0]))
-	if err != nil {
-		return time.Time{}, fmt.Errorf("invalid day: %s", parts[0])
-	}
-	
-	// Parse Turkish month name
-	monthStr := strings.ToLower(strings.TrimSpace(parts[1]))
-	month, exists := turkishMonths[monthStr]
-	if !exists {
-		return time.Time{}, fmt.Errorf("unknown Turkish month: %s", parts[1])
-	}
-	
-	// Parse year
-	year, err := strconv.Atoi(strings.TrimSpace(parts[2]))
-	if err != nil {
-		return time.Time{}, fmt.Errorf("invalid year: %s", parts[2])
-	}
-	
-	// Create the time
-	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
-	
-	log.Printf("Parsed Turkish date: %s -> %s (day=%d, month=%s(%d), year=%d)", 
-		dateStr, t.Format("2006-01-02"), day, parts[1], month, year)
-	
-	return t, nil
-}
-
-// isNumericDate checks if a date string contains only numbers and separators
-func isNumericDate(dateStr string) bool {
-	for _, char := range dateStr {
-		if char != '/' && char != '-' && char != '.' && (char < '0' || char > '9') {
-			return false
-		}
-	}
-	return true
-}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"tahsilat-raporu/models"
+)
+
+// DefaultProjects returns the projects used until others are saved. "3. Etap"
+// is sold under MSM until the phase gets a project of its own.
+func DefaultProjects() []models.Project {
+	return []models.Project{
+		{Code: "MKM", Name: "Model Kuyum Merkezi", Patterns: []string{"model kuyum", "kuyum merkezi", "mkm"}, SortOrder: 10},
+		{Code: "MSM", Name: "Model Sanayi Merkezi", Patterns: []string{"model sanayi", "sanayi merkezi", "msm", "3. etap"}, SortOrder: 20},
+	}
+}
+
+// NormalizeProjectCode returns the form project codes are stored in
+func NormalizeProjectCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ValidateProject checks that a project has a code other than the fallback
+// project, a name and only non-empty patterns
+func ValidateProject(project models.Project) error {
+	code := NormalizeProjectCode(project.Code)
+	if code == "" {
+		return fmt.Errorf("code must not be empty")
+	}
+	if code == ClassificationFallbacks[models.RuleFieldProject] {
+		return fmt.Errorf("code %s is reserved for payments without a project", code)
+	}
+	if strings.TrimSpace(project.Name) == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	for _, pattern := range project.Patterns {
+		if foldText(pattern) == "" {
+			return fmt.Errorf("patterns must not be empty")
+		}
+	}
+	return nil
+}
+
+// SortProjects orders projects by sort order and then by code
+func SortProjects(projects []models.Project) {
+	sort.SliceStable(projects, func(i, j int) bool {
+		if projects[i].SortOrder != projects[j].SortOrder {
+			return projects[i].SortOrder < projects[j].SortOrder
+		}
+		return projects[i].Code < projects[j].Code
+	})
+}
+
+// ReportProjects returns the projects a report shows: the configured ones in
+// order, then any other project code with a total, e.g. of a deleted project
+func ReportProjects(projects []models.Project, totals models.ProjectTotal) []models.Project {
+	listed := append([]models.Project{}, projects...)
+	SortProjects(listed)
+
+	known := make(map[string]bool)
+	for _, project := range listed {
+		known[project.Code] = true
+	}
+	var others []string
+	for code := range totals {
+		if !known[code] {
+			others = append(others, code)
+		}
+	}
+	sort.Strings(others)
+	for _, code := range others {
+		listed = append(listed, models.Project{Code: code, Name: code})
+	}
+	return listed
+}
+
+// projectRules turns the patterns of the projects into contains rules on Proje
+// Adı, in project order. They have no ID, so payments do not record them.
+func projectRules(projects []models.Project) []models.ClassificationRule {
+	sorted := append([]models.Project{}, projects...)
+	SortProjects(sorted)
+
+	var rules []models.ClassificationRule
+	for _, project := range sorted {
+		for _, pattern := range project.Patterns {
+			rules = append(rules, models.ClassificationRule{
+				Field:     models.RuleFieldProject,
+				Source:    models.FieldProjeAdi,
+				MatchType: models.RuleMatchContains,
+				Pattern:   pattern,
+				Value:     project.Code,
+				Enabled:   true,
+				Note:      "Pattern of project " + project.Code,
+			})
+		}
+	}
+	return rules
+}
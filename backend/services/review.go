@@ -0,0 +1,102 @@
+package services
+
+import (
+	"strings"
+	"tahsilat-raporu/models"
+)
+
+// Review reason codes, one for each classified field
+const (
+	ReviewCodeUnknownPaymentMethod = "unknown_payment_method"
+	ReviewCodeUnknownLocation      = "unknown_location"
+	ReviewCodeUnknownProject       = "unknown_project"
+)
+
+// fallbackReviews holds, for each classified field, the review reason code,
+// the input column shown with it and its Turkish and English message
+var fallbackReviews = map[string]struct {
+	code     string
+	source   string
+	messages [2]string
+}{
+	models.RuleFieldPaymentMethod: {ReviewCodeUnknownPaymentMethod, models.FieldTahsilatSekli,
+		[2]string{"Tahsilat şekli tanınmadı, Nakit sayıldı", "Payment method was not recognised and was taken as cash"}},
+	models.RuleFieldLocation: {ReviewCodeUnknownLocation, models.FieldHesapAdi,
+		[2]string{"Lokasyon tanınmadı, DİĞER altında raporlandı", "Location was not recognised and is reported under DİĞER"}},
+	models.RuleFieldProject: {ReviewCodeUnknownProject, models.FieldProjeAdi,
+		[2]string{"Proje tanımlanamadı", "Project could not be identified"}},
+}
+
+// FallbackReviewReason explains why a field that no rule matched needs review
+func FallbackReviewReason(field string, raw models.RawPaymentData) models.ReviewReason {
+	review := fallbackReviews[field]
+	reason := models.ReviewReason{
+		Field:     field,
+		Column:    models.RawPaymentFieldHeaders[review.source],
+		Fallback:  ClassificationFallbacks[field],
+		Code:      review.code,
+		Message:   review.messages[0],
+		MessageEN: review.messages[1],
+	}
+	if source, ok := ruleSources[review.source]; ok {
+		reason.Value = strings.TrimSpace(source(raw))
+	}
+	return reason
+}
+
+// StoredReviewReasons lists the fields of a stored payment that hold their
+// fallback value because no rule of the rule set matches its input row
+func StoredReviewReasons(rules *RuleSet, payment models.PaymentRecord, raw models.RawPaymentData) []models.ReviewReason {
+	var reasons []models.ReviewReason
+	for _, field := range []struct {
+		name, value string
+	}{
+		{models.RuleFieldPaymentMethod, payment.PaymentMethod},
+		{models.RuleFieldLocation, payment.Location},
+		{models.RuleFieldProject, payment.Project},
+	} {
+		if field.value != ClassificationFallbacks[field.name] {
+			continue
+		}
+		if _, rule := rules.Classify(field.name, raw); rule == nil {
+			reasons = append(reasons, FallbackReviewReason(field.name, raw))
+		}
+	}
+	return reasons
+}
+
+// ReviewStatusFor returns the review status of a newly classified payment
+func ReviewStatusFor(reasons []models.ReviewReason) string {
+	if len(reasons) == 0 {
+		return ""
+	}
+	return models.ReviewStatusPending
+}
+
+// KeepReviewedClassification gives a reprocessed payment the classification
+// and review of the stored payment. A field that now differs from what the
+// rules give no longer records a rule.
+func KeepReviewedClassification(stored models.PaymentRecord, updated *models.PaymentRecord) {
+	for _, field := range []struct {
+		name    string
+		value   string
+		updated *string
+	}{
+		{models.RuleFieldPaymentMethod, stored.PaymentMethod, &updated.PaymentMethod},
+		{models.RuleFieldLocation, stored.Location, &updated.Location},
+		{models.RuleFieldProject, stored.Project, &updated.Project},
+	} {
+		if *field.updated != field.value {
+			*field.updated = field.value
+			delete(updated.MatchedRules, field.name)
+		}
+	}
+	updated.ReviewStatus = stored.ReviewStatus
+	updated.ReviewReasons = stored.ReviewReasons
+}
+
+// IsReviewed reports whether an operator has confirmed or corrected a
+// payment's classification, which reprocessing then keeps
+func IsReviewed(status string) bool {
+	return status == models.ReviewStatusConfirmed || status == models.ReviewStatusCorrected
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"fmt"
+	"tahsilat-raporu/models"
+)
+
+// Error codes used in UploadResponse.Errors
+const (
+	ErrCodeInvalidRequest    = "invalid_request"
+	ErrCodeFileError         = "file_error"
+	ErrCodeInvalidAmount     = "invalid_amount"
+	ErrCodeAmbiguousAmount   = "ambiguous_amount"
+	ErrCodeInvalidDate       = "invalid_date"
+	ErrCodeAmbiguousDate     = "ambiguous_date"
+	ErrCodeMissingCustomer   = "missing_customer"
+	ErrCodeNonPositiveAmount = "non_positive_amount"
+	ErrCodeInvalidCurrency   = "invalid_currency"
+	ErrCodeUnknownProject    = "unknown_project"
+	ErrCodeFutureDate        = "future_date"
+	ErrCodeOldDate           = "old_date"
+	ErrCodeDateOutOfRange    = "date_out_of_range"
+	ErrCodeBlockedPeriod     = "blocked_period"
+	ErrCodeConversionFailed  = "conversion_failed"
+	ErrCodeNoGoldPrice       = "no_gold_price"
+	ErrCodeInvalidKind       = "invalid_kind"
+	ErrCodeInvalidOriginal   = "invalid_original_payment"
+	ErrCodeProcessingFailed  = "processing_failed"
+	ErrCodeDatabase          = "database_error"
+)
+
+// rowErrorMessages holds the Turkish and English message for each error code
+var rowErrorMessages = map[string][2]string{
+	ErrCodeInvalidRequest:    {"Geçersiz istek", "Invalid request"},
+	ErrCodeFileError:         {"Dosya okunamadı", "File could not be read"},
+	ErrCodeInvalidAmount:     {"Geçersiz tutar", "Invalid amount"},
+	ErrCodeAmbiguousAmount:   {"Tutarda binlik ve ondalık ayırıcı belirsiz", "Thousands and decimal separators of the amount are ambiguous"},
+	ErrCodeInvalidDate:       {"Geçersiz tarih", "Invalid date"},
+	ErrCodeAmbiguousDate:     {"Tarih sütununda gün/ay sırası belirsiz", "Day/month order of the date column is ambiguous"},
+	ErrCodeMissingCustomer:   {"Müşteri adı boş olamaz", "Customer name is required"},
+	ErrCodeNonPositiveAmount: {"Ödenen tutar sıfırdan büyük olmalı (iadeler için İşlem Türü kullanın)", "Amount must be greater than zero (use the payment kind for refunds)"},
+	ErrCodeInvalidCurrency:   {"Geçersiz para birimi", "Invalid currency"},
+	ErrCodeUnknownProject:    {"Proje tanımlanamadı", "Project could not be identified"},
+	ErrCodeFutureDate:        {"Çok ileri tarihli ödeme", "Payment date is too far in the future"},
+	ErrCodeOldDate:           {"Çok eski tarihli ödeme", "Payment date is too far in the past"},
+	ErrCodeDateOutOfRange:    {"Tarih izin verilen aralığın dışında", "Payment date is outside the allowed range"},
+	ErrCodeBlockedPeriod:     {"Tarih kapalı bir döneme denk geliyor", "Payment date falls in a blocked period"},
+	ErrCodeConversionFailed:  {"Kur dönüşümü yapılamadı", "Currency conversion failed"},
+	ErrCodeNoGoldPrice:       {"Bu tarih için altın fiyatı girilmemiş", "No gold price has been entered for this date"},
+	ErrCodeInvalidKind:       {"Geçersiz işlem türü", "Invalid payment kind"},
+	ErrCodeInvalidOriginal:   {"Orijinal ödeme geçersiz", "Invalid original payment"},
+	ErrCodeProcessingFailed:  {"Satır işlenemedi", "Row could not be processed"},
+	ErrCodeDatabase:          {"Veritabanı hatası", "Database error"},
+}
+
+// NewRowError builds a RowError with the messages for code. field is a
+// canonical RawPaymentData field (or "") and cause, if not nil, becomes the detail.
+func NewRowError(row int, field, value, code string, cause error) models.RowError {
+	messages, ok := rowErrorMessages[code]
+	if !ok {
+		messages = [2]string{code, code}
+	}
+
+	rowError := models.RowError{
+		Row:       row,
+		Column:    models.RawPaymentFieldHeaders[field],
+		Value:     value,
+		Code:      code,
+		Message:   messages[0],
+		MessageEN: messages[1],
+	}
+	if cause != nil {
+		rowError.Detail = cause.Error()
+	}
+	return rowError
+}
+
+// FormatRowError renders a RowError as a single log line
+func FormatRowError(rowError models.RowError) string {
+	text := rowError.Message
+	if rowError.Value != "" {
+		text += fmt.Sprintf(" '%s'", rowError.Value)
+	}
+	if rowError.Detail != "" {
+		text += ": " + rowError.Detail
+	}
+	if rowError.Row > 0 {
+		return fmt.Sprintf("Satır %d (%s): %s", rowError.Row, rowError.Customer, text)
+	}
+	return text
+}
+
+// fieldError is returned by Process so that ProcessBatch can tell which field failed
+type fieldError struct {
+	field string
+	value string
+	code  string
+	cause error
+}
+
+func (e *fieldError) Error() string {
+	return e.cause.Error()
+}
+
+// RawPaymentValues returns the fields of a raw payment as cell text, with the
+// amount as it was typed when the source sent it as text
+func RawPaymentValues(raw models.RawPaymentData) map[string]string {
+	amount := raw.OdenenTutar.String()
+	if raw.OdenenTutarText != "" {
+		amount = raw.OdenenTutarText
+	}
+	return map[string]string{
+		models.FieldMusteriAdiSoyadi: raw.MusteriAdiSoyadi,
+		models.FieldTarih:            raw.Tarih,
+		models.FieldTahsilatSekli:    raw.TahsilatSekli,
+		models.FieldHesapAdi:         raw.HesapAdi,
+		models.FieldOdenenTutar:      amount,
+		models.FieldOdenenDoviz:      raw.OdenenDoviz,
+		models.FieldProjeAdi:         raw.ProjeAdi,
+		models.FieldIslemTuru:        raw.IslemTuru,
+		models.FieldOrijinalOdemeNo:  raw.OrijinalOdemeNo,
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"encoding/json"
+	"sort"
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/money"
+)
+
+// SimulateClassification classifies the stored payments again with a proposed
+// rule set and compares the result with what is stored. Only payment method,
+// location and project are re-derived; dates and USD amounts stay as stored.
+func SimulateClassification(rules *RuleSet, payments []models.PaymentRecord) models.ClassificationSimulation {
+	simulation := models.ClassificationSimulation{
+		Matched:       len(payments),
+		Changes:       []models.ReprocessChange{},
+		WeeklyShifts:  []models.TotalShift{},
+		MonthlyShifts: []models.TotalShift{},
+	}
+
+	simulated := make([]models.PaymentRecord, len(payments))
+	for i, payment := range payments {
+		simulated[i] = payment
+
+		// Reprocessing keeps classifications an operator reviewed
+		if IsReviewed(payment.ReviewStatus) {
+			continue
+		}
+
+		var rawData models.PaymentRawData
+		if err := json.Unmarshal([]byte(payment.RawData), &rawData); err != nil || rawData.Original == (models.RawPaymentData{}) {
+			simulation.SkippedLegacy++
+			continue
+		}
+		raw := rawData.Original
+
+		var changes []models.FieldChange
+		for _, field := range []struct {
+			name  string
+			value *string
+		}{
+			{models.RuleFieldPaymentMethod, &simulated[i].PaymentMethod},
+			{models.RuleFieldLocation, &simulated[i].Location},
+			{models.RuleFieldProject, &simulated[i].Project},
+		} {
+			value, _ := rules.Classify(field.name, raw)
+			if value != *field.value {
+				changes = append(changes, models.FieldChange{Field: field.name, Old: *field.value, New: value})
+				*field.value = value
+			}
+		}
+		if len(changes) > 0 {
+			simulation.Changes = append(simulation.Changes, models.ReprocessChange{
+				PaymentID:    payment.ID,
+				CustomerName: payment.CustomerName,
+				PaymentDate:  payment.PaymentDate,
+				Changes:      changes,
+			})
+		}
+	}
+	simulation.Changed = len(simulation.Changes)
+	if simulation.Changed == 0 {
+		return simulation
+	}
+
+	before, after := make(map[string]map[string]money.Amount), make(map[string]map[string]money.Amount)
+	for _, report := range GenerateWeeklyReports(payments) {
+		before[weekPeriod(report)] = weeklyReportTotals(report)
+	}
+	for _, report := range GenerateWeeklyReports(simulated) {
+		after[weekPeriod(report)] = weeklyReportTotals(report)
+	}
+	simulation.WeeklyShifts = totalShifts(before, after)
+
+	before, after = make(map[string]map[string]money.Amount), make(map[string]map[string]money.Amount)
+	for _, report := range GenerateMonthlyReports(payments) {
+		before[report.Month.Format("2006-01")] = monthlyReportTotals(report)
+	}
+	for _, report := range GenerateMonthlyReports(simulated) {
+		after[report.Month.Format("2006-01")] = monthlyReportTotals(report)
+	}
+	simulation.MonthlyShifts = totalShifts(before, after)
+
+	return simulation
+}
+
+// weekPeriod names the days a weekly report covers
+func weekPeriod(report models.WeeklyReport) string {
+	return report.StartDate.Format("2006-01-02") + " - " + report.EndDate.Format("2006-01-02")
+}
+
+// weeklyReportTotals lists the USD totals of a weekly report that depend on
+// classification, keyed by their JSON path
+func weeklyReportTotals(report models.WeeklyReport) map[string]money.Amount {
+	totals := make(map[string]money.Amount)
+	addMethodTotals(totals, "payment_methods", report.PaymentMethods)
+	addProjectAndLocationTotals(totals, report.ProjectSummary, report.LocationSummary)
+	return totals
+}
+
+// monthlyReportTotals lists the USD totals of a monthly report that depend on
+// classification, keyed by their JSON path
+func monthlyReportTotals(report models.MonthlyReport) map[string]money.Amount {
+	totals := make(map[string]money.Amount)
+	addMethodTotals(totals, "payment_methods", report.PaymentMethods)
+	for project, methods := range report.ProjectPaymentMethods {
+		addMethodTotals(totals, "project_payment_methods."+project, methods)
+	}
+	addProjectAndLocationTotals(totals, report.ProjectSummary, report.LocationSummary)
+	return totals
+}
+
+// addMethodTotals adds the USD total of each payment method under prefix
+func addMethodTotals(totals map[string]money.Amount, prefix string, methods map[string]models.PaymentMethodTotal) {
+	for method, total := range methods {
+		totals[prefix+"."+method] = total.TotalUSD
+	}
+}
+
+// addProjectAndLocationTotals adds the project totals and the per-project
+// totals of each location
+func addProjectAndLocationTotals(totals map[string]money.Amount, projects models.ProjectTotal, locations map[string]models.LocationTotal) {
+	for project, total := range projects {
+		totals["project_summary."+project] = total
+	}
+	for location, total := range locations {
+		for project, amount := range total.Projects {
+			totals["location_summary."+location+".projects."+project] = amount
+		}
+		totals["location_summary."+location+".total"] = total.Total
+	}
+}
+
+// totalShifts compares the totals of each period and returns those that
+// differ, by period and then by total
+func totalShifts(before, after map[string]map[string]money.Amount) []models.TotalShift {
+	shifts := []models.TotalShift{}
+	for period, afterTotals := range after {
+		beforeTotals := before[period]
+		for total, amount := range afterTotals {
+			if amount != beforeTotals[total] {
+				shifts = append(shifts, models.TotalShift{Period: period, Total: total, Before: beforeTotals[total], After: amount, Delta: amount - beforeTotals[total]})
+			}
+		}
+		// Totals that only exist before the change drop to zero
+		for total, amount := range beforeTotals {
+			if _, ok := afterTotals[total]; !ok && amount != 0 {
+				shifts = append(shifts, models.TotalShift{Period: period, Total: total, Before: amount, Delta: -amount})
+			}
+		}
+	}
+	sort.Slice(shifts, func(i, j int) bool {
+		if shifts[i].Period != shifts[j].Period {
+			return shifts[i].Period < shifts[j].Period
+		}
+		return shifts[i].Total < shifts[j].Total
+	})
+	return shifts
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/money"
+)
+
+func TestTotalShifts(t *testing.T) {
+	type totals = map[string]map[string]money.Amount
+	tests := []struct {
+		name          string
+		before, after totals
+		want          []models.TotalShift
+	}{
+		{
+			name:   "unchanged",
+			before: totals{"2025-01": {"payment_methods.Nakit": 10000}},
+			after:  totals{"2025-01": {"payment_methods.Nakit": 10000}},
+			want:   []models.TotalShift{},
+		},
+		{
+			name:   "moved between totals",
+			before: totals{"2025-01": {"payment_methods.Nakit": 10000, "payment_methods.Çek": 0}},
+			after:  totals{"2025-01": {"payment_methods.Nakit": 2500, "payment_methods.Çek": 7500}},
+			want: []models.TotalShift{
+				{Period: "2025-01", Total: "payment_methods.Nakit", Before: 10000, After: 2500, Delta: -7500},
+				{Period: "2025-01", Total: "payment_methods.Çek", Before: 0, After: 7500, Delta: 7500},
+			},
+		},
+		{
+			name:   "total only after the change",
+			before: totals{"2025-01": {"location_summary.OFIS.total": 5000}},
+			after:  totals{"2025-01": {"location_summary.OFIS.total": 3000, "location_summary.CARSI.total": 2000}},
+			want: []models.TotalShift{
+				{Period: "2025-01", Total: "location_summary.CARSI.total", After: 2000, Delta: 2000},
+				{Period: "2025-01", Total: "location_summary.OFIS.total", Before: 5000, After: 3000, Delta: -2000},
+			},
+		},
+		{
+			name:   "total only before the change drops to zero",
+			before: totals{"2025-01": {"project_summary.MKM": 4000, "project_summary.UNKNOWN": 1000, "project_summary.MSM": 0}},
+			after:  totals{"2025-01": {"project_summary.MKM": 5000}},
+			want: []models.TotalShift{
+				{Period: "2025-01", Total: "project_summary.MKM", Before: 4000, After: 5000, Delta: 1000},
+				{Period: "2025-01", Total: "project_summary.UNKNOWN", Before: 1000, Delta: -1000},
+			},
+		},
+		{
+			name: "ordered by period, then total",
+			before: totals{
+				"2025-02": {"b": 100, "a": 100},
+				"2025-01": {"a": 100},
+			},
+			after: totals{
+				"2025-02": {"b": 200, "a": 50},
+				"2025-01": {"a": 300},
+			},
+			want: []models.TotalShift{
+				{Period: "2025-01", Total: "a", Before: 100, After: 300, Delta: 200},
+				{Period: "2025-02", Total: "a", Before: 100, After: 50, Delta: -50},
+				{Period: "2025-02", Total: "b", Before: 100, After: 200, Delta: 100},
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := totalShifts(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: totalShifts = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
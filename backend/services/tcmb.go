@@ -2,17 +2,88 @@ package services
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"tahsilat-raporu/models"
+	"tahsilat-raporu/money"
 	"time"
 )
 
-// Currency represents a single currency from TCMB XML
+// Currency represents a single currency from TCMB XML. The rate is kept as
+// text so that it can be parsed exactly. Unit is the number of currency units
+// the rate is quoted for, e.g. 100 for JPY.
 type Currency struct {
-	CurrencyCode string  `xml:"CurrencyCode,attr"`
-	ForexSelling float64 `xml:"ForexSelling"`
+	CurrencyCode string `xml:"CurrencyCode,attr"`
+	Unit         string `xml:"Unit"`
+	ForexSelling string `xml:"ForexSelling"`
+}
+
+// ErrCurrencyNotPublished is returned for a currency that is not in the TCMB rates
+var ErrCurrencyNotPublished = errors.New("currency not published by TCMB")
+
+// currencyAliases maps other names of a currency to the code it is stored with
+var currencyAliases = map[string]string{
+	"TRY": models.CurrencyTL,
+	"YTL": models.CurrencyTL,
+	"₺":   models.CurrencyTL,
+	"$":   models.CurrencyUSD,
+	"€":   models.CurrencyEUR,
+	"£":   "GBP",
+}
+
+// tcmbCurrencies are the currencies in the TCMB daily rates, so a gold unit
+// must not be named like one of them
+var tcmbCurrencies = []string{
+	"USD", "AUD", "DKK", "EUR", "GBP", "CHF", "SEK", "CAD", "KWD", "NOK", "SAR", "JPY",
+	"BGN", "RON", "RUB", "IRR", "CNY", "PKR", "QAR", "KRW", "AZN", "AED", "XDR",
+}
+
+// IsPublishedCurrency reports whether an "Ödenen Döviz" value names TL or a
+// currency TCMB publishes, under its code or an alias such as "$"
+func IsPublishedCurrency(value string) bool {
+	code := NormalizeCurrency(value)
+	if code == models.CurrencyTL {
+		return true
+	}
+	for _, published := range tcmbCurrencies {
+		if code == published {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeCurrency returns the code an "Ödenen Döviz" value is stored with,
+// e.g. "try" becomes "TL"
+func NormalizeCurrency(value string) string {
+	code := strings.ToUpper(strings.TrimSpace(value))
+	if alias, ok := currencyAliases[code]; ok {
+		return alias
+	}
+	return code
+}
+
+// IsCurrencyCode reports whether code can be a currency TCMB publishes: TL or
+// a three letter ISO 4217 code. Whether TCMB really has it is only known once
+// its rate is fetched.
+func IsCurrencyCode(code string) bool {
+	if code == models.CurrencyTL {
+		return true
+	}
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
 }
 
 // TarihDate represents the XML structure from TCMB
@@ -21,15 +92,15 @@ type TarihDate struct {
 }
 
 var (
-	rateCache = make(map[string]float64)
+	rateCache = make(map[string]money.Rate)
 	cacheMux  sync.RWMutex
 )
 
 // GetExchangeRate fetches exchange rate from TCMB for a given date and currency
-func GetExchangeRate(paymentDate time.Time, currency string) (float64, error) {
+func GetExchangeRate(paymentDate time.Time, currency string) (money.Rate, error) {
 	// TL doesn't need conversion
 	if currency == "TL" {
-		return 1.0, nil
+		return money.OneRate, nil
 	}
 
 	// If the payment date is in the future, use the latest available rate (today or last business day)
@@ -53,6 +124,10 @@ func GetExchangeRate(paymentDate time.Time, currency string) (float64, error) {
 
 	// Try to fetch rate from TCMB
 	rate, err := fetchTCMBRate(targetDate, currency)
+	if errors.Is(err, ErrCurrencyNotPublished) {
+		// Earlier days will not have it either
+		return 0, err
+	}
 	if err != nil {
 		// If failed, try going back more days (up to 30 days to handle holidays)
 		rate, err = tryPreviousDays(targetDate, currency, 30)
@@ -70,7 +145,7 @@ func GetExchangeRate(paymentDate time.Time, currency string) (float64, error) {
 }
 
 // fetchTCMBRate fetches rate from TCMB API for a specific date
-func fetchTCMBRate(date time.Time, currency string) (float64, error) {
+func fetchTCMBRate(date time.Time, currency string) (money.Rate, error) {
 	url := fmt.Sprintf("https://www.tcmb.gov.tr/kurlar/%s/%s.xml",
 		date.Format("200601"),
 		date.Format("02012006"))
@@ -98,14 +173,23 @@ func fetchTCMBRate(date time.Time, currency string) (float64, error) {
 	// Find the requested currency
 	for _, curr := range data.Currency {
 		if curr.CurrencyCode == currency {
-			if curr.ForexSelling == 0 {
+			rate, err := money.ParseRate(strings.TrimSpace(curr.ForexSelling))
+			if err != nil || rate <= 0 {
 				return 0, fmt.Errorf("no forex selling rate available for %s", currency)
 			}
-			return curr.ForexSelling, nil
+			// Some currencies are quoted per 100 or more units
+			if unit, err := strconv.ParseInt(strings.TrimSpace(curr.Unit), 10, 64); err == nil && unit > 1 {
+				return rate.Div(unit)
+			}
+			return rate, nil
 		}
 	}
 
-	return 0, fmt.Errorf("currency %s not found in TCMB data", currency)
+	// An empty list means the file is not a rate list, not that the currency is unknown
+	if len(data.Currency) == 0 {
+		return 0, fmt.Errorf("no currencies in TCMB data for %s", date.Format("2006-01-02"))
+	}
+	return 0, fmt.Errorf("%w: %s", ErrCurrencyNotPublished, currency)
 }
 
 // getLatestBusinessDay returns the current date if it's a business day, otherwise the previous business day
@@ -132,7 +216,7 @@ func getPreviousBusinessDay(date time.Time) time.Time {
 }
 
 // tryPreviousDays tries to fetch rate by going back multiple days
-func tryPreviousDays(startDate time.Time, currency string, maxDays int) (float64, error) {
+func tryPreviousDays(startDate time.Time, currency string, maxDays int) (money.Rate, error) {
 	for i := 0; i < maxDays; i++ {
 		date := startDate.AddDate(0, 0, -i)
 		// Skip weekends
@@ -153,33 +237,37 @@ func tryPreviousDays(startDate time.Time, currency string, maxDays int) (float64
 }
 
 // ConvertToUSD converts any currency amount to USD
-func ConvertToUSD(amount float64, currency string, paymentDate time.Time) (float64, float64, error) {
+func ConvertToUSD(amount money.Amount, currency string, paymentDate time.Time) (money.Amount, money.Rate, error) {
 	if currency == "USD" {
-		return amount, 1.0, nil
+		return amount, money.OneRate, nil
 	}
 
-	rate, err := GetExchangeRate(paymentDate, currency)
+	usdRate, err := GetExchangeRate(paymentDate, "USD")
 	if err != nil {
 		return 0, 0, err
 	}
 
-	var amountUSD float64
 	if currency == "TL" {
-		// TL to USD: divide by TL/USD rate
-		amountUSD = amount / rate
-	} else if currency == "EUR" {
-		// EUR to USD: need both EUR/TL and USD/TL rates
-		eurRate, err := GetExchangeRate(paymentDate, "EUR")
+		// TL to USD: divide by USD/TL rate
+		amountUSD, err := amount.ConvertInverse(usdRate)
 		if err != nil {
 			return 0, 0, err
 		}
-		usdRate, err := GetExchangeRate(paymentDate, "USD")
-		if err != nil {
-			return 0, 0, err
-		}
-		// EUR amount * (EUR/TL rate) / (USD/TL rate) = USD amount
-		amountUSD = (amount * eurRate) / usdRate
-		rate = eurRate / usdRate // Store the effective rate
+		return amountUSD, usdRate, nil
+	}
+
+	// Other currencies go through TL: amount * (currency/TL rate) / (USD/TL rate)
+	rate, err := GetExchangeRate(paymentDate, currency)
+	if err != nil {
+		return 0, 0, err
+	}
+	amountUSD, err := amount.ConvertCross(rate, usdRate)
+	if err != nil {
+		return 0, 0, err
+	}
+	rate, err = rate.Cross(usdRate) // Store the effective rate
+	if err != nil {
+		return 0, 0, err
 	}
 
 	return amountUSD, rate, nil
@@ -189,7 +277,7 @@ func ConvertToUSD(amount float64, currency string, paymentDate time.Time) (float
 func ClearCache() {
 	cacheMux.Lock()
 	defer cacheMux.Unlock()
-	rateCache = make(map[string]float64)
+	rateCache = make(map[string]money.Rate)
 }
 
 // GetCacheSize returns the current cache size
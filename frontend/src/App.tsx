@@ -8,7 +8,7 @@ import { Login } from './components/Login';
 import YearlyReportComponent from './components/YearlyReport';
 import { paymentAPI, authAPI, setAuthCredentials, clearAuthCredentials, isAuthenticated } from './services/api';
 import api from './services/api';
-import { WeeklyReport as WeeklyReportType, MonthlyReport as MonthlyReportType, UploadResponse, YearlyReport, PaymentRecord } from './types/payment.types';
+import { WeeklyReport as WeeklyReportType, MonthlyReport as MonthlyReportType, UploadResponse, YearlyReport, PaymentRecord, Project, Location } from './types/payment.types';
 
 function App() {
   // Authentication states
@@ -20,6 +20,8 @@ function App() {
   // Existing states
   const [weeklyReports, setWeeklyReports] = useState<WeeklyReportType[]>([]);
   const [monthlyReports, setMonthlyReports] = useState<MonthlyReportType[]>([]);
+  const [projects, setProjects] = useState<Project[]>([]);
+  const [locations, setLocations] = useState<Location[]>([]);
   const [allPayments, setAllPayments] = useState<PaymentRecord[]>([]);
   const [yearlyReport, setYearlyReport] = useState<YearlyReport | null>(null);
   const [isLoading, setIsLoading] = useState(false);
@@ -218,6 +220,8 @@ function App() {
       console.log('Reports response:', response);
       setWeeklyReports(response.weekly_reports || []);
       setMonthlyReports(response.monthly_reports || []);
+      setProjects(response.projects || []);
+      setLocations(response.locations || []);
       
       // Also load all payments for check payment details
       const paymentsResponse = await paymentAPI.getPayments();
@@ -654,6 +658,8 @@ function App() {
                           report={report}
                           weekNumber={index + 1}
                           allPayments={allPayments}
+                          projects={projects}
+                          locations={locations}
                         />
                       ))
                     )}
@@ -677,6 +683,8 @@ function App() {
                           key={report.month}
                           report={report}
                           allPayments={allPayments}
+                          projects={projects}
+                          locations={locations}
                         />
                       ))
                     )}
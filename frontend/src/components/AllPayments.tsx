@@ -588,6 +588,11 @@ export const AllPayments: React.FC<AllPaymentsProps> = () => {
                     </td>
                     <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
                       {payment.payment_method}
+                      {payment.review_status === 'pending' && (
+                        <span className="ml-2 inline-flex px-2 py-1 text-xs font-semibold rounded-full bg-amber-100 text-amber-800" title="Sınıflandırma onay bekliyor">
+                          Onay bekliyor
+                        </span>
+                      )}
                     </td>
                     <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900">
                       <span className={`inline-flex px-2 py-1 text-xs font-semibold rounded-full ${
@@ -1,7 +1,7 @@
 import React, { useState, useCallback } from 'react';
 import { ExcelParser } from '../services/excelParser';
 import { paymentAPI } from '../services/api';
-import { RawPaymentData, UploadResponse } from '../types/payment.types';
+import { RawPaymentData, UploadResponse, formatRowError } from '../types/payment.types';
 import { formatFileSize } from '../utils/formatters';
 
 interface FileUploadProps {
@@ -53,7 +53,12 @@ export const FileUpload: React.FC<FileUploadProps> = ({ onUploadSuccess, onUploa
         // Show detailed success message
         let successMessage = response.message;
         if (response.errors && response.errors.length > 0) {
-          successMessage += `\n\nWarnings/Errors encountered:\n${response.errors.join('\n')}`;
+          successMessage += `\n\nWarnings/Errors encountered:\n${response.errors.map(formatRowError).join('\n')}`;
+        }
+        if (response.customer_suggestions && response.customer_suggestions.length > 0) {
+          successMessage += `\n\nBenzer müşteri adları (birleştirilebilir):\n${response.customer_suggestions
+            .map((s) => `${s.customer_name} → ${s.suggested_name}`)
+            .join('\n')}`;
         }
         onUploadSuccess({
           ...response,
@@ -62,7 +67,7 @@ export const FileUpload: React.FC<FileUploadProps> = ({ onUploadSuccess, onUploa
       } else {
         let errorMessage = response.message || 'Upload failed';
         if (response.errors && response.errors.length > 0) {
-          errorMessage += `\n\nDetails:\n${response.errors.join('\n')}`;
+          errorMessage += `\n\nDetails:\n${response.errors.map(formatRowError).join('\n')}`;
         }
         onUploadError(errorMessage);
       }
@@ -216,7 +221,7 @@ export const FileUpload: React.FC<FileUploadProps> = ({ onUploadSuccess, onUploa
           <li>• Tahsilat Şekli</li>
           <li>• Hesap Adı</li>
           <li>• Ödenen Tutar(Σ:...) - Sayısal değer</li>
-          <li>• Ödenen Döviz - TL, USD, veya EUR</li>
+          <li>• Ödenen Döviz - TL, USD, EUR veya TCMB'nin kur yayımladığı diğer dövizler (GBP, CHF, SAR...) ya da altın birimi (gram, çeyrek, yarım, tam...)</li>
           <li>• Proje Adı</li>
         </ul>
       </div>
@@ -1,15 +1,25 @@
 import React from 'react';
-import { MonthlyReport as MonthlyReportType, PaymentRecord } from '../types/payment.types';
-import { formatAmountUSDPlain } from '../utils/formatters';
+import { MonthlyReport as MonthlyReportType, PaymentRecord, PaymentMethodTotal, Project, Location, LocationTotal } from '../types/payment.types';
+import { formatAmountUSDPlain, currencyAmount, reportProjects, reportLocations, sumProjectTotal, formatUnconfirmed } from '../utils/formatters';
 import { formatMonth } from '../utils/dateHelpers';
 
 interface MonthlyReportProps {
   report: MonthlyReportType;
   allPayments: PaymentRecord[];
+  projects?: Project[];
+  locations?: Location[];
 }
 
-export const MonthlyReport: React.FC<MonthlyReportProps> = ({ report, allPayments }) => {
-  const totalProjectUSD = report.project_summary.mkm + report.project_summary.msm;
+const emptyMethod: PaymentMethodTotal = { currencies: {}, total_usd: 0 };
+const emptyLocation: LocationTotal = { projects: {}, total: 0 };
+
+export const MonthlyReport: React.FC<MonthlyReportProps> = ({ report, allPayments, projects, locations }) => {
+  const totalProjectUSD = sumProjectTotal(report.project_summary);
+  const shownProjects = reportProjects(projects, report.project_summary);
+  const shownLocations = reportLocations(locations, report.location_summary);
+  const unconfirmed = formatUnconfirmed(report.unconfirmed);
+  const projectMethods = (code: string) => report.project_payment_methods?.[code] || {};
+  const allProjectMethods = shownProjects.flatMap(project => Object.values(projectMethods(project.code)));
   const totalLocationUSD = Object.values(report.location_summary).reduce((sum, location) => sum + location.total, 0);
   
   // Get the month name in Turkish for dynamic headers
@@ -24,6 +34,9 @@ export const MonthlyReport: React.FC<MonthlyReportProps> = ({ report, allPayment
         <p className="text-gray-600">
           {formatMonth(report.month)}
         </p>
+        {unconfirmed && (
+          <p className="text-sm text-amber-700 mt-1">{unconfirmed}</p>
+        )}
       </div>
 
       {/* Daily USD Collection Table */}
@@ -260,111 +273,60 @@ export const MonthlyReport: React.FC<MonthlyReportProps> = ({ report, allPayment
           Proje Bazında Ödeme Şekli Dağılımı
         </h3>
         <div className="grid grid-cols-1 lg:grid-cols-3 gap-6">
-          {/* MKM Project */}
-          <div>
-            <h4 className="text-md font-medium text-gray-900 mb-3">
-              {monthName} AYI MKM TAHSİLATLAR
-            </h4>
-            <div className="overflow-x-auto">
-              <table className="w-full divide-y divide-gray-200 border border-gray-300">
-                <thead className="bg-gray-50">
-                  <tr>
-                    <th className="px-3 py-2 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                      Ödeme Nedeni
-                    </th>
-                    <th className="px-3 py-2 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                      Toplam TL
-                    </th>
-                    <th className="px-3 py-2 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                      Toplam USD
-                    </th>
-                  </tr>
-                </thead>
-                <tbody className="bg-white divide-y divide-gray-200">
-                  {['Banka Havalesi', 'Nakit', 'Çek'].map((method) => {
-                    const methodData = report.mkm_payment_methods?.[method] || { tl: 0, usd: 0, total_usd: 0 };
-                    return (
-                      <tr key={method} className="hover:bg-gray-50">
-                        <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                          {method}
-                        </td>
-                        <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                          {methodData.tl > 0 ? formatAmountUSDPlain(methodData.tl) : '-'}
-                        </td>
-                        <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                          {methodData.total_usd > 0 ? formatAmountUSDPlain(methodData.total_usd) : '-'}
-                        </td>
-                      </tr>
-                    );
-                  })}
-                  <tr className="bg-gray-100 font-semibold">
-                    <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                      Genel Toplam
-                    </td>
-                    <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                      {formatAmountUSDPlain(Object.values(report.mkm_payment_methods || {}).reduce((sum, method) => sum + method.tl, 0))}
-                    </td>
-                    <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                      {formatAmountUSDPlain(Object.values(report.mkm_payment_methods || {}).reduce((sum, method) => sum + method.total_usd, 0))}
-                    </td>
-                  </tr>
-                </tbody>
-              </table>
-            </div>
-          </div>
-
-          {/* MSM Project */}
-          <div>
-            <h4 className="text-md font-medium text-gray-900 mb-3">
-              {monthName} AYI MSM TAHSİLATLAR
-            </h4>
-            <div className="overflow-x-auto">
-              <table className="w-full divide-y divide-gray-200 border border-gray-300">
-                <thead className="bg-gray-50">
-                  <tr>
-                    <th className="px-3 py-2 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                      Ödeme Nedeni
-                    </th>
-                    <th className="px-3 py-2 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                      Toplam TL
-                    </th>
-                    <th className="px-3 py-2 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                      Toplam USD
-                    </th>
-                  </tr>
-                </thead>
-                <tbody className="bg-white divide-y divide-gray-200">
-                  {['Banka Havalesi', 'Nakit', 'Çek'].map((method) => {
-                    const methodData = report.msm_payment_methods?.[method] || { tl: 0, usd: 0, total_usd: 0 };
-                    return (
-                      <tr key={method} className="hover:bg-gray-50">
-                        <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                          {method}
-                        </td>
-                        <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                          {methodData.tl > 0 ? formatAmountUSDPlain(methodData.tl) : '-'}
-                        </td>
-                        <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                          {methodData.total_usd > 0 ? formatAmountUSDPlain(methodData.total_usd) : '-'}
-                        </td>
-                      </tr>
-                    );
-                  })}
-                  <tr className="bg-gray-100 font-semibold">
-                    <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                      Genel Toplam
-                    </td>
-                    <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                      {formatAmountUSDPlain(Object.values(report.msm_payment_methods || {}).reduce((sum, method) => sum + method.tl, 0))}
-                    </td>
-                    <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                      {formatAmountUSDPlain(Object.values(report.msm_payment_methods || {}).reduce((sum, method) => sum + method.total_usd, 0))}
-                    </td>
-                  </tr>
-                </tbody>
-              </table>
+          {/* One table per project */}
+          {shownProjects.map(project => (
+            <div key={project.code}>
+              <h4 className="text-md font-medium text-gray-900 mb-3">
+                {monthName} AYI {project.code} TAHSİLATLAR
+              </h4>
+              <div className="overflow-x-auto">
+                <table className="w-full divide-y divide-gray-200 border border-gray-300">
+                  <thead className="bg-gray-50">
+                    <tr>
+                      <th className="px-3 py-2 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
+                        Ödeme Nedeni
+                      </th>
+                      <th className="px-3 py-2 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
+                        Toplam TL
+                      </th>
+                      <th className="px-3 py-2 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
+                        Toplam USD
+                      </th>
+                    </tr>
+                  </thead>
+                  <tbody className="bg-white divide-y divide-gray-200">
+                    {['Banka Havalesi', 'Nakit', 'Çek'].map((method) => {
+                      const methodData = projectMethods(project.code)[method] || emptyMethod;
+                      return (
+                        <tr key={method} className="hover:bg-gray-50">
+                          <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
+                            {method}
+                          </td>
+                          <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
+                            {currencyAmount(methodData, 'TL') > 0 ? formatAmountUSDPlain(currencyAmount(methodData, 'TL')) : '-'}
+                          </td>
+                          <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
+                            {methodData.total_usd > 0 ? formatAmountUSDPlain(methodData.total_usd) : '-'}
+                          </td>
+                        </tr>
+                      );
+                    })}
+                    <tr className="bg-gray-100 font-semibold">
+                      <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
+                        Genel Toplam
+                      </td>
+                      <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
+                        {formatAmountUSDPlain(Object.values(projectMethods(project.code)).reduce((sum, method) => sum + currencyAmount(method, 'TL'), 0))}
+                      </td>
+                      <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
+                        {formatAmountUSDPlain(Object.values(projectMethods(project.code)).reduce((sum, method) => sum + method.total_usd, 0))}
+                      </td>
+                    </tr>
+                  </tbody>
+                </table>
+              </div>
             </div>
-          </div>
+          ))}
 
           {/* Combined Total */}
           <div>
@@ -388,10 +350,9 @@ export const MonthlyReport: React.FC<MonthlyReportProps> = ({ report, allPayment
                 </thead>
                 <tbody className="bg-white divide-y divide-gray-200">
                   {['Banka Havalesi', 'Nakit', 'Çek'].map((method) => {
-                    const mkmData = report.mkm_payment_methods?.[method] || { tl: 0, usd: 0, total_usd: 0 };
-                    const msmData = report.msm_payment_methods?.[method] || { tl: 0, usd: 0, total_usd: 0 };
-                    const combinedTL = mkmData.tl + msmData.tl;
-                    const combinedUSD = mkmData.total_usd + msmData.total_usd;
+                    const methodData = shownProjects.map(project => projectMethods(project.code)[method] || emptyMethod);
+                    const combinedTL = methodData.reduce((sum, data) => sum + currencyAmount(data, 'TL'), 0);
+                    const combinedUSD = methodData.reduce((sum, data) => sum + data.total_usd, 0);
                     return (
                       <tr key={method} className="hover:bg-gray-50">
                         <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
@@ -411,16 +372,10 @@ export const MonthlyReport: React.FC<MonthlyReportProps> = ({ report, allPayment
                       Toplam
                     </td>
                     <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                      {formatAmountUSDPlain(
-                        Object.values(report.mkm_payment_methods || {}).reduce((sum, method) => sum + method.tl, 0) +
-                        Object.values(report.msm_payment_methods || {}).reduce((sum, method) => sum + method.tl, 0)
-                      )}
+                      {formatAmountUSDPlain(allProjectMethods.reduce((sum, method) => sum + currencyAmount(method, 'TL'), 0))}
                     </td>
                     <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                      {formatAmountUSDPlain(
-                        Object.values(report.mkm_payment_methods || {}).reduce((sum, method) => sum + method.total_usd, 0) +
-                        Object.values(report.msm_payment_methods || {}).reduce((sum, method) => sum + method.total_usd, 0)
-                      )}
+                      {formatAmountUSDPlain(allProjectMethods.reduce((sum, method) => sum + method.total_usd, 0))}
                     </td>
                   </tr>
                 </tbody>
@@ -449,22 +404,16 @@ export const MonthlyReport: React.FC<MonthlyReportProps> = ({ report, allPayment
               </tr>
             </thead>
             <tbody className="bg-white divide-y divide-gray-200">
-              <tr className="hover:bg-gray-50">
-                <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                  AYLIK MKM
-                </td>
-                <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
-                  {formatAmountUSDPlain(report.project_summary.mkm)}
-                </td>
-              </tr>
-              <tr className="hover:bg-gray-50">
-                <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                  AYLIK MSM
-                </td>
-                <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
-                  {formatAmountUSDPlain(report.project_summary.msm)}
-                </td>
-              </tr>
+              {shownProjects.map(project => (
+                <tr key={project.code} className="hover:bg-gray-50">
+                  <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300" title={project.name}>
+                    AYLIK {project.code}
+                  </td>
+                  <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
+                    {formatAmountUSDPlain(report.project_summary[project.code] || 0)}
+                  </td>
+                </tr>
+              ))}
               <tr className="bg-gray-100 font-semibold">
                 <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
                   TOPLAM
@@ -491,48 +440,46 @@ export const MonthlyReport: React.FC<MonthlyReportProps> = ({ report, allPayment
                 <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
                   Lokasyon/Method
                 </th>
-                <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                  MKM AYLIK (USD)
-                </th>
-                <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                  MSM AYLIK (USD)
-                </th>
+                {shownProjects.map(project => (
+                  <th key={project.code} className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
+                    {project.code} AYLIK (USD)
+                  </th>
+                ))}
                 <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
                   TOPLAM (USD)
                 </th>
               </tr>
             </thead>
             <tbody className="bg-white divide-y divide-gray-200">
-              {Object.entries(report.location_summary).map(([location, totals]) => (
-                <tr key={location} className="hover:bg-gray-50">
-                  <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                    {location}
-                  </td>
-                  <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
-                    {formatAmountUSDPlain(totals.mkm)}
-                  </td>
-                  <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
-                    {formatAmountUSDPlain(totals.msm)}
-                  </td>
-                  <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
-                    {formatAmountUSDPlain(totals.total)}
-                  </td>
-                </tr>
-              ))}
+              {shownLocations.map(location => {
+                const totals = report.location_summary[location.code] || emptyLocation;
+                return (
+                  <tr key={location.code} className="hover:bg-gray-50">
+                    <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
+                      {location.label}
+                    </td>
+                    {shownProjects.map(project => (
+                      <td key={project.code} className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
+                        {formatAmountUSDPlain(totals.projects?.[project.code] || 0)}
+                      </td>
+                    ))}
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
+                      {formatAmountUSDPlain(totals.total)}
+                    </td>
+                  </tr>
+                );
+              })}
               <tr className="bg-gray-100 font-semibold">
                 <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
                   TOPLAM
                 </td>
-                <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
-                  {formatAmountUSDPlain(
-                    Object.values(report.location_summary).reduce((sum, location) => sum + location.mkm, 0)
-                  )}
-                </td>
-                <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
-                  {formatAmountUSDPlain(
-                    Object.values(report.location_summary).reduce((sum, location) => sum + location.msm, 0)
-                  )}
-                </td>
+                {shownProjects.map(project => (
+                  <td key={project.code} className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
+                    {formatAmountUSDPlain(
+                      Object.values(report.location_summary).reduce((sum, location) => sum + (location.projects?.[project.code] || 0), 0)
+                    )}
+                  </td>
+                ))}
                 <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
                   {formatAmountUSDPlain(totalLocationUSD)}
                 </td>
@@ -1,19 +1,26 @@
 import React from 'react';
-import { WeeklyReport as WeeklyReportType, PaymentRecord } from '../types/payment.types';
-import { formatAmountUSD, formatAmountTL, formatAmountUSDPlain, formatAmountTLPlain } from '../utils/formatters';
+import { WeeklyReport as WeeklyReportType, PaymentRecord, Project, Location, LocationTotal } from '../types/payment.types';
+import { formatAmountUSD, formatAmountTL, formatAmountUSDPlain, formatAmountTLPlain, currencyAmount, reportProjects, reportLocations, sumProjectTotal, formatUnconfirmed } from '../utils/formatters';
 import { formatWeekRange } from '../utils/dateHelpers';
 
 interface WeeklyReportProps {
   report: WeeklyReportType;
   weekNumber: number;
   allPayments: PaymentRecord[];
+  projects?: Project[];
+  locations?: Location[];
 }
 
-export const WeeklyReport: React.FC<WeeklyReportProps> = ({ report, weekNumber, allPayments }) => {
+const emptyLocation: LocationTotal = { projects: {}, total: 0 };
+
+export const WeeklyReport: React.FC<WeeklyReportProps> = ({ report, weekNumber, allPayments, projects, locations }) => {
   const totalCustomerUSD = Object.values(report.customer_summary).reduce((sum, amount) => sum + amount, 0);
-  const totalMethodTL = Object.values(report.payment_methods).reduce((sum, method) => sum + method.tl, 0);
-  const totalMethodUSD = Object.values(report.payment_methods).reduce((sum, method) => sum + method.usd, 0);
-  const totalProjectUSD = report.project_summary.mkm + report.project_summary.msm;
+  const totalMethodTL = Object.values(report.payment_methods).reduce((sum, method) => sum + currencyAmount(method, 'TL'), 0);
+  const totalMethodUSD = Object.values(report.payment_methods).reduce((sum, method) => sum + currencyAmount(method, 'USD'), 0);
+  const totalProjectUSD = sumProjectTotal(report.project_summary);
+  const shownProjects = reportProjects(projects, report.project_summary);
+  const shownLocations = reportLocations(locations, report.location_summary);
+  const unconfirmed = formatUnconfirmed(report.unconfirmed);
 
   return (
     <div className="bg-white rounded-lg shadow-md p-6 mb-6">
@@ -24,6 +31,9 @@ export const WeeklyReport: React.FC<WeeklyReportProps> = ({ report, weekNumber,
         <p className="text-gray-600">
           {formatWeekRange(report.start_date, report.end_date)}
         </p>
+        {unconfirmed && (
+          <p className="text-sm text-amber-700 mt-1">{unconfirmed}</p>
+        )}
       </div>
 
       {/* Daily Collections Summary Table */}
@@ -206,8 +216,8 @@ export const WeeklyReport: React.FC<WeeklyReportProps> = ({ report, weekNumber,
               {Object.entries(report.payment_methods).map(([method, totals]) => (
                 <tr key={method} className="hover:bg-gray-50">
                   <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">{method}</td>
-                  <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountTLPlain(totals.tl)}</td>
-                  <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountUSDPlain(totals.usd)}</td>
+                  <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountTLPlain(currencyAmount(totals, 'TL'))}</td>
+                  <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountUSDPlain(currencyAmount(totals, 'USD'))}</td>
                   <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountUSDPlain(totals.total_usd)}</td>
                 </tr>
               ))}
@@ -237,18 +247,32 @@ export const WeeklyReport: React.FC<WeeklyReportProps> = ({ report, weekNumber,
               </tr>
             </thead>
             <tbody className="bg-white divide-y divide-gray-200">
-              <tr className="hover:bg-gray-50">
-                <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">HAFTALIK MKM</td>
-                <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountUSDPlain(report.project_summary.mkm)}</td>
-              </tr>
-              <tr className="hover:bg-gray-50">
-                <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">HAFTALIK MSM</td>
-                <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountUSDPlain(report.project_summary.msm)}</td>
-              </tr>
+              {shownProjects.map(project => (
+                <tr key={project.code} className="hover:bg-gray-50">
+                  <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300" title={project.name}>HAFTALIK {project.code}</td>
+                  <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountUSDPlain(report.project_summary[project.code] || 0)}</td>
+                </tr>
+              ))}
               <tr className="bg-gray-100 font-semibold">
                 <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">TOPLAM</td>
                 <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountUSDPlain(totalProjectUSD)}</td>
               </tr>
+              {report.kind_summary && (report.kind_summary.refund_count > 0 || report.kind_summary.reversal_count > 0) && (
+                <>
+                  <tr className="hover:bg-gray-50">
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500 border border-gray-300">Brüt Tahsilat</td>
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500 border border-gray-300">{formatAmountUSDPlain(report.kind_summary.collections)}</td>
+                  </tr>
+                  <tr className="hover:bg-gray-50">
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500 border border-gray-300">İade ({report.kind_summary.refund_count})</td>
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-red-600 border border-gray-300">{formatAmountUSDPlain(report.kind_summary.refunds)}</td>
+                  </tr>
+                  <tr className="hover:bg-gray-50">
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500 border border-gray-300">İptal / Karşılıksız ({report.kind_summary.reversal_count})</td>
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-red-600 border border-gray-300">{formatAmountUSDPlain(report.kind_summary.reversals)}</td>
+                  </tr>
+                </>
+              )}
             </tbody>
           </table>
         </div>
@@ -265,29 +289,33 @@ export const WeeklyReport: React.FC<WeeklyReportProps> = ({ report, weekNumber,
             <thead className="bg-gray-50">
               <tr>
                 <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">Lokasyon/Method</th>
-                <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">MKM HAFTALIK (USD)</th>
-                <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">MSM HAFTALIK (USD)</th>
+                {shownProjects.map(project => (
+                  <th key={project.code} className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">{project.code} HAFTALIK (USD)</th>
+                ))}
                 <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">TOPLAM (USD)</th>
               </tr>
             </thead>
             <tbody className="bg-white divide-y divide-gray-200">
-              {Object.entries(report.location_summary).map(([location, totals]) => (
-                <tr key={location} className="hover:bg-gray-50">
-                  <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">{location}</td>
-                  <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountUSDPlain(totals.mkm)}</td>
-                  <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountUSDPlain(totals.msm)}</td>
-                  <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountUSDPlain(totals.total)}</td>
-                </tr>
-              ))}
+              {shownLocations.map(location => {
+                const totals = report.location_summary[location.code] || emptyLocation;
+                return (
+                  <tr key={location.code} className="hover:bg-gray-50">
+                    <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">{location.label}</td>
+                    {shownProjects.map(project => (
+                      <td key={project.code} className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountUSDPlain(totals.projects?.[project.code] || 0)}</td>
+                    ))}
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">{formatAmountUSDPlain(totals.total)}</td>
+                  </tr>
+                );
+              })}
               {/* Project Totals Row */}
               <tr className="bg-gray-100 font-semibold border-t-2 border-gray-400">
                 <td className="px-6 py-4 whitespace-nowrap text-sm font-bold text-gray-900 border border-gray-300">TOPLAM</td>
-                <td className="px-6 py-4 whitespace-nowrap text-sm font-bold text-gray-900 border border-gray-300">
-                  {formatAmountUSDPlain(Object.values(report.location_summary).reduce((sum, loc) => sum + loc.mkm, 0))}
-                </td>
-                <td className="px-6 py-4 whitespace-nowrap text-sm font-bold text-gray-900 border border-gray-300">
-                  {formatAmountUSDPlain(Object.values(report.location_summary).reduce((sum, loc) => sum + loc.msm, 0))}
-                </td>
+                {shownProjects.map(project => (
+                  <td key={project.code} className="px-6 py-4 whitespace-nowrap text-sm font-bold text-gray-900 border border-gray-300">
+                    {formatAmountUSDPlain(Object.values(report.location_summary).reduce((sum, loc) => sum + (loc.projects?.[project.code] || 0), 0))}
+                  </td>
+                ))}
                 <td className="px-6 py-4 whitespace-nowrap text-sm font-bold text-gray-900 border border-gray-300">
                   {formatAmountUSDPlain(Object.values(report.location_summary).reduce((sum, loc) => sum + loc.total, 0))}
                 </td>
@@ -327,7 +355,7 @@ export const WeeklyReport: React.FC<WeeklyReportProps> = ({ report, weekNumber,
             <tbody className="bg-white divide-y divide-gray-200">
               {(() => {
                 // Filter only ÇEK payments
-                const cekPayments = (report.payments ?? []).filter(p => p.payment_method === 'Çek' || p.location === 'ÇEK');
+                const cekPayments = (report.payments ?? []).filter(p => p.payment_method === 'Çek' || p.location === 'CEK');
                 
                 // Group by customer
                 const customerCekSummary = cekPayments.reduce((acc, payment) => {
@@ -403,7 +431,7 @@ export const WeeklyReport: React.FC<WeeklyReportProps> = ({ report, weekNumber,
               <tr className="bg-gray-100 font-semibold">
                 <td colSpan={3} className="px-2 py-4 whitespace-nowrap text-sm font-medium text-gray-900 text-right border border-gray-300">TOPLAM</td>
                 {Array.from({length: 7}).map((_, dayIndex) => {
-                  const cekPayments = (report.payments ?? []).filter(p => p.payment_method === 'Çek' || p.location === 'ÇEK');
+                  const cekPayments = (report.payments ?? []).filter(p => p.payment_method === 'Çek' || p.location === 'CEK');
                   let dayTotalTL = 0;
                   let dayTotalUSD = 0;
                   
@@ -439,10 +467,10 @@ export const WeeklyReport: React.FC<WeeklyReportProps> = ({ report, weekNumber,
                   );
                 })}
                 <td className="px-2 py-4 whitespace-nowrap text-sm text-center text-gray-900 font-semibold border border-gray-300">
-                  {formatAmountTLPlain((report.payments ?? []).filter(p => p.payment_method === 'Çek' || p.location === 'ÇEK').reduce((sum, p) => p.currency === 'TL' ? sum + p.amount : sum, 0))}
+                  {formatAmountTLPlain((report.payments ?? []).filter(p => p.payment_method === 'Çek' || p.location === 'CEK').reduce((sum, p) => p.currency === 'TL' ? sum + p.amount : sum, 0))}
                 </td>
                 <td className="px-2 py-4 whitespace-nowrap text-sm text-center text-gray-900 font-semibold border border-gray-300">
-                  {formatAmountUSDPlain((report.payments ?? []).filter(p => p.payment_method === 'Çek' || p.location === 'ÇEK').reduce((sum, p) => sum + p.amount_usd, 0))}
+                  {formatAmountUSDPlain((report.payments ?? []).filter(p => p.payment_method === 'Çek' || p.location === 'CEK').reduce((sum, p) => sum + p.amount_usd, 0))}
                 </td>
               </tr>
             </tbody>
@@ -491,7 +519,7 @@ export const WeeklyReport: React.FC<WeeklyReportProps> = ({ report, weekNumber,
                   }, {} as Record<string, number>);
                   
                   const dominantProject = Object.entries(projectCounts)
-                    .sort(([,a], [,b]) => b - a)[0]?.[0] || 'MKM';
+                    .sort(([,a], [,b]) => b - a)[0]?.[0] || '-';
                   
                   // Group payments by day and sum amounts for each day
                   const dailyAmounts = Array(7).fill(0);
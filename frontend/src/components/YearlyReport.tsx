@@ -1,14 +1,29 @@
 import React from 'react';
-import { YearlyReport } from '../types/payment.types';
-import { formatAmountUSDPlain } from '../utils/formatters';
+import { YearlyReport, MonthlyReport, PaymentMethodTotal, LocationTotal } from '../types/payment.types';
+import { formatAmountUSDPlain, currencyAmount, reportProjects, reportLocations, sumProjectTotal, formatUnconfirmed } from '../utils/formatters';
 import { paymentAPI } from '../services/api';
 
 interface YearlyReportProps {
   report: YearlyReport;
 }
 
+const emptyMethod: PaymentMethodTotal = { currencies: {}, total_usd: 0 };
+const emptyLocation: LocationTotal = { projects: {}, total: 0 };
+
+// Payment method breakdown of one project
+const projectMethods = (report: YearlyReport | MonthlyReport, code: string): Record<string, PaymentMethodTotal> =>
+  report.project_payment_methods?.[code] || {};
+
+// Payment method totals of all projects of a report
+const allProjectMethods = (report: YearlyReport | MonthlyReport, codes: string[]): PaymentMethodTotal[] =>
+  codes.flatMap(code => Object.values(projectMethods(report, code)));
+
 const YearlyReportComponent: React.FC<YearlyReportProps> = ({ report }) => {
-  const totalProjectUSD = report.project_summary.mkm + report.project_summary.msm;
+  const totalProjectUSD = sumProjectTotal(report.project_summary);
+  const shownProjects = reportProjects(report.projects, report.project_summary);
+  const shownLocations = reportLocations(report.locations, report.location_summary);
+  const unconfirmed = formatUnconfirmed(report.unconfirmed);
+  const projectCodes = shownProjects.map(project => project.code);
 
   const handleExportExcel = async () => {
     try {
@@ -30,7 +45,12 @@ const YearlyReportComponent: React.FC<YearlyReportProps> = ({ report }) => {
   return (
     <div className="p-6">
       <div className="flex justify-between items-center mb-6">
-        <h2 className="text-2xl font-bold">{report.year} Yılı Tahsilat Raporu</h2>
+        <div>
+          <h2 className="text-2xl font-bold">{report.year} Yılı Tahsilat Raporu</h2>
+          {unconfirmed && (
+            <p className="text-sm text-amber-700 mt-1">{unconfirmed}</p>
+          )}
+        </div>
         <button
           onClick={handleExportExcel}
           className="bg-green-600 hover:bg-green-700 text-white px-4 py-2 rounded flex items-center gap-2"
@@ -61,22 +81,16 @@ const YearlyReportComponent: React.FC<YearlyReportProps> = ({ report }) => {
               </tr>
             </thead>
             <tbody className="bg-white divide-y divide-gray-200">
-              <tr className="hover:bg-gray-50">
-                <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                  YILLIK MKM
-                </td>
-                <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
-                  {formatAmountUSDPlain(report.project_summary.mkm)}
-                </td>
-              </tr>
-              <tr className="hover:bg-gray-50">
-                <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                  YILLIK MSM
-                </td>
-                <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
-                  {formatAmountUSDPlain(report.project_summary.msm)}
-                </td>
-              </tr>
+              {shownProjects.map(project => (
+                <tr key={project.code} className="hover:bg-gray-50">
+                  <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300" title={project.name}>
+                    YILLIK {project.code}
+                  </td>
+                  <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 border border-gray-300">
+                    {formatAmountUSDPlain(report.project_summary[project.code] || 0)}
+                  </td>
+                </tr>
+              ))}
               <tr className="bg-gray-100 font-semibold">
                 <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
                   TOPLAM
@@ -96,111 +110,60 @@ const YearlyReportComponent: React.FC<YearlyReportProps> = ({ report }) => {
           Yıllık Proje Bazında Ödeme Şekli Dağılımı
         </h3>
         <div className="flex w-full gap-6" style={{width: '100%'}}>
-          {/* MKM Payment Methods */}
-          <div className="flex-1 flex flex-col min-h-[400px]">
-            <h4 className="text-md font-medium text-gray-900 mb-3">
-              {report.year} YILI MKM TAHSİLATLAR
-            </h4>
-            <div className="overflow-y-auto" style={{maxHeight: '340px'}}>
-              <table className="w-full divide-y divide-gray-200 border border-gray-300">
-                <thead className="bg-gray-50">
-                  <tr>
-                    <th className="px-3 py-2 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                      Ödeme Nedeni
-                    </th>
-                    <th className="px-3 py-2 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                      Toplam TL
-                    </th>
-                    <th className="px-3 py-2 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                      Toplam USD
-                    </th>
-                  </tr>
-                </thead>
-                <tbody className="bg-white divide-y divide-gray-200">
-                  {['Banka Havalesi', 'Nakit', 'Çek'].map((method) => {
-                    const methodData = report.mkm_payment_methods?.[method] || { tl: 0, usd: 0, total_usd: 0 };
-                    return (
-                      <tr key={method} className="hover:bg-gray-50">
-                        <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                          {method}
-                        </td>
-                        <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                          {formatAmountUSDPlain(methodData.tl)}
-                        </td>
-                        <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                          {formatAmountUSDPlain(methodData.usd)}
-                        </td>
-                      </tr>
-                    );
-                  })}
-                  <tr className="bg-gray-100 font-semibold">
-                    <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                      Genel Toplam
-                    </td>
-                    <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                      {formatAmountUSDPlain(Object.values(report.mkm_payment_methods || {}).reduce((sum, method) => sum + method.tl, 0))}
-                    </td>
-                    <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                      {formatAmountUSDPlain(Object.values(report.mkm_payment_methods || {}).reduce((sum, method) => sum + method.usd, 0))}
-                    </td>
-                  </tr>
-                </tbody>
-              </table>
-            </div>
-          </div>
-
-          {/* MSM Payment Methods */}
-          <div className="flex-1 flex flex-col min-h-[400px]">
-            <h4 className="text-md font-medium text-gray-900 mb-3">
-              {report.year} YILI MSM TAHSİLATLAR
-            </h4>
-            <div className="overflow-y-auto" style={{maxHeight: '340px'}}>
-              <table className="w-full divide-y divide-gray-200 border border-gray-300">
-                <thead className="bg-gray-50">
-                  <tr>
-                    <th className="px-3 py-2 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                      Ödeme Nedeni
-                    </th>
-                    <th className="px-3 py-2 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                      Toplam TL
-                    </th>
-                    <th className="px-3 py-2 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                      Toplam USD
-                    </th>
-                  </tr>
-                </thead>
-                <tbody className="bg-white divide-y divide-gray-200">
-                  {['Banka Havalesi', 'Nakit', 'Çek'].map((method) => {
-                    const methodData = report.msm_payment_methods?.[method] || { tl: 0, usd: 0, total_usd: 0 };
-                    return (
-                      <tr key={method} className="hover:bg-gray-50">
-                        <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                          {method}
-                        </td>
-                        <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                          {formatAmountUSDPlain(methodData.tl)}
-                        </td>
-                        <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                          {formatAmountUSDPlain(methodData.usd)}
-                        </td>
-                      </tr>
-                    );
-                  })}
-                  <tr className="bg-yellow-200 font-semibold">
-                    <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                      Toplam
-                    </td>
-                    <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                      {formatAmountUSDPlain(Object.values(report.msm_payment_methods || {}).reduce((sum, method) => sum + method.tl, 0))}
-                    </td>
-                    <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                      {formatAmountUSDPlain(Object.values(report.msm_payment_methods || {}).reduce((sum, method) => sum + method.usd, 0))}
-                    </td>
-                  </tr>
-                </tbody>
-              </table>
+          {/* One table per project */}
+          {shownProjects.map(project => (
+            <div key={project.code} className="flex-1 flex flex-col min-h-[400px]">
+              <h4 className="text-md font-medium text-gray-900 mb-3">
+                {report.year} YILI {project.code} TAHSİLATLAR
+              </h4>
+              <div className="overflow-y-auto" style={{maxHeight: '340px'}}>
+                <table className="w-full divide-y divide-gray-200 border border-gray-300">
+                  <thead className="bg-gray-50">
+                    <tr>
+                      <th className="px-3 py-2 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
+                        Ödeme Nedeni
+                      </th>
+                      <th className="px-3 py-2 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
+                        Toplam TL
+                      </th>
+                      <th className="px-3 py-2 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
+                        Toplam USD
+                      </th>
+                    </tr>
+                  </thead>
+                  <tbody className="bg-white divide-y divide-gray-200">
+                    {['Banka Havalesi', 'Nakit', 'Çek'].map((method) => {
+                      const methodData = projectMethods(report, project.code)[method] || emptyMethod;
+                      return (
+                        <tr key={method} className="hover:bg-gray-50">
+                          <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
+                            {method}
+                          </td>
+                          <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
+                            {formatAmountUSDPlain(currencyAmount(methodData, 'TL'))}
+                          </td>
+                          <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
+                            {formatAmountUSDPlain(methodData.total_usd)}
+                          </td>
+                        </tr>
+                      );
+                    })}
+                    <tr className="bg-gray-100 font-semibold">
+                      <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
+                        Genel Toplam
+                      </td>
+                      <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
+                        {formatAmountUSDPlain(Object.values(projectMethods(report, project.code)).reduce((sum, method) => sum + currencyAmount(method, 'TL'), 0))}
+                      </td>
+                      <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
+                        {formatAmountUSDPlain(Object.values(projectMethods(report, project.code)).reduce((sum, method) => sum + method.total_usd, 0))}
+                      </td>
+                    </tr>
+                  </tbody>
+                </table>
+              </div>
             </div>
-          </div>
+          ))}
 
           {/* Combined Total */}
           <div className="flex-1 flex flex-col min-h-[400px]">
@@ -224,10 +187,9 @@ const YearlyReportComponent: React.FC<YearlyReportProps> = ({ report }) => {
                 </thead>
                 <tbody className="bg-white divide-y divide-gray-200">
                   {['Banka Havalesi', 'Nakit', 'Çek'].map((method) => {
-                    const mkmData = report.mkm_payment_methods?.[method] || { tl: 0, usd: 0, total_usd: 0 };
-                    const msmData = report.msm_payment_methods?.[method] || { tl: 0, usd: 0, total_usd: 0 };
-                    const combinedTL = mkmData.tl + msmData.tl;
-                    const combinedUSD = mkmData.usd + msmData.usd;
+                    const methodData = projectCodes.map(code => projectMethods(report, code)[method] || emptyMethod);
+                    const combinedTL = methodData.reduce((sum, data) => sum + currencyAmount(data, 'TL'), 0);
+                    const combinedUSD = methodData.reduce((sum, data) => sum + data.total_usd, 0);
                     return (
                       <tr key={method} className="hover:bg-gray-50">
                         <td className="px-3 py-2 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
@@ -247,16 +209,10 @@ const YearlyReportComponent: React.FC<YearlyReportProps> = ({ report }) => {
                       Toplam
                     </td>
                     <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                      {formatAmountUSDPlain(
-                        Object.values(report.mkm_payment_methods || {}).reduce((sum, method) => sum + method.tl, 0) +
-                        Object.values(report.msm_payment_methods || {}).reduce((sum, method) => sum + method.tl, 0)
-                      )}
+                      {formatAmountUSDPlain(allProjectMethods(report, projectCodes).reduce((sum, method) => sum + currencyAmount(method, 'TL'), 0))}
                     </td>
                     <td className="px-3 py-2 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                      {formatAmountUSDPlain(
-                        Object.values(report.mkm_payment_methods || {}).reduce((sum, method) => sum + method.usd, 0) +
-                        Object.values(report.msm_payment_methods || {}).reduce((sum, method) => sum + method.usd, 0)
-                      )}
+                      {formatAmountUSDPlain(allProjectMethods(report, projectCodes).reduce((sum, method) => sum + method.total_usd, 0))}
                     </td>
                   </tr>
                 </tbody>
@@ -272,7 +228,7 @@ const YearlyReportComponent: React.FC<YearlyReportProps> = ({ report }) => {
           <h3 className="text-lg font-semibold text-gray-900 mb-4">
             Aylık Proje Bazında Ödeme Şekli Dağılımı
           </h3>
-          {report.monthly_reports.map((monthReport: import('../types/payment.types').MonthlyReport, idx: number) => (
+          {report.monthly_reports.map((monthReport: MonthlyReport, idx: number) => (
             <div key={monthReport.month} className="mb-8">
               <h4 className="text-md font-bold text-gray-900 mb-2">
                 {new Date(monthReport.month).toLocaleDateString('tr-TR', { 
@@ -281,70 +237,40 @@ const YearlyReportComponent: React.FC<YearlyReportProps> = ({ report }) => {
                 })}
               </h4>
               <div className="flex w-full gap-6" style={{width: '100%'}}>
-                {/* MKM Payment Methods */}
-                <div className="flex-1 flex flex-col min-h-[300px]">
-                  <h5 className="text-sm font-medium text-gray-900 mb-2">MKM</h5>
-                  <div className="overflow-y-auto" style={{maxHeight: '240px'}}>
-                    <table className="w-full divide-y divide-gray-200 border border-gray-300 text-xs">
-                      <thead className="bg-gray-50">
-                        <tr>
-                          <th className="px-2 py-2 text-left text-xs font-medium text-gray-500 border border-gray-300">Ödeme Nedeni</th>
-                          <th className="px-2 py-2 text-center text-xs font-medium text-gray-500 border border-gray-300">Toplam TL</th>
-                          <th className="px-2 py-2 text-center text-xs font-medium text-gray-500 border border-gray-300">Toplam USD</th>
-                        </tr>
-                      </thead>
-                      <tbody>
-                        {['Banka Havalesi', 'Nakit', 'Çek'].map((method) => {
-                          const methodData = monthReport.mkm_payment_methods?.[method] || { tl: 0, usd: 0, total_usd: 0 };
-                          return (
-                            <tr key={method}>
-                              <td className="px-2 py-2 border border-gray-300">{method}</td>
-                              <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(methodData.tl)}</td>
-                              <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(methodData.usd)}</td>
-                            </tr>
-                          );
-                        })}
-                        <tr className="bg-gray-100 font-semibold">
-                          <td className="px-2 py-2 border border-gray-300">Genel Toplam</td>
-                          <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(Object.values(monthReport.mkm_payment_methods || {}).reduce((sum, method) => sum + (method as import('../types/payment.types').PaymentMethodTotal).tl, 0))}</td>
-                          <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(Object.values(monthReport.mkm_payment_methods || {}).reduce((sum, method) => sum + (method as import('../types/payment.types').PaymentMethodTotal).usd, 0))}</td>
-                        </tr>
-                      </tbody>
-                    </table>
+                {/* One table per project */}
+                {shownProjects.map(project => (
+                  <div key={project.code} className="flex-1 flex flex-col min-h-[300px]">
+                    <h5 className="text-sm font-medium text-gray-900 mb-2" title={project.name}>{project.code}</h5>
+                    <div className="overflow-y-auto" style={{maxHeight: '240px'}}>
+                      <table className="w-full divide-y divide-gray-200 border border-gray-300 text-xs">
+                        <thead className="bg-gray-50">
+                          <tr>
+                            <th className="px-2 py-2 text-left text-xs font-medium text-gray-500 border border-gray-300">Ödeme Nedeni</th>
+                            <th className="px-2 py-2 text-center text-xs font-medium text-gray-500 border border-gray-300">Toplam TL</th>
+                            <th className="px-2 py-2 text-center text-xs font-medium text-gray-500 border border-gray-300">Toplam USD</th>
+                          </tr>
+                        </thead>
+                        <tbody>
+                          {['Banka Havalesi', 'Nakit', 'Çek'].map((method) => {
+                            const methodData = projectMethods(monthReport, project.code)[method] || emptyMethod;
+                            return (
+                              <tr key={method}>
+                                <td className="px-2 py-2 border border-gray-300">{method}</td>
+                                <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(currencyAmount(methodData, 'TL'))}</td>
+                                <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(methodData.total_usd)}</td>
+                              </tr>
+                            );
+                          })}
+                          <tr className="bg-gray-100 font-semibold">
+                            <td className="px-2 py-2 border border-gray-300">Genel Toplam</td>
+                            <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(Object.values(projectMethods(monthReport, project.code)).reduce((sum, method) => sum + currencyAmount(method, 'TL'), 0))}</td>
+                            <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(Object.values(projectMethods(monthReport, project.code)).reduce((sum, method) => sum + method.total_usd, 0))}</td>
+                          </tr>
+                        </tbody>
+                      </table>
+                    </div>
                   </div>
-                </div>
-                {/* MSM Payment Methods */}
-                <div className="flex-1 flex flex-col min-h-[300px]">
-                  <h5 className="text-sm font-medium text-gray-900 mb-2">MSM</h5>
-                  <div className="overflow-y-auto" style={{maxHeight: '240px'}}>
-                    <table className="w-full divide-y divide-gray-200 border border-gray-300 text-xs">
-                      <thead className="bg-gray-50">
-                        <tr>
-                          <th className="px-2 py-2 text-left text-xs font-medium text-gray-500 border border-gray-300">Ödeme Nedeni</th>
-                          <th className="px-2 py-2 text-center text-xs font-medium text-gray-500 border border-gray-300">Toplam TL</th>
-                          <th className="px-2 py-2 text-center text-xs font-medium text-gray-500 border border-gray-300">Toplam USD</th>
-                        </tr>
-                      </thead>
-                      <tbody>
-                        {['Banka Havalesi', 'Nakit', 'Çek'].map((method) => {
-                          const methodData = monthReport.msm_payment_methods?.[method] || { tl: 0, usd: 0, total_usd: 0 };
-                          return (
-                            <tr key={method}>
-                              <td className="px-2 py-2 border border-gray-300">{method}</td>
-                              <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(methodData.tl)}</td>
-                              <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(methodData.usd)}</td>
-                            </tr>
-                          );
-                        })}
-                        <tr className="bg-gray-100 font-semibold">
-                          <td className="px-2 py-2 border border-gray-300">Toplam</td>
-                          <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(Object.values(monthReport.msm_payment_methods || {}).reduce((sum, method) => sum + (method as import('../types/payment.types').PaymentMethodTotal).tl, 0))}</td>
-                          <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(Object.values(monthReport.msm_payment_methods || {}).reduce((sum, method) => sum + (method as import('../types/payment.types').PaymentMethodTotal).usd, 0))}</td>
-                        </tr>
-                      </tbody>
-                    </table>
-                  </div>
-                </div>
+                ))}
                 {/* GENEL Payment Methods */}
                 <div className="flex-1 flex flex-col min-h-[300px]">
                   <h5 className="text-sm font-medium text-gray-900 mb-2">GENEL</h5>
@@ -359,10 +285,9 @@ const YearlyReportComponent: React.FC<YearlyReportProps> = ({ report }) => {
                       </thead>
                       <tbody>
                         {['Banka Havalesi', 'Nakit', 'Çek'].map((method) => {
-                          const mkmData = monthReport.mkm_payment_methods?.[method] || { tl: 0, usd: 0, total_usd: 0 };
-                          const msmData = monthReport.msm_payment_methods?.[method] || { tl: 0, usd: 0, total_usd: 0 };
-                          const combinedTL = mkmData.tl + msmData.tl;
-                          const combinedUSD = mkmData.usd + msmData.usd;
+                          const methodData = projectCodes.map(code => projectMethods(monthReport, code)[method] || emptyMethod);
+                          const combinedTL = methodData.reduce((sum, data) => sum + currencyAmount(data, 'TL'), 0);
+                          const combinedUSD = methodData.reduce((sum, data) => sum + data.total_usd, 0);
                           return (
                             <tr key={method}>
                               <td className="px-2 py-2 border border-gray-300">{method}</td>
@@ -373,14 +298,8 @@ const YearlyReportComponent: React.FC<YearlyReportProps> = ({ report }) => {
                         })}
                         <tr className="bg-gray-100 font-semibold">
                           <td className="px-2 py-2 border border-gray-300">Toplam</td>
-                          <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(
-                            Object.values(monthReport.mkm_payment_methods || {}).reduce((sum, method) => sum + (method as import('../types/payment.types').PaymentMethodTotal).tl, 0) +
-                            Object.values(monthReport.msm_payment_methods || {}).reduce((sum, method) => sum + (method as import('../types/payment.types').PaymentMethodTotal).tl, 0)
-                          )}</td>
-                          <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(
-                            Object.values(monthReport.mkm_payment_methods || {}).reduce((sum, method) => sum + (method as import('../types/payment.types').PaymentMethodTotal).usd, 0) +
-                            Object.values(monthReport.msm_payment_methods || {}).reduce((sum, method) => sum + (method as import('../types/payment.types').PaymentMethodTotal).usd, 0)
-                          )}</td>
+                          <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(allProjectMethods(monthReport, projectCodes).reduce((sum, method) => sum + currencyAmount(method, 'TL'), 0))}</td>
+                          <td className="px-2 py-2 text-center border border-gray-300">{formatAmountUSDPlain(allProjectMethods(monthReport, projectCodes).reduce((sum, method) => sum + method.total_usd, 0))}</td>
                         </tr>
                       </tbody>
                     </table>
@@ -404,44 +323,44 @@ const YearlyReportComponent: React.FC<YearlyReportProps> = ({ report }) => {
                 <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
                   Lokasyon/Method
                 </th>
-                <th className="px-6 py-3 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                  MKM YILLIK (USD)
-                </th>
-                <th className="px-6 py-3 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
-                  MSM YILLIK (USD)
-                </th>
+                {shownProjects.map(project => (
+                  <th key={project.code} className="px-6 py-3 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
+                    {project.code} YILLIK (USD)
+                  </th>
+                ))}
                 <th className="px-6 py-3 text-center text-xs font-medium text-gray-500 uppercase tracking-wider border border-gray-300">
                   TOPLAM (USD)
                 </th>
               </tr>
             </thead>
             <tbody className="bg-white divide-y divide-gray-200">
-              {Object.entries(report.location_summary).map(([location, summary]) => (
-                <tr key={location} className="hover:bg-gray-50">
-                  <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
-                    {location}
-                  </td>
-                  <td className="px-6 py-4 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                    {formatAmountUSDPlain(summary.mkm)}
-                  </td>
-                  <td className="px-6 py-4 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                    {formatAmountUSDPlain(summary.msm)}
-                  </td>
-                  <td className="px-6 py-4 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                    {formatAmountUSDPlain(summary.total)}
-                  </td>
-                </tr>
-              ))}
+              {shownLocations.map(location => {
+                const summary = report.location_summary[location.code] || emptyLocation;
+                return (
+                  <tr key={location.code} className="hover:bg-gray-50">
+                    <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
+                      {location.label}
+                    </td>
+                    {shownProjects.map(project => (
+                      <td key={project.code} className="px-6 py-4 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
+                        {formatAmountUSDPlain(summary.projects?.[project.code] || 0)}
+                      </td>
+                    ))}
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
+                      {formatAmountUSDPlain(summary.total)}
+                    </td>
+                  </tr>
+                );
+              })}
               <tr className="bg-gray-100 font-semibold">
                 <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900 border border-gray-300">
                   TOPLAM
                 </td>
-                <td className="px-6 py-4 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                  {formatAmountUSDPlain(report.project_summary.mkm)}
-                </td>
-                <td className="px-6 py-4 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
-                  {formatAmountUSDPlain(report.project_summary.msm)}
-                </td>
+                {shownProjects.map(project => (
+                  <td key={project.code} className="px-6 py-4 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
+                    {formatAmountUSDPlain(report.project_summary[project.code] || 0)}
+                  </td>
+                ))}
                 <td className="px-6 py-4 whitespace-nowrap text-sm text-center text-gray-900 border border-gray-300">
                   {formatAmountUSDPlain(totalProjectUSD)}
                 </td>
@@ -341,13 +341,15 @@ export class ExcelParser {
     }
 
     const amount = this.parseAmount(row[odenenTutarKey]);
-    if (amount <= 0) {
-      throw new Error('Amount must be greater than 0');
+    // Negative amounts are refunds; the server checks them against the payment kind
+    if (amount === '' || amount === 0) {
+      throw new Error('Amount cannot be 0');
     }
 
-    const currency = String(row['Ödenen Döviz']).trim().toUpperCase();
-    if (!['TL', 'USD', 'EUR'].includes(currency)) {
-      throw new Error('Invalid currency. Must be TL, USD, or EUR');
+    // Gold units (gram, çeyrek, ...) are configured on the server, which checks the value
+    const currency = String(row['Ödenen Döviz']).trim().toLocaleUpperCase('tr-TR');
+    if (!currency) {
+      throw new Error('Currency cannot be empty');
     }
 
     const tahsilatSekli = String(row['Tahsilat Şekli']).trim();
@@ -365,6 +367,10 @@ export class ExcelParser {
       throw new Error('Project name cannot be empty');
     }
 
+    // Optional refund columns
+    const islemTuru = row['İşlem Türü'] != null ? String(row['İşlem Türü']).trim() : '';
+    const orijinalOdemeNo = row['Orijinal Ödeme No'] != null ? String(row['Orijinal Ödeme No']).trim() : '';
+
     return {
       musteri_adi_soyadi: customerName,
       tarih: tarih,
@@ -373,6 +379,8 @@ export class ExcelParser {
       odenen_tutar: amount,
       odenen_doviz: currency,
       proje_adi: projeAdi,
+      ...(islemTuru && { islem_turu: islemTuru }),
+      ...(orijinalOdemeNo && { orijinal_odeme_no: orijinalOdemeNo }),
     };
   }
 
@@ -469,21 +477,12 @@ export class ExcelParser {
     return null;
   }
 
-  private static parseAmount(amountValue: any): number {
+  // Text amounts like "1.234,56 TL" are sent as-is; the server detects the separators
+  private static parseAmount(amountValue: any): number | string {
     if (typeof amountValue === 'number') {
       return amountValue;
     }
-
-    const amountStr = String(amountValue).trim();
-    
-    // Remove currency symbols and spaces
-    const cleaned = amountStr
-      .replace(/[₺$€,\s]/g, '')
-      .replace(/\./g, '')
-      .replace(/,/g, '.');
-
-    const amount = parseFloat(cleaned);
-    return isNaN(amount) ? 0 : amount;
+    return String(amountValue ?? '').trim();
   }
 
   static validateFile(file: File): { valid: boolean; error?: string } {
@@ -4,25 +4,38 @@ export interface RawPaymentData {
   tarih: string;              // "Tarih" - DD/MM/YYYY format
   tahsilat_sekli: string;     // "Tahsilat Şekli"
   hesap_adi: string;          // "Hesap Adı"
-  odenen_tutar: number;       // "Ödenen Tutar(Σ:...)"
+  odenen_tutar: number | string; // "Ödenen Tutar(Σ:...)", text is parsed by the server
   odenen_doviz: string;       // "Ödenen Döviz"
   proje_adi: string;          // "Proje Adı"
+  islem_turu?: string;        // "İşlem Türü": tahsilat, iade, iptal
+  orijinal_odeme_no?: string; // "Orijinal Ödeme No": refunded payment ID
 }
 
+export type PaymentKind = 'collection' | 'refund' | 'reversal';
+
 // Processed payment record
 export interface PaymentRecord {
   id?: number;
   customer_name: string;
   payment_date: string;
   amount: number;
-  currency: string; // TL, USD, EUR
+  currency: string; // TL or a TCMB currency code (USD, EUR, GBP, ...) or a gold unit code (XAU, CEYREK, ...)
   payment_method: string; // Nakit, Banka Havalesi, Çek
-  location: string; // ÇARŞI, KUYUMCUKENT, OFİS, BANKA HAVALESİ
-  project: string; // MKM, MSM
+  location: string; // location code, e.g. CARSI; DIGER when no location matched
+  project: string; // project code, e.g. MKM; UNKNOWN if no project matched
   account_name: string;
   amount_usd: number;
   exchange_rate: number;
   created_at?: string;
+  kind?: PaymentKind; // refunds and reversals have negative amounts
+  original_payment_id?: number;
+  gold_grams?: number; // pure gold content of gold payments
+  customer_id?: number; // entry of the customer master table
+  matched_rules?: Record<string, number>; // classification rule ID that set each field
+  review_status?: ReviewStatus; // set when a field got its fallback value
+  review_reasons?: ReviewReason[];
+  review_note?: string;
+  reviewed_at?: string;
   // KDV (Tax) related fields
   includes_kdv?: boolean;
   kdv_amount?: number;
@@ -37,34 +50,95 @@ export interface WeeklyReport {
   payment_methods: Record<string, PaymentMethodTotal>;
   project_summary: ProjectTotal;
   location_summary: Record<string, LocationTotal>;
+  kind_summary?: KindTotal;
+  gold_summary?: Record<string, GoldTotal>; // gold unit code -> totals
+  unconfirmed?: ReviewTotal; // payments still awaiting review
   payments: PaymentRecord[];
 }
 
+// Gross collections and the refunds/reversals netted out of a report (USD, refunds negative)
+export interface KindTotal {
+  collections: number;
+  refunds: number;
+  reversals: number;
+  net: number;
+  refund_count: number;
+  reversal_count: number;
+}
+
 export interface PaymentMethodTotal {
-  tl: number;
-  usd: number;
-  total_usd: number; // Grand total in USD (TL converted + USD)
+  currencies: Record<string, number>; // currency -> total in that currency, before conversion (gold is in gold_grams)
+  gold_grams?: number; // pure gold content of gold payments
+  total_usd: number; // Grand total in USD (all currencies converted + gold valued)
 }
 
-export interface ProjectTotal {
-  mkm: number;
-  msm: number;
+// Gold payments of one unit, valued at the gold price of each payment date
+export interface GoldTotal {
+  quantity: number;
+  fine_grams: number;
+  usd: number;
 }
 
+// USD totals keyed by project code
+export type ProjectTotal = Record<string, number>;
+
 export interface LocationTotal {
-  mkm: number;
-  msm: number;
+  projects: ProjectTotal;
   total: number;
 }
 
+// A project payments are reported under, matched by patterns in Proje Adı
+export interface Project {
+  code: string; // e.g. MKM
+  name: string; // e.g. Model Kuyum Merkezi
+  patterns: string[];
+  sort_order: number; // lower comes first
+  created_at?: string;
+  updated_at?: string;
+}
+
+// A location payments are reported under, matched by patterns in Hesap Adı.
+// Payments made with one of the override methods always belong to it.
+export interface Location {
+  code: string; // e.g. CARSI
+  label: string; // e.g. ÇARŞI
+  account_patterns: string[];
+  method_overrides: string[]; // e.g. Çek
+  sort_order: number; // lower comes first
+  created_at?: string;
+  updated_at?: string;
+}
+
+// Why a payment needs review: a field no classification rule matched
+export interface ReviewReason {
+  field: ClassificationField;
+  column?: string; // e.g. Tahsilat Şekli
+  value: string; // cell value no rule matched
+  fallback: string; // value the field was given, e.g. Nakit or DIGER
+  code: string; // e.g. unknown_payment_method
+  message: string;
+  message_en: string;
+}
+
+export type ReviewStatus = 'pending' | 'confirmed' | 'corrected';
+
+// Part of a report total whose classification still awaits review
+export interface ReviewTotal {
+  count: number;
+  amount_usd: number;
+  share: number; // percent of the net USD total
+}
+
 export interface MonthlyReport {
   month: string;
   project_summary: ProjectTotal;
   location_summary: Record<string, LocationTotal>;
   daily_totals: Record<string, number>; // date string -> USD amount
   payment_methods: Record<string, PaymentMethodTotal>; // payment method breakdown
-  mkm_payment_methods: Record<string, PaymentMethodTotal>; // MKM project payment methods
-  msm_payment_methods: Record<string, PaymentMethodTotal>; // MSM project payment methods
+  project_payment_methods: Record<string, Record<string, PaymentMethodTotal>>; // project code -> payment method breakdown
+  kind_summary?: KindTotal;
+  gold_summary?: Record<string, GoldTotal>; // gold unit code -> totals
+  unconfirmed?: ReviewTotal; // payments still awaiting review
 }
 
 export interface YearlyReport {
@@ -72,26 +146,65 @@ export interface YearlyReport {
   project_summary: ProjectTotal;
   location_summary: Record<string, LocationTotal>;
   payment_methods: Record<string, PaymentMethodTotal>; // payment method breakdown
-  mkm_payment_methods: Record<string, PaymentMethodTotal>; // MKM project payment methods
-  msm_payment_methods: Record<string, PaymentMethodTotal>; // MSM project payment methods
+  project_payment_methods: Record<string, Record<string, PaymentMethodTotal>>; // project code -> payment method breakdown
+  kind_summary?: KindTotal;
+  gold_summary?: Record<string, GoldTotal>; // gold unit code -> totals
+  unconfirmed?: ReviewTotal; // payments still awaiting review
   monthly_reports?: MonthlyReport[];
+  projects?: Project[]; // projects in report order
+  locations?: Location[]; // locations in report order
 }
 
 export interface UploadRequest {
   raw_payments: RawPaymentData[];
 }
 
+export interface RowError {
+  row?: number; // source row number, absent for errors about the whole upload
+  column?: string;
+  value?: string;
+  customer?: string;
+  code: string;
+  message: string; // Turkish
+  message_en: string;
+  detail?: string;
+}
+
 export interface UploadResponse {
   success: boolean;
   message: string;
   processed: number;
-  errors?: string[];
+  batch_id?: number;
+  errors?: RowError[];
   weekly_reports?: WeeklyReport[];
+  customer_suggestions?: CustomerSuggestion[];
+}
+
+// A new customer name from an upload that looks like an existing customer
+export interface CustomerSuggestion {
+  customer_name: string;
+  customer_id: number;
+  suggested_id: number;
+  suggested_name: string;
+  similarity: number;
 }
 
+export const formatRowError = (error: RowError): string => {
+  let text = error.message;
+  if (error.value) {
+    text += ` '${error.value}'`;
+  }
+  if (error.column) {
+    text = `${error.column}: ${text}`;
+  }
+  return error.row ? `Satır ${error.row}${error.customer ? ` (${error.customer})` : ''}: ${text}` : text;
+};
+
 export interface ReportsResponse {
   weekly_reports: WeeklyReport[];
   monthly_reports: MonthlyReport[];
+  projects?: Project[]; // projects in report order
+  locations?: Location[]; // locations in report order
 }
 
 // Constants
@@ -107,15 +220,40 @@ export const PAYMENT_METHODS = {
   CHECK: 'Çek',
 } as const;
 
-export const PROJECTS = {
-  MKM: 'MKM',
-  MSM: 'MSM',
-} as const;
+export type ClassificationField = 'payment_method' | 'location' | 'project';
 
-export const LOCATIONS = {
-  CARSI: 'CARŞI',
-  KUYUMCUKENT: 'KUYUMCUKENT',
-  OFIS: 'OFİS',
-  BANKA: 'BANKA HAVALESİ',
-  CEK: 'ÇEK',
-} as const;
+export interface ClassificationRule {
+  id: number;
+  field: ClassificationField;
+  source: string; // input field, e.g. tahsilat_sekli
+  match_type: 'contains' | 'equals' | 'regex';
+  pattern: string;
+  value: string;
+  priority: number; // lower runs first
+  enabled: boolean;
+  note?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface TotalShift {
+  period: string; // "2024-01-15 - 2024-01-21" for a week, "2024-01" for a month
+  total: string; // report field, e.g. payment_methods.Nakit
+  before: number;
+  after: number;
+  delta: number;
+}
+
+export interface ClassificationSimulation {
+  matched: number;
+  changed: number;
+  skipped_legacy: number;
+  changes: {
+    payment_id: number;
+    customer_name: string;
+    payment_date: string;
+    changes: { field: string; old: string; new: string }[];
+  }[];
+  weekly_shifts: TotalShift[];
+  monthly_shifts: TotalShift[];
+}
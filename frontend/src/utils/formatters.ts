@@ -1,3 +1,5 @@
+import { Location, LocationTotal, PaymentMethodTotal, Project, ProjectTotal, ReviewTotal } from '../types/payment.types';
+
 // Date formatting utilities
 export const formatDate = (dateString: string): string => {
   const date = new Date(dateString);
@@ -103,3 +105,46 @@ export const formatTurkishText = (text: string): string => {
     .map(word => capitalizeFirst(word))
     .join(' ');
 };
+
+// Payment method totals keep the original amount of each currency; a currency
+// that was not collected has no entry
+export const currencyAmount = (total: PaymentMethodTotal | undefined, currency: string): number => {
+  return total?.currencies?.[currency] ?? 0;
+};
+
+// Projects a report shows: the given ones in order, then any other project code with a total
+export const reportProjects = (projects: Project[] | undefined, totals: ProjectTotal): Project[] => {
+  const listed = [...(projects || [])];
+  Object.keys(totals).sort().forEach(code => {
+    if (!listed.some(project => project.code === code)) {
+      listed.push({ code, name: code, patterns: [], sort_order: 0 });
+    }
+  });
+  return listed;
+};
+
+// Locations a report shows: the given ones in order, then any other location
+// code with a total, e.g. DIGER for payments no location matched
+export const reportLocations = (locations: Location[] | undefined, totals: Record<string, LocationTotal>): Location[] => {
+  const listed = [...(locations || [])];
+  Object.keys(totals).sort().forEach(code => {
+    if (!listed.some(location => location.code === code)) {
+      listed.push({ code, label: code === 'DIGER' ? 'DİĞER' : code, account_patterns: [], method_overrides: [], sort_order: 0 });
+    }
+  });
+  return listed;
+};
+
+// Line telling how much of a report total still awaits classification review,
+// or null when nothing does
+export const formatUnconfirmed = (total: ReviewTotal | undefined): string | null => {
+  if (!total || total.count === 0) {
+    return null;
+  }
+  const share = total.share.toLocaleString('tr-TR', { maximumFractionDigits: 2 });
+  return `Onay bekleyen ${total.count} ödeme: ${formatAmountUSD(total.amount_usd)} (toplam içinde %${share})`;
+};
+
+// Sum of the totals of all projects
+export const sumProjectTotal = (totals: ProjectTotal): number =>
+  Object.values(totals || {}).reduce((sum, amount) => sum + amount, 0);